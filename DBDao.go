@@ -34,6 +34,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -66,6 +67,10 @@ var FuncReadWriteStrategy = func(ctx context.Context, rwType int) (*DBDao, error
 // DataSourceConfig 数据库连接池的配置
 // DateSourceConfig Database connection pool configuration
 type DataSourceConfig struct {
+	// Name 数据源名称,用于监控指标,日志等区分多个数据源,可以为空
+	// Name of the datasource, used to distinguish multiple datasources in metrics, logs etc. Can be empty
+	Name string
+
 	// DSN dataSourceName 连接字符串
 	// DSN DataSourceName Database connection string
 	DSN string
@@ -76,8 +81,13 @@ type DataSourceConfig struct {
 
 	// Dialect 数据库方言:mysql,postgresql,oracle,mssql,sqlite,db2,clickhouse,dm,kingbase,shentong,tdengine 和 DriverName 对应
 	// Dialect:mysql,postgresql,oracle,mssql,sqlite,db2,clickhouse,dm,kingbase,shentong,tdengine corresponds to DriverName
+	// 其他数据库实现IDialect接口,使用RegisterDialect注册 | Other databases implement the IDialect interface and register it with RegisterDialect
 	Dialect string
 
+	// DialectVersion 数据库版本,默认""使用最新的语法.oracle配置11g及之前的版本使用ROWNUM分页,mssql配置2008,2008R2使用ROW_NUMBER()分页
+	// DialectVersion database version, "" uses the latest syntax by default. oracle 11g and earlier use ROWNUM paging, mssql 2008,2008R2 use ROW_NUMBER() paging
+	DialectVersion string
+
 	// Deprecated
 	// DBType 即将废弃,请使用Dialect属性
 	// DBType is about to be deprecated, please use the Dialect property
@@ -120,6 +130,52 @@ type DataSourceConfig struct {
 
 	// InsertSQLNoColumn insert语句中是否没有列名.true没有列名,插入值和数据库列顺序保持一致,减少语句长度
 	InsertSQLNoColumn bool
+
+	// SQLHooks SQL执行的钩子,用于链路追踪,监控指标等扩展,按顺序调用Before,倒序调用After
+	// SQLHooks Hooks of SQL execution for tracing, metrics and other extensions. Before is called in order, After in reverse order
+	SQLHooks []ISQLHook
+
+	// FuncSQLComment 构建SQL尾部注释的键值,例如 app,route,traceparent,和BindContextSQLComment绑定的值合并,ctx绑定的值优先.默认nil
+	// 注释格式参照sqlcommenter,例如 /*app='order',route='%2Forders'*/ ,不支持注释的数据库(例如tdengine)不会添加
+	// FuncSQLComment builds the key-values of the trailing SQL comment, e.g. app,route,traceparent, merged with the values bound by BindContextSQLComment, the ctx values take precedence. nil by default
+	// The comment format follows sqlcommenter, e.g. /*app='order',route='%2Forders'*/ , it is not added for databases that do not support comments (e.g. tdengine)
+	FuncSQLComment func(ctx context.Context) map[string]string
+
+	// FuncSlowSQLExplain 慢SQL的处理函数,默认nil不启用.SlowSQLMillis大于0时,超过阈值的 SELECT,UPDATE,DELETE,WITH 语句
+	// 使用新的连接和相同的参数异步执行数据库的EXPLAIN(例如 mysql EXPLAIN FORMAT=JSON),然后把执行计划传给这个函数.不支持EXPLAIN的数据库Plan为""
+	// ctx不会随请求取消,不包含数据库连接和事务,可以获取请求ctx的其他值,例如链路追踪的span
+	// FuncSlowSQLExplain handler of slow SQL, nil and disabled by default. When SlowSQLMillis > 0, SELECT, UPDATE, DELETE, WITH statements over the threshold
+	// run the database EXPLAIN (e.g. mysql EXPLAIN FORMAT=JSON) asynchronously on a new connection with the same args, then the plan is passed to this function. Plan is "" for databases without EXPLAIN support
+	// ctx is not cancelled with the request and holds no connection or transaction, other values of the request ctx are available, e.g. the tracing span
+	FuncSlowSQLExplain func(ctx context.Context, slowSQL *SlowSQLExplain)
+
+	// SlowSQLExplainIntervalSecond 同一个SQL指纹获取执行计划的最小间隔秒数,默认60,小于0不限流
+	// SlowSQLExplainIntervalSecond minimum interval in seconds of getting the plan for the same SQL fingerprint, 60 by default, no rate limit when < 0
+	SlowSQLExplainIntervalSecond int
+
+	// QuoteIdentifier 使用方言的IDialect.QuoteIdentifier包裹生成的INSERT,UPDATE,DELETE语句中的表名和列名,例如mysql的`order`,默认false.
+	// 已经包裹的tag不再处理,开启后postgresql,oracle等数据库的名称区分大小写
+	// QuoteIdentifier quotes the table and column names of the generated INSERT,UPDATE,DELETE statements with IDialect.QuoteIdentifier of the dialect, e.g. `order` of mysql, false by default.
+	// Already quoted tags are kept, names become case sensitive in postgresql,oracle etc. when enabled
+	QuoteIdentifier bool
+
+	// BatchMaxRows InsertSlice,InsertEntityMapSlice,UpdateSlice,DeleteSlice每条语句的最大行数,超过时拆分成多条语句在同一个事务中执行,默认0不限制.可用于mysql的max_allowed_packet
+	// BatchMaxRows maximum rows of each statement of InsertSlice,InsertEntityMapSlice,UpdateSlice,DeleteSlice, more rows are split into several statements executed in one transaction, 0 (no limit) by default. Can be used for mysql max_allowed_packet
+	BatchMaxRows int
+
+	// BatchMaxParams 每条语句的最大参数数量,默认0使用IDialect.MaxParams(),例如mssql的2100,小于0不限制
+	// BatchMaxParams maximum parameters of each statement, 0 uses IDialect.MaxParams() by default, e.g. 2100 of mssql, no limit when < 0
+	BatchMaxParams int
+
+	// ContiguousAutoIncrement 批量插入的自增主键是连续的,InsertSlice和InsertEntityMapSlice按照LastInsertId()+i对每行的主键赋值,默认false不赋值.
+	// 只用于方言返回GeneratedKeysLastInsertID的数据库,例如mysql要求 auto_increment_increment=1 并且 innodb_autoinc_lock_mode 是0或者1,否则赋值的主键和数据库不一致
+	// ContiguousAutoIncrement the auto-increment keys of a batch insert are contiguous, InsertSlice and InsertEntityMapSlice assign LastInsertId()+i to the key of each row, false (not assigned) by default.
+	// Only used for databases whose dialect returns GeneratedKeysLastInsertID, e.g. mysql requires auto_increment_increment=1 and innodb_autoinc_lock_mode 0 or 1, otherwise the assigned keys differ from the database
+	ContiguousAutoIncrement bool
+
+	// TimePolicy 时区和时间精度策略,保存时转换Finder参数,实体类字段和EntityMap的time.Time,查询时转换实体类字段,单列查询和QueryMap的时间,默认nil保持数据库驱动的行为
+	// TimePolicy time zone and time precision policy, converts time.Time of Finder args, entity fields and EntityMap when saving, and times of entity fields, single column queries and QueryMap when querying, nil keeps the behavior of the database driver by default
+	TimePolicy *TimePolicy
 }
 
 // DBDao 数据库操作基类,隔离原生操作数据库API入口,所有数据库操作必须通过DBDao进行
@@ -142,6 +198,9 @@ func NewDBDao(config *DataSourceConfig) (*DBDao, error) {
 		FuncLogError(nil, err)
 		return nil, err
 	}
+	if len(config.SQLHooks) > 0 {
+		atomic.AddInt32(&sqlHookConfigCount, 1)
+	}
 	dbdao, err := FuncReadWriteStrategy(nil, 1)
 	// dbDao 不存在,初始化defaultDao
 	if dbdao == nil {
@@ -172,6 +231,24 @@ func (dbDao *DBDao) newDBConnection() (*dataBaseConnection, error) {
 	return dbConnection, nil
 }
 
+// GetDataSourceConfig 获取DBDao的数据源配置,请不要修改配置的值
+// GetDataSourceConfig Get the datasource configuration of DBDao, please do not modify its values
+func (dbDao *DBDao) GetDataSourceConfig() (*DataSourceConfig, error) {
+	if dbDao == nil || dbDao.dataSource == nil {
+		return nil, errors.New("->GetDataSourceConfig-->请不要自己创建dbDao,请使用NewDBDao方法进行创建")
+	}
+	return dbDao.config, nil
+}
+
+// Stats 获取数据库连接池的统计信息,用于监控
+// Stats Get the statistics of the database connection pool, used for monitoring
+func (dbDao *DBDao) Stats() (sql.DBStats, error) {
+	if dbDao == nil || dbDao.dataSource == nil {
+		return sql.DBStats{}, errors.New("->Stats-->请不要自己创建dbDao,请使用NewDBDao方法进行创建")
+	}
+	return dbDao.dataSource.Stats(), nil
+}
+
 // BindContextDBConnection 多库的时候,通过dbDao创建DBConnection绑定到子context,返回的context就有了DBConnection. parent 不能为空
 // BindContextDBConnection In the case of multiple databases, create a DB Connection through db Dao and bind it to a sub-context,and the returned context will have a DB Connection. parent is not nil
 func (dbDao *DBDao) BindContextDBConnection(parent context.Context) (context.Context, error) {
@@ -261,7 +338,21 @@ Transaction 的示例代码
 // The impact is limited. Anonymous functions can also be extracted outside
 // If the return error is not nil, the transaction will be rolled back
 func Transaction(ctx context.Context, doTransaction func(ctx context.Context) (interface{}, error)) (interface{}, error) {
-	return transaction(ctx, doTransaction)
+	// 有SQLHooks时,记录整个事务方法,事务内的语句使用钩子返回的ctx
+	// If there are SQLHooks, record the whole transaction method, statements in the transaction use the ctx returned by the hooks
+	var hookEvent *SQLHookEvent
+	// 没有数据源配置SQLHooks时,不解析数据源配置
+	// Do not resolve the config when no data source is configured with SQLHooks
+	if ctx != nil && atomic.LoadInt32(&sqlHookConfigCount) > 0 {
+		dbConnection, _ := getDBConnectionFromContext(ctx)
+		config, errConfig := getConfigFromConnection(ctx, dbConnection, 1)
+		if errConfig == nil {
+			ctx, hookEvent = beforeSQLHooks(ctx, config, SQLHookOperationTransaction, "", nil, dbConnection != nil && dbConnection.tx != nil)
+		}
+	}
+	result, err := transaction(ctx, doTransaction)
+	afterSQLHooks(hookEvent, err)
+	return result, err
 }
 
 var transaction = func(ctx context.Context, doTransaction func(ctx context.Context) (interface{}, error)) (interface{}, error) {
@@ -337,6 +428,11 @@ var transaction = func(ctx context.Context, doTransaction func(ctx context.Conte
 				FuncLogError(ctx, errGlobal)
 				return nil, errGlobal
 			}
+			// 有SQLHooks时,包装分布式事务对象,记录BeginGTX/CommitGTX/RollbackGTX
+			// If there are SQLHooks, wrap the distributed transaction object to record BeginGTX/CommitGTX/RollbackGTX
+			if len(dbConnection.config.SQLHooks) > 0 {
+				globalTransaction = &hookGlobalTransaction{globalTransaction: globalTransaction, config: dbConnection.config}
+			}
 
 		}
 		if globalTxOpen { // 如果是分布事务开启方,启动分布式事务 | If it is the opening party of the distributed transaction, start the distributed transaction
@@ -499,7 +595,14 @@ var errQueryRow = errors.New("->QueryRow查询出多条数据")
 // Question 1. A selice needs to be constructed, and question 2. Other values ​​of the object passed by the caller will be discarded or overwritten
 // context must be passed in and cannot be empty
 func QueryRow(ctx context.Context, finder *Finder, entity interface{}) (bool, error) {
-	return queryRow(ctx, finder, entity)
+	has, err := queryRow(ctx, finder, entity)
+	if has && err == nil { // 记录EntitySnapshot的快照 | Record the snapshot of EntitySnapshot
+		err = snapshotQueryRows(ctx, entity, 0)
+	}
+	if has && err == nil { // 预加载BindContextPreload绑定的关联关系 | Preload the relations bound by BindContextPreload
+		err = preloadQueryRows(ctx, entity, 0)
+	}
+	return has, err
 }
 
 var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bool, error) {
@@ -531,9 +634,17 @@ var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bo
 		FuncLogError(ctx, errConfig)
 		return has, errConfig
 	}
+	// 增加租户条件,返回新的Finder
+	// Add the tenant condition, a new Finder is returned
+	queryFinder, errTenant := wrapTenantFinder(ctx, config, finder)
+	if errTenant != nil {
+		errTenant = fmt.Errorf("->QueryRow-->wrapTenantFinder增加租户条件错误:%w", errTenant)
+		FuncLogError(ctx, errTenant)
+		return has, errTenant
+	}
 	// 获取到sql语句
 	// Get the sql statement
-	sqlstr, errSQL := wrapQuerySQL(ctx, config, finder, nil)
+	sqlstr, errSQL := wrapQuerySQL(ctx, config, queryFinder, nil)
 	if errSQL != nil {
 		errSQL = fmt.Errorf("->QueryRow-->wrapQuerySQL获取查询SQL语句错误:%w", errSQL)
 		FuncLogError(ctx, errSQL)
@@ -551,7 +662,7 @@ var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bo
 
 	// 根据语句和参数查询
 	// Query based on statements and parameters
-	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &finder.values)
+	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &queryFinder.values)
 	if errQueryContext != nil {
 		errQueryContext = fmt.Errorf("->QueryRow-->queryContext查询数据库错误:%w", errQueryContext)
 		FuncLogError(ctx, errQueryContext)
@@ -606,7 +717,7 @@ var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bo
 		_, oneColumnScanner = entity.(sql.Scanner)
 		if !oneColumnScanner {
 			pkgPath := (*typeOf).PkgPath()
-			if pkgPath == "" || pkgPath == "time" { // 系统内置变量和time包 | System built-in variables and time package
+			if pkgPath == "" || pkgPath == "time" || typeValueConver(*typeOf) != nil { // 系统内置变量,time包和注册了IValueConver的类型 | System built-in variables, time package and types with IValueConver registered
 				oneColumnScanner = true
 			}
 		}
@@ -627,7 +738,7 @@ var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bo
 		}
 		// 构建查询字段缓存
 		// Build query field cache
-		fieldCaches, err = buildSelectFieldColumnCache(columnTypes, entityCache, config.Dialect)
+		fieldCaches, err = buildSelectFieldColumnCache(ctx, columnTypes, entityCache, config)
 		if err != nil {
 			err = fmt.Errorf("->QueryRow-->buildSelectFieldColumnCache构建字段缓存错误:%w", err)
 			return has, err
@@ -635,13 +746,12 @@ var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bo
 	} else {
 		// 对于单字段查询,创建一个空的fieldCache,但包含columnTypes信息
 		// For single field query, create an empty field Cache, but contains column Types information
-		fieldCaches = buildEmptySelectFieldColumnCache(columnTypes, config.Dialect)
+		fieldCaches = buildEmptySelectFieldColumnCache(ctx, columnTypes, typeOf, config)
 	}
 
-	// 反射获取 []driver.Value的值,用于处理nil值和自定义类型
-	// Reflect to get the value of []driver.Value, used to deal with nil values ​​and custom types
-	driverValue := reflect.Indirect(reflect.ValueOf(rows))
-	driverValue = driverValue.FieldByName("lastcols")
+	// 检查每行的NULL列,只使用公开的rows.Scan,兼容包装的驱动
+	// Check the NULL columns of each row, only the public rows.Scan is used, compatible with wrapped drivers
+	nullChecker := newRowsNullChecker(len(columnTypes))
 
 	// 缓存entity的反射值,避免在循环中重复计算
 	// Pre calculate the reflection value of entity to avoid repeated calculation in the loop
@@ -659,9 +769,9 @@ var queryRow = func(ctx context.Context, finder *Finder, entity interface{}) (bo
 			return has, errQueryRow
 		}
 		if oneColumnScanner {
-			err = sqlRowsValues(ctx, nil, typeOf, rows, &driverValue, fieldCaches, entity)
+			err = sqlRowsValues(ctx, nil, typeOf, rows, nullChecker, fieldCaches, entity)
 		} else {
-			err = sqlRowsValues(ctx, &pv, typeOf, rows, &driverValue, fieldCaches, nil)
+			err = sqlRowsValues(ctx, &pv, typeOf, rows, nullChecker, fieldCaches, nil)
 		}
 
 		if err != nil {
@@ -691,7 +801,17 @@ var errQuerySlice = errors.New("->Query数组必须是*[]struct类型或者*[]*s
 // According to the Finder and encapsulation for the specified entity type, the entity must be of the *[]struct type, which has been initialized,This method only Append elements, so the caller does not need to force type conversion
 // context must be passed in and cannot be empty
 var Query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{}, page *Page) error {
-	return query(ctx, finder, rowsSlicePtr, page)
+	// 调用方传入的slice可能已经有元素,只处理Query新增的元素
+	// The slice passed by the caller may already have elements, only the elements appended by Query are processed
+	start := querySliceLen(rowsSlicePtr)
+	err := query(ctx, finder, rowsSlicePtr, page)
+	if err == nil { // 记录EntitySnapshot的快照 | Record the snapshots of EntitySnapshot
+		err = snapshotQueryRows(ctx, rowsSlicePtr, start)
+	}
+	if err == nil { // 预加载BindContextPreload绑定的关联关系 | Preload the relations bound by BindContextPreload
+		err = preloadQueryRows(ctx, rowsSlicePtr, start)
+	}
+	return err
 }
 
 var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{}, page *Page) error {
@@ -747,7 +867,15 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 		FuncLogError(ctx, errConfig)
 		return errConfig
 	}
-	sqlstr, errSQL := wrapQuerySQL(ctx, config, finder, page)
+	// 增加租户条件,返回新的Finder,查询总条数也使用增加了租户条件的Finder
+	// Add the tenant condition, a new Finder is returned, the total count also uses the Finder with the tenant condition
+	queryFinder, errTenant := wrapTenantFinder(ctx, config, finder)
+	if errTenant != nil {
+		errTenant = fmt.Errorf("->Query-->wrapTenantFinder增加租户条件错误:%w", errTenant)
+		FuncLogError(ctx, errTenant)
+		return errTenant
+	}
+	sqlstr, errSQL := wrapQuerySQL(ctx, config, queryFinder, page)
 	if errSQL != nil {
 		errSQL = fmt.Errorf("->Query-->wrapQuerySQL获取查询SQL语句错误:%w", errSQL)
 		FuncLogError(ctx, errSQL)
@@ -765,7 +893,7 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 
 	// 根据语句和参数查询
 	// Query based on statements and parameters
-	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &finder.values)
+	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &queryFinder.values)
 	if errQueryContext != nil {
 		errQueryContext = fmt.Errorf("->Query-->queryContext查询rows错误:%w", errQueryContext)
 		FuncLogError(ctx, errQueryContext)
@@ -803,9 +931,15 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 		FuncLogError(ctx, errColumnTypes)
 		return errColumnTypes
 	}
-	// 查询的字段长度
-	// Length of queried fields
+	// 分页语句增加的行号列,不赋值到结果
+	// The row number column added by the page statement, not assigned to the result
+	rownumIndex := pageRownumColumnIndex(columnTypes, page)
+	// 查询的字段长度,不包括行号列
+	// Length of queried fields, excluding the row number column
 	ctLen := len(columnTypes)
+	if rownumIndex >= 0 {
+		ctLen--
+	}
 	// 是否只有一列,而且可以直接赋值
 	// Whether there is only one column and can be directly assigned
 	oneColumnScanner := false
@@ -819,7 +953,7 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 		_, oneColumnScanner = reflect.New(sliceElementType).Interface().(sql.Scanner)
 		if !oneColumnScanner {
 			pkgPath := sliceElementType.PkgPath()
-			if pkgPath == "" || pkgPath == "time" { // 系统内置变量和time包 | System built-in variables and time package
+			if pkgPath == "" || pkgPath == "time" || typeValueConver(sliceElementType) != nil { // 系统内置变量,time包和注册了IValueConver的类型 | System built-in variables, time package and types with IValueConver registered
 				oneColumnScanner = true
 			}
 		}
@@ -839,7 +973,7 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 		}
 		// 构建查询字段缓存
 		// Build query field cache
-		fieldCache, err = buildSelectFieldColumnCache(columnTypes, entityCache, config.Dialect)
+		fieldCache, err = buildSelectFieldColumnCache(ctx, columnTypes, entityCache, config)
 		if err != nil {
 			err = fmt.Errorf("->Query-->buildSelectFieldColumnCache构建字段缓存错误:%w", err)
 			return err
@@ -847,12 +981,14 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 	} else {
 		// 对于单字段查询,创建一个空的fieldCache,但包含columnTypes信息
 		// For single field query, create an empty field Cache, but contains column Types information
-		fieldCache = buildEmptySelectFieldColumnCache(columnTypes, config.Dialect)
+		fieldCache = buildEmptySelectFieldColumnCache(ctx, columnTypes, &sliceElementType, config)
+	}
+	if rownumIndex >= 0 { // 丢弃行号列 | Discard the row number column
+		fieldCache[rownumIndex] = nil
 	}
-	// 反射获取 []driver.Value的值,用于处理nil值和自定义类型
-	// Reflect to get the value of []driver.Value, used to deal with nil values ​​and custom types
-	driverValue := reflect.Indirect(reflect.ValueOf(rows))
-	driverValue = driverValue.FieldByName("lastcols")
+	// 检查每行的NULL列,只使用公开的rows.Scan,兼容包装的驱动
+	// Check the NULL columns of each row, only the public rows.Scan is used, compatible with wrapped drivers
+	nullChecker := newRowsNullChecker(len(columnTypes))
 	// 预分配 slice 容量,避免 reflect.Append 在循环中多次扩容
 	// Pre-allocate slice capacity to avoid repeated growth in reflect.Append during the loop
 	if page != nil && page.PageSize > 0 {
@@ -868,9 +1004,9 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 	for rows.Next() {
 		pv := reflect.New(sliceElementType)
 		if oneColumnScanner {
-			err = sqlRowsValues(ctx, nil, &sliceElementType, rows, &driverValue, fieldCache, pv.Interface())
+			err = sqlRowsValues(ctx, nil, &sliceElementType, rows, nullChecker, fieldCache, pv.Interface())
 		} else {
-			err = sqlRowsValues(ctx, &pv, &sliceElementType, rows, &driverValue, fieldCache, nil)
+			err = sqlRowsValues(ctx, &pv, &sliceElementType, rows, nullChecker, fieldCache, nil)
 		}
 		pv = pv.Elem()
 		// scan赋值.是一个指针数组,已经根据struct的属性类型初始化了,sql驱动能感知到参数类型,所以可以直接赋值给struct的指针.这样struct的属性就有值了
@@ -901,7 +1037,7 @@ var query = func(ctx context.Context, finder *Finder, rowsSlicePtr interface{},
 	// 查询总条数
 	// Query total number
 	if finder.SelectTotalCount && page != nil {
-		count, errCount := selectCount(ctx, finder)
+		count, errCount := selectCount(ctx, config, queryFinder)
 		if errCount != nil {
 			errCount = fmt.Errorf("->Query-->selectCount查询总条数错误:%w", errCount)
 			FuncLogError(ctx, errCount)
@@ -989,7 +1125,15 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 		FuncLogError(ctx, errConfig)
 		return nil, errConfig
 	}
-	sqlstr, errSQL := wrapQuerySQL(ctx, config, finder, page)
+	// 增加租户条件,返回新的Finder,查询总条数也使用增加了租户条件的Finder
+	// Add the tenant condition, a new Finder is returned, the total count also uses the Finder with the tenant condition
+	queryFinder, errTenant := wrapTenantFinder(ctx, config, finder)
+	if errTenant != nil {
+		errTenant = fmt.Errorf("->QueryMap-->wrapTenantFinder增加租户条件错误:%w", errTenant)
+		FuncLogError(ctx, errTenant)
+		return nil, errTenant
+	}
+	sqlstr, errSQL := wrapQuerySQL(ctx, config, queryFinder, page)
 	if errSQL != nil {
 		errSQL = fmt.Errorf("->QueryMap -->wrapQuerySQL查询SQL语句错误:%w", errSQL)
 		FuncLogError(ctx, errSQL)
@@ -1006,7 +1150,7 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 
 	// 根据语句和参数查询
 	// Query based on statements and parameters
-	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &finder.values)
+	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &queryFinder.values)
 	if errQueryContext != nil {
 		errQueryContext = fmt.Errorf("->QueryMap-->queryContext查询rows错误:%w", errQueryContext)
 		FuncLogError(ctx, errQueryContext)
@@ -1042,10 +1186,12 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 		FuncLogError(ctx, errColumnTypes)
 		return nil, errColumnTypes
 	}
-	// 反射获取 []driver.Value的值
-	// Reflect to get the value of []driver.Value
-	driverValue := reflect.Indirect(reflect.ValueOf(rows))
-	driverValue = driverValue.FieldByName("lastcols")
+	// 检查每行的NULL列,只使用公开的rows.Scan,兼容包装的驱动
+	// Check the NULL columns of each row, only the public rows.Scan is used, compatible with wrapped drivers
+	nullChecker := newRowsNullChecker(len(columnTypes))
+	// 分页语句增加的行号列,不放到map中
+	// The row number column added by the page statement, not put into the map
+	rownumIndex := pageRownumColumnIndex(columnTypes, page)
 
 	// 预分配resultMapList容量,提高性能 | Pre allocate result Map List capacity to improve performance
 	// 如果有分页参数,根据每页大小预分配容量 | If there is a paging parameter, pre allocate capacity according to the page size
@@ -1063,14 +1209,40 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 	// 缓存数据库类型名称,避免在循环中重复调用strings.ToUpper
 	// Pre calculate database type names to avoid calling strings.ToUpper repeatedly in the loop
 	databaseTypeNames := make([]string, columnTypeLen)
+	// BindContextQueryMapOptions绑定的选项,map的key使用KeyCase转换的列名
+	// Options bound by BindContextQueryMapOptions, the map keys are the column names converted by KeyCase
+	options := getContextQueryMapOptions(ctx)
+	// 方言原生的JSON列,QueryMapOptions.DecodeJSON为true时反序列化为map[string]interface{}或者[]interface{}
+	// Native JSON columns of the dialect, deserialized to map[string]interface{} or []interface{} when QueryMapOptions.DecodeJSON is true
+	jsonColumns := make([]bool, columnTypeLen)
+	keys := make([]string, columnTypeLen)
 	for i, columnType := range columnTypes {
 		databaseTypeNames[i] = strings.ToUpper(columnType.DatabaseTypeName())
+		jsonColumns[i] = options != nil && options.DecodeJSON && isJSONDatabaseType(config.Dialect, databaseTypeNames[i])
+		keys[i] = columnType.Name()
+		if options != nil {
+			keys[i] = queryMapKey(options.KeyCase, keys[i])
+		}
+	}
+	if columns, ok := ctx.Value(contextQueryMapColumnsValueKey).(*[]string); ok { // QueryMapColumns接收列的顺序 | QueryMapColumns receives the column order
+		*columns = make([]string, 0, columnTypeLen)
+		for i, key := range keys {
+			if i != rownumIndex {
+				*columns = append(*columns, key)
+			}
+		}
 	}
 	// 预分配变量,循环内复用,循环的旧值会被完全覆盖,减少GC压力
 	// Pre-allocate variables for reuse in the loop to reduce GC pressure
 	// 接收数据库返回的数据,需要使用指针接收
 	// To receive the data returned by the database, you need to use the pointer to receive
 	values := make([]interface{}, columnTypeLen)
+	// 接收类型注册了IValueConver的列,没有注册时是nil
+	// Columns whose receiving type has IValueConver registered, nil without registrations
+	var valueScanners []*valueConverScanner
+	if atomic.LoadInt32(&typeValueConverCount) > 0 {
+		valueScanners = make([]*valueConverScanner, columnTypeLen)
+	}
 	// 循环遍历结果集
 	// Loop through the result set
 	for rows.Next() {
@@ -1087,13 +1259,19 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 			fieldTempDriverValueMap = make(map[int]*fieldColumnCache)
 		}
 
+		// 记录当前行的NULL列
+		// Record the NULL columns of the current row
+		if errNull := nullChecker.scan(rows); errNull != nil {
+			errNull = fmt.Errorf("->QueryMap-->nullChecker.scan错误:%w", errNull)
+			FuncLogError(ctx, errNull)
+			return nil, errNull
+		}
+
 		// 给数据赋值初始化变量
 		// Initialize variables by assigning values ​​to data
 		for i, columnType := range columnTypes {
-			dv := driverValue.Index(i)
-			// if dv.IsValid() && dv.InterfaceData()[0] == 0 {
-			if dv.IsValid() && dv.IsNil() { // 该字段的数据库值是null,不再处理,使用默认值 | The database value of this field is null, no further processing is required, use the default value
-				values[i] = new(interface{})
+			if nullChecker.isNull(i) || i == rownumIndex { // 该字段的数据库值是null或者是行号列,不再处理 | The database value of this field is null or it is the row number column, no further processing is required
+				values[i] = discardScanner{}
 				continue
 			}
 			// 类型转换的接口实现
@@ -1153,7 +1331,11 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 			case "DOUBLE", "FLOAT8":
 				values[i] = new(float64)
 			case "DATE", "TIME", "DATETIME", "TIMESTAMP", "TIMESTAMPTZ", "TIMETZ", "INTERVAL", "DATETIME2", "SMALLDATETIME", "DATETIMEOFFSET":
-				values[i] = new(time.Time)
+				if config.TimePolicy != nil || (options != nil && options.NormalizeValue) { // 驱动可能返回字符串,使用TimePolicy或者NormalizeValue解析 | The driver may return strings, parsed with TimePolicy or NormalizeValue
+					values[i] = new(interface{})
+				} else {
+					values[i] = new(time.Time)
+				}
 			case "NUMBER":
 				precision, scale, isDecimal := columnType.DecimalSize()
 				if isDecimal || precision > 18 || precision-scale > 18 { // 如果是Decimal类型 | If it is Decimal type
@@ -1175,6 +1357,18 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 				// No type conversion is required, normal assignment
 				values[i] = new(interface{})
 			}
+			// 接收类型注册了IValueConver,使用IValueConver转换数据库驱动的值
+			// The receiving type has IValueConver registered, convert the database driver value with IValueConver
+			if valueScanners != nil {
+				if valueScanners[i] == nil {
+					if valueConver := typeValueConver(reflect.TypeOf(values[i]).Elem()); valueConver != nil {
+						valueScanners[i] = &valueConverScanner{ctx: ctx, valueConver: valueConver}
+					}
+				}
+				if valueScanners[i] != nil {
+					values[i] = valueScanners[i]
+				}
+			}
 		}
 		// scan赋值
 		// scan assignment
@@ -1184,6 +1378,13 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 			FuncLogError(ctx, errScan)
 			return nil, errScan
 		}
+		// IValueConver转换后的值
+		// Values converted by IValueConver
+		for i, valueScanner := range valueScanners {
+			if valueScanner != nil && values[i] == valueScanner {
+				values[i] = &valueScanner.value
+			}
+		}
 
 		// 循环 需要类型转换的字段,把临时值赋值给实际的接收对象
 		// Loop through the fields that require type conversion and assign the temporary value to the actual receiving object
@@ -1204,17 +1405,52 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 		// 获取每一列的值
 		// Get the value of each column
 		for i, columnType := range columnTypes {
-
+			if i == rownumIndex {
+				continue
+			}
+			if nullChecker.isNull(i) {
+				result[keys[i]] = nil
+				continue
+			}
 			// 取到指针下的值,[]byte格式
 			// Get the value under the pointer, []byte format
 			// v := *(values[i].(*interface{}))
 			v := reflect.ValueOf(values[i]).Elem().Interface()
+			if jsonColumns[i] && fieldTempDriverValueMap[i] == nil { // 原生JSON列,没有自定义类型转换 | Native JSON column without custom type conversion
+				jsonValue, errJSON := jsonMapValue(v)
+				if errJSON != nil {
+					errJSON = fmt.Errorf("->QueryMap-->jsonMapValue错误:%w", errJSON)
+					FuncLogError(ctx, errJSON)
+					return nil, errJSON
+				}
+				v = jsonValue
+			}
+			if config.TimePolicy != nil && fieldTempDriverValueMap[i] == nil { // 使用TimePolicy转换时间列和time.Time的值 | Convert time columns and time.Time values with TimePolicy
+				timeValue, errTime := timePolicyMapValue(config, databaseTypeNames[i], v)
+				if errTime != nil {
+					errTime = fmt.Errorf("->QueryMap-->timePolicyMapValue错误:%w", errTime)
+					FuncLogError(ctx, errTime)
+					return nil, errTime
+				}
+				v = timeValue
+			}
+			// 统一值的类型,不处理ICustomDriverValueConver和IValueConver转换的值
+			// Normalize the value type, values converted by ICustomDriverValueConver and IValueConver are not processed
+			if options != nil && options.NormalizeValue && fieldTempDriverValueMap[i] == nil && (valueScanners == nil || valueScanners[i] == nil) {
+				normalizeValue, errNormalize := normalizeMapValue(ctx, config, columnType, databaseTypeNames[i], v)
+				if errNormalize != nil {
+					errNormalize = fmt.Errorf("->QueryMap-->normalizeMapValue列%s错误:%w", columnType.Name(), errNormalize)
+					FuncLogError(ctx, errNormalize)
+					return nil, errNormalize
+				}
+				v = normalizeValue
+			}
 			// 从[]byte转化成实际的类型值,例如string,int
 			// Convert from []byte to actual type value, such as string, int
 			// v = converValueColumnType(v, columnType)
 			// 赋值到Map
 			// Assign to Map
-			result[columnType.Name()] = v
+			result[keys[i]] = v
 
 		}
 
@@ -1234,7 +1470,7 @@ var queryMap = func(ctx context.Context, finder *Finder, page *Page) ([]map[stri
 	// 查询总条数
 	// Query total number
 	if finder.SelectTotalCount && page != nil {
-		count, errCount := selectCount(ctx, finder)
+		count, errCount := selectCount(ctx, config, queryFinder)
 		if errCount != nil {
 			errCount = fmt.Errorf("->QueryMap-->selectCount查询总条数错误:%w", errCount)
 			FuncLogError(ctx, errCount)
@@ -1267,7 +1503,15 @@ var resultSetRows = func(ctx context.Context, finder *Finder, page *Page, doRows
 		FuncLogError(ctx, errConfig)
 		return nil, errConfig
 	}
-	sqlstr, errSQL := wrapQuerySQL(ctx, config, finder, page)
+	// 增加租户条件,返回新的Finder
+	// Add the tenant condition, a new Finder is returned
+	queryFinder, errTenant := wrapTenantFinder(ctx, config, finder)
+	if errTenant != nil {
+		errTenant = fmt.Errorf("->ResultSetRows-->wrapTenantFinder增加租户条件错误:%w", errTenant)
+		FuncLogError(ctx, errTenant)
+		return nil, errTenant
+	}
+	sqlstr, errSQL := wrapQuerySQL(ctx, config, queryFinder, page)
 	if errSQL != nil {
 		errSQL = fmt.Errorf("->ResultSetRows-->wrapQuerySQL获取查询SQL语句错误:%w", errSQL)
 		FuncLogError(ctx, errSQL)
@@ -1285,7 +1529,7 @@ var resultSetRows = func(ctx context.Context, finder *Finder, page *Page, doRows
 
 	// 根据语句和参数查询
 	// Query based on statements and parameters
-	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &finder.values)
+	rows, errQueryContext := dbConnection.queryContext(ctx, &sqlstr, &queryFinder.values)
 	if errQueryContext != nil {
 		errQueryContext = fmt.Errorf("->ResultSetRows-->queryContext查询rows错误:%w", errQueryContext)
 		FuncLogError(ctx, errQueryContext)
@@ -1341,7 +1585,25 @@ var updateFinder = func(ctx context.Context, finder *Finder) (int, error) {
 	if finder == nil {
 		return affected, errors.New("->UpdateFinder-->finder不能为空")
 	}
-	sqlstr, err := finder.GetSQL()
+	// 从contxt中获取数据库连接,可能为nil
+	// Get database connection from contxt, may be nil
+	dbConnection, err := getDBConnectionFromContext(ctx)
+	if err != nil {
+		return affected, err
+	}
+	config, err := getConfigFromConnection(ctx, dbConnection, 1)
+	if err != nil {
+		return affected, err
+	}
+	// 增加租户条件,返回新的Finder
+	// Add the tenant condition, a new Finder is returned
+	finder, err = wrapTenantFinder(ctx, config, finder)
+	if err != nil {
+		err = fmt.Errorf("->UpdateFinder-->wrapTenantFinder增加租户条件错误:%w", err)
+		FuncLogError(ctx, err)
+		return affected, err
+	}
+	sqlstr, err := finder.getSQL(config)
 	if err != nil {
 		err = fmt.Errorf("->UpdateFinder-->finder.GetSQL()错误:%w", err)
 		FuncLogError(ctx, err)
@@ -1409,7 +1671,9 @@ var insertEntity = func(ctx context.Context, entity IEntityStruct) (int, error)
 		FuncLogError(ctx, err)
 		return affected, err
 	}
-	insertSQL := entityCache.insertSQL
+	// ctx中有表名映射时,替换缓存语句中的表名
+	// Replace the table name of the cached statement when ctx has a table name mapping
+	insertSQL := wrapCacheTableName(ctx, config, entityCache.insertSQL, "INSERT INTO ", entity.GetTableName())
 	sqlstr := &insertSQL
 	// oracle 12c+ 支持IDENTITY属性的自增列,因为分页也要求12c+的语法,所以数据库就IDENTITY创建自增吧
 	// 处理序列产生的自增主键,例如oracle,postgresql等
@@ -1467,31 +1731,10 @@ var insertEntity = func(ctx context.Context, entity IEntityStruct) (int, error)
 			// 不返回错误,因为插入操作可能已成功,只是不支持返回自增的值
 			return affected, nil
 		}
-		//pkName := entity.GetPKColumnName()
-		switch entityCache.pkType {
-		case "int":
-			// int64 转 int
-			// int64 to int
-			autoIncrementIDInt, err := typeConvertInt64toInt(autoIncrementIDInt64)
-			if err != nil {
-				return affected, err
-			}
-			// 设置自增主键的值
-			// Set the value of the auto-incrementing primary key
-			pk := reflect.ValueOf(entity).Elem().FieldByIndex(entityCache.pkField.fieldIndex)
-			pk.Set(reflect.ValueOf(autoIncrementIDInt))
-			//err = setFieldValueByColumnName(entity, pkName, autoIncrementIDInt)
-		case "int64":
-			// 设置自增主键的值
-			// Set the value of the auto-incrementing primary key
-			pk := reflect.ValueOf(entity).Elem().FieldByIndex(entityCache.pkField.fieldIndex)
-			pk.Set(reflect.ValueOf(autoIncrementIDInt64))
-			//err = setFieldValueByColumnName(entity, pkName, autoIncrementIDInt64)
-		}
-
+		// 设置自增主键的值
+		// Set the value of the auto-incrementing primary key
+		err = setEntityAutoIncrementPK(entity, entityCache, autoIncrementIDInt64)
 		if err != nil {
-			err = fmt.Errorf("->Insert-->setFieldValueByColumnName反射赋值数据库返回的自增主键错误:%w", err)
-			FuncLogError(ctx, err)
 			return affected, err
 		}
 	}
@@ -1500,11 +1743,13 @@ var insertEntity = func(ctx context.Context, entity IEntityStruct) (int, error)
 }
 
 // InsertSlice 批量保存Struct Slice 数组对象,必须是[]IEntityStruct类型,使用IEntityStruct接口,兼容Struct实体类
-// 如果是自增主键,无法对Struct对象里的主键属性赋值
+// 如果是自增主键,按照IDialect.WrapAutoIncrementInsertSliceSQL的方式对Struct对象里的主键属性赋值:
+// postgresql,kingbase,sqlite 3.35+ 使用 RETURNING,mysql在DataSourceConfig.ContiguousAutoIncrement为true时使用LastInsertId()和连续的自增主键,oracle,shentong,mssql逐行插入
 // ctx不能为nil,参照使用zorm.Transaction方法传入ctx
 // affected影响的行数,如果异常或者驱动不支持,返回-1
 // InsertSlice batch saves Struct Slice array objects, which must be of type [] IEntityStruct, using the IEntityStruct interface to be compatible with Struct entity classes
-// If it is an auto-incrementing primary key, the primary key attribute in the Struct object cannot be assigned
+// If it is an auto-incrementing primary key, the primary key attribute in the Struct object is assigned as IDialect.WrapAutoIncrementInsertSliceSQL returns:
+// RETURNING for postgresql,kingbase,sqlite 3.35+, LastInsertId() with contiguous keys for mysql when DataSourceConfig.ContiguousAutoIncrement is true, one by one for oracle,shentong,mssql
 // ctx cannot be nil, refer to zorm.Transaction method to pass in ctx. Don't build DB Connection yourself
 // The number of rows affected by affected, if it is abnormal or the driver does not support it, return -1
 func InsertSlice(ctx context.Context, entityStructSlice []IEntityStruct) (int, error) {
@@ -1542,8 +1787,23 @@ var insertSlice = func(ctx context.Context, entityStructSlice []IEntityStruct) (
 		FuncLogError(ctx, err)
 		return affected, err
 	}
+	// 按照行数和参数数量的限制拆分成多条语句,在同一个事务中执行
+	// Split into several statements by the row and parameter limits, executed in one transaction
+	if batchRows := batchRowCount(config, len(entityCache.columns)); batchRows > 0 && len(entityStructSlice) > batchRows {
+		return execBatchChunks(ctx, len(entityStructSlice), batchRows, func(ctx context.Context, start int, end int) (int, error) {
+			return insertSliceStatement(ctx, config, entityCache, entityStructSlice[start:end])
+		})
+	}
+	return insertSliceStatement(ctx, config, entityCache, entityStructSlice)
+}
+
+// insertSliceStatement 使用一条语句批量保存entityStructSlice
+// insertSliceStatement saves entityStructSlice with one statement
+func insertSliceStatement(ctx context.Context, config *DataSourceConfig, entityCache *entityStructCache, entityStructSlice []IEntityStruct) (int, error) {
+	affected := -1
+	entity := entityStructSlice[0]
 	values := make([]interface{}, 0, (len(entityCache.columns)+1)*len(entityStructSlice))
-	err = insertEntityFieldValues(ctx, entity, entityCache, true, &values)
+	err := insertEntityFieldValues(ctx, entity, entityCache, true, &values)
 	if err != nil {
 		FuncLogError(ctx, err)
 		return affected, err
@@ -1555,8 +1815,9 @@ var insertSlice = func(ctx context.Context, entityStructSlice []IEntityStruct) (
 	// SQL语句的构造器
 	// SQL statement constructor
 	var insertSliceSQLBuilder strings.Builder
-	insertSliceSQLBuilder.Grow(len(entityCache.insertSQL) + (len(entityCache.valuesSQL)+1)*len(entityStructSlice))
-	insertSliceSQLBuilder.WriteString(entityCache.insertSQL)
+	insertSQL := wrapCacheTableName(ctx, config, entityCache.insertSQL, "INSERT INTO ", tableName)
+	insertSliceSQLBuilder.Grow(len(insertSQL) + (len(entityCache.valuesSQL)+1)*len(entityStructSlice))
+	insertSliceSQLBuilder.WriteString(insertSQL)
 
 	for i := 1; i < len(entityStructSlice); i++ {
 		entity := entityStructSlice[i]
@@ -1570,7 +1831,7 @@ var insertSlice = func(ctx context.Context, entityStructSlice []IEntityStruct) (
 		if config.Dialect == "tdengine" && tableName != newTableName { // 如果是tdengine,拼接类似 INSERT INTO table1 values('1','2'),('3','4')  table2 values('5','6'),目前要求字段和类型必须一致,如果不一致,改动略多
 			tableName = newTableName
 			insertSliceSQLBuilder.WriteByte(' ')
-			insertSliceSQLBuilder.WriteString(tableName)
+			insertSliceSQLBuilder.WriteString(wrapTableName(ctx, config, tableName))
 			insertSliceSQLBuilder.WriteString(" VALUES")
 		} else {
 			insertSliceSQLBuilder.WriteByte(',')
@@ -1581,15 +1842,44 @@ var insertSlice = func(ctx context.Context, entityStructSlice []IEntityStruct) (
 
 	sqlstr := insertSliceSQLBuilder.String()
 
-	// 包装update执行,赋值给影响的函数指针变量,返回*sql.Result
-	// Package update execution, assign it to the function pointer variable affected, and return *sql.Result
-	_, errexec := wrapExecUpdateValuesAffected(ctx, &affected, &sqlstr, &values, nil)
+	// 自增主键的获取方式
+	// How the auto-incrementing primary keys are fetched
+	generatedKeys := GeneratedKeysNone
+	if entityCache.autoIncrement > 0 {
+		generatedKeys = wrapAutoIncrementInsertSliceSQL(ctx, config, entity.GetPKColumnName(), &sqlstr)
+	}
+	// 逐行插入,使用Insert获取每行的自增主键
+	// Insert row by row, Insert gets the auto-incrementing primary key of each row
+	if generatedKeys == GeneratedKeysPerRow {
+		affected = 0
+		for _, entity := range entityStructSlice {
+			rowAffected, err := insertEntity(ctx, entity)
+			if err != nil {
+				return affected, err
+			}
+			affected += rowAffected
+		}
+		return affected, nil
+	}
+
+	// 包装insert执行,赋值给影响的函数指针变量,返回每行的自增主键
+	// Package insert execution, assign it to the function pointer variable affected, and return the auto-incrementing primary key of each row
+	ids, errexec := wrapExecInsertSliceValuesAffected(ctx, &affected, &sqlstr, &values, generatedKeys, len(entityStructSlice))
 	if errexec != nil {
-		errexec = fmt.Errorf("->InsertSlice-->wrapExecUpdateValuesAffected执行保存错误:%w", errexec)
+		errexec = fmt.Errorf("->InsertSlice-->wrapExecInsertSliceValuesAffected执行保存错误:%w", errexec)
 		FuncLogError(ctx, errexec)
+		return affected, errexec
+	}
+	for i, id := range ids {
+		// 设置自增主键的值
+		// Set the value of the auto-incrementing primary key
+		err = setEntityAutoIncrementPK(entityStructSlice[i], entityCache, id)
+		if err != nil {
+			return affected, err
+		}
 	}
 
-	return affected, errexec
+	return affected, nil
 }
 
 // Update 更新struct所有属性,必须是IEntityStruct类型
@@ -1690,12 +1980,20 @@ var deleteEntity = func(ctx context.Context, entity IEntityStruct) (int, error)
 
 	// SQL语句
 	// SQL statement
-	sqlstr := entityCache.deleteSQL
+	sqlstr := wrapCacheTableName(ctx, config, entityCache.deleteSQL, "DELETE FROM ", entity.GetTableName())
 	value := reflect.ValueOf(entity).Elem().FieldByIndex(entityCache.pkField.fieldIndex).Interface()
 	// 包装update执行,赋值给影响的函数指针变量,返回*sql.Result
 	// Package update execution, assign it to the function pointer variable affected, and return *sql.Result
-	values := make([]interface{}, 1)
+	values := make([]interface{}, 1, 2)
 	values[0] = value
+	// 租户条件
+	// Tenant condition
+	if tenantField := entityTenantField(entity, entityCache); tenantField != nil {
+		if err = wrapTenantWhereSQL(ctx, config, tenantField.columnTag, &sqlstr, &values); err != nil {
+			FuncLogError(ctx, err)
+			return affected, err
+		}
+	}
 	_, errexec := wrapExecUpdateValuesAffected(ctx, &affected, &sqlstr, &values, nil)
 	if errexec != nil {
 		errexec = fmt.Errorf("->Delete-->wrapExecUpdateValuesAffected执行删除错误:%w", errexec)
@@ -1705,6 +2003,161 @@ var deleteEntity = func(ctx context.Context, entity IEntityStruct) (int, error)
 	return affected, errexec
 }
 
+// UpdateSlice 批量更新struct所有属性,必须是相同类型的[]IEntityStruct,主键必须有值.支持BindContextOnlyUpdateCols
+// 每列使用 CASE pk WHEN ? THEN ? ... END 一条语句更新多行,按照DataSourceConfig.BatchMaxRows和BatchMaxParams拆分成多条语句,在同一个事务中执行
+// ctx不能为nil,参照使用zorm.Transaction方法传入ctx
+// affected影响的行数,如果异常或者驱动不支持,返回-1
+// UpdateSlice updates all attributes of the structs in batch, which must be []IEntityStruct of the same type, the primary key must have a value. BindContextOnlyUpdateCols is supported
+// Each column uses CASE pk WHEN ? THEN ? ... END to update several rows in one statement, split by DataSourceConfig.BatchMaxRows and BatchMaxParams into several statements executed in one transaction
+// ctx cannot be nil, refer to zorm.Transaction method to pass in ctx. Don't build DB Connection yourself
+// The number of rows affected by affected, if it is abnormal or the driver does not support it, return -1
+func UpdateSlice(ctx context.Context, entityStructSlice []IEntityStruct) (int, error) {
+	return updateSlice(ctx, entityStructSlice, false)
+}
+
+// UpdateNotZeroValueSlice 批量更新struct不为默认零值的属性,和UpdateSlice一样,每行只更新不为零值的列.支持BindContextMustUpdateCols
+// ctx不能为nil,参照使用zorm.Transaction方法传入ctx
+// affected影响的行数,如果异常或者驱动不支持,返回-1
+// UpdateNotZeroValueSlice updates the attributes of the structs that are not the default zero value in batch, like UpdateSlice, only the not zero columns of each row are updated. BindContextMustUpdateCols is supported
+// ctx cannot be nil, refer to zorm.Transaction method to pass in ctx. Don't build DB Connection yourself
+// The number of rows affected by affected, if it is abnormal or the driver does not support it, return -1
+func UpdateNotZeroValueSlice(ctx context.Context, entityStructSlice []IEntityStruct) (int, error) {
+	return updateSlice(ctx, entityStructSlice, true)
+}
+
+var updateSlice = func(ctx context.Context, entityStructSlice []IEntityStruct, onlyUpdateNotZero bool) (int, error) {
+	affected := -1
+	if len(entityStructSlice) < 1 || entityStructSlice[0] == nil {
+		return affected, errors.New("->UpdateSlice-->entityStructSlice对象数组不能为空")
+	}
+	entity := entityStructSlice[0]
+	if entity.GetPKColumnName() == "" {
+		return affected, errors.New("->UpdateSlice-->entity没有主键")
+	}
+	// 从contxt中获取数据库连接,可能为nil
+	// Get database connection from contxt, may be nil
+	dbConnection, errFromContxt := getDBConnectionFromContext(ctx)
+	if errFromContxt != nil {
+		return affected, errFromContxt
+	}
+	config, errConfig := getConfigFromConnection(ctx, dbConnection, 1)
+	if errConfig != nil {
+		return affected, errConfig
+	}
+	entityCache, err := getEntityStructCache(ctx, entity, config) // 预热缓存
+	if err != nil {
+		FuncLogError(ctx, err)
+		return affected, err
+	}
+	execChunk := func(ctx context.Context, start int, end int) (int, error) {
+		chunkAffected := -1
+		sqlstr, values, err := updateSliceFieldValues(ctx, config, entityStructSlice[start:end], entityCache, onlyUpdateNotZero)
+		if err != nil {
+			err = fmt.Errorf("->UpdateSlice-->updateSliceFieldValues包装语句错误:%w", err)
+			FuncLogError(ctx, err)
+			return chunkAffected, err
+		}
+		// 包装update执行,赋值给影响的函数指针变量,返回*sql.Result
+		// Package update execution, assign it to the function pointer variable affected, and return *sql.Result
+		_, errexec := wrapExecUpdateValuesAffected(ctx, &chunkAffected, sqlstr, values, nil)
+		if errexec != nil {
+			errexec = fmt.Errorf("->UpdateSlice-->wrapExecUpdateValuesAffected执行更新错误:%w", errexec)
+			FuncLogError(ctx, errexec)
+		}
+		return chunkAffected, errexec
+	}
+	// 按照行数和参数数量的限制拆分成多条语句,在同一个事务中执行.每行每列最多2个参数,加上主键条件
+	// Split into several statements by the row and parameter limits, executed in one transaction. At most 2 parameters per column of each row, plus the primary key condition
+	if batchRows := batchRowCount(config, 2*len(entityCache.columns)+1); batchRows > 0 && len(entityStructSlice) > batchRows {
+		return execBatchChunks(ctx, len(entityStructSlice), batchRows, execChunk)
+	}
+	return execChunk(ctx, 0, len(entityStructSlice))
+}
+
+// DeleteSlice 根据主键批量删除对象,必须是相同类型的[]IEntityStruct,使用 DELETE FROM table WHERE pk IN (?,?)
+// 按照DataSourceConfig.BatchMaxRows和BatchMaxParams拆分成多条语句,在同一个事务中执行
+// ctx不能为nil,参照使用zorm.Transaction方法传入ctx
+// affected影响的行数,如果异常或者驱动不支持,返回-1
+// DeleteSlice deletes objects in batch based on the primary key, which must be []IEntityStruct of the same type, DELETE FROM table WHERE pk IN (?,?) is used
+// Split by DataSourceConfig.BatchMaxRows and BatchMaxParams into several statements executed in one transaction
+// The number of rows affected by affected, if it is abnormal or the driver does not support it, return -1
+func DeleteSlice(ctx context.Context, entityStructSlice []IEntityStruct) (int, error) {
+	return deleteSlice(ctx, entityStructSlice)
+}
+
+var deleteSlice = func(ctx context.Context, entityStructSlice []IEntityStruct) (int, error) {
+	affected := -1
+	if len(entityStructSlice) < 1 || entityStructSlice[0] == nil {
+		return affected, errors.New("->DeleteSlice-->entityStructSlice对象数组不能为空")
+	}
+	entity := entityStructSlice[0]
+	if entity.GetPKColumnName() == "" { // 没有主键
+		return affected, errors.New("->DeleteSlice-->entity没有主键")
+	}
+	// 从contxt中获取数据库连接,可能为nil
+	// Get database connection from contxt, may be nil
+	dbConnection, errFromContxt := getDBConnectionFromContext(ctx)
+	if errFromContxt != nil {
+		return affected, errFromContxt
+	}
+	config, errConfig := getConfigFromConnection(ctx, dbConnection, 1)
+	if errConfig != nil {
+		return affected, errConfig
+	}
+	entityCache, err := getEntityStructCache(ctx, entity, config) // 预热缓存
+	if err != nil {
+		FuncLogError(ctx, err)
+		return affected, err
+	}
+	typeOf := reflect.TypeOf(entity)
+	execChunk := func(ctx context.Context, start int, end int) (int, error) {
+		chunkAffected := -1
+		// SQL语句的构造器
+		// SQL statement constructor
+		var sqlBuilder strings.Builder
+		sqlBuilder.Grow(stringBuilderGrowLen + 2*(end-start))
+		sqlBuilder.WriteString("DELETE FROM ")
+		sqlBuilder.WriteString(wrapTableName(ctx, config, entity.GetTableName()))
+		sqlBuilder.WriteString(" WHERE ")
+		sqlBuilder.WriteString(wrapQuoteIdentifier(config, entity.GetPKColumnName()))
+		sqlBuilder.WriteString(" IN (")
+		values := make([]interface{}, 0, end-start)
+		for i := start; i < end; i++ {
+			if entityStructSlice[i] == nil || reflect.TypeOf(entityStructSlice[i]) != typeOf {
+				return chunkAffected, errors.New("->DeleteSlice-->entityStructSlice必须是相同类型的非nil对象")
+			}
+			if i > start {
+				sqlBuilder.WriteByte(',')
+			}
+			sqlBuilder.WriteByte('?')
+			values = append(values, reflect.ValueOf(entityStructSlice[i]).Elem().FieldByIndex(entityCache.pkField.fieldIndex).Interface())
+		}
+		sqlBuilder.WriteByte(')')
+		sqlstr := sqlBuilder.String()
+		// 租户条件
+		// Tenant condition
+		if tenantField := entityTenantField(entity, entityCache); tenantField != nil {
+			if err := wrapTenantWhereSQL(ctx, config, tenantField.columnTag, &sqlstr, &values); err != nil {
+				return chunkAffected, err
+			}
+		}
+		// 包装update执行,赋值给影响的函数指针变量,返回*sql.Result
+		// Package update execution, assign it to the function pointer variable affected, and return *sql.Result
+		_, errexec := wrapExecUpdateValuesAffected(ctx, &chunkAffected, &sqlstr, &values, nil)
+		if errexec != nil {
+			errexec = fmt.Errorf("->DeleteSlice-->wrapExecUpdateValuesAffected执行删除错误:%w", errexec)
+			FuncLogError(ctx, errexec)
+		}
+		return chunkAffected, errexec
+	}
+	// 按照行数和参数数量的限制拆分成多条语句,在同一个事务中执行
+	// Split into several statements by the row and parameter limits, executed in one transaction
+	if batchRows := batchRowCount(config, 1); batchRows > 0 && len(entityStructSlice) > batchRows {
+		return execBatchChunks(ctx, len(entityStructSlice), batchRows, execChunk)
+	}
+	return execChunk(ctx, 0, len(entityStructSlice))
+}
+
 // InsertEntityMap 保存*IEntityMap对象.使用Map保存数据,用于不方便使用struct的场景,如果主键是自增或者序列,不要entityMap.Set主键的值
 // ctx不能为nil,参照使用zorm.Transaction方法传入ctx
 // affected影响的行数,如果异常或者驱动不支持,返回-1
@@ -1741,6 +2194,12 @@ var insertEntityMap = func(ctx context.Context, entity IEntityMap) (int, error)
 	if errConfig != nil {
 		return affected, errConfig
 	}
+	// 租户列赋值
+	// Assign the tenant column
+	if err := wrapTenantEntityMap(ctx, entity); err != nil {
+		FuncLogError(ctx, err)
+		return affected, err
+	}
 	// SQL语句
 	// SQL statement
 	sqlstr, values, autoIncrement, err := wrapInsertEntityMapSQL(ctx, config, entity)
@@ -1811,9 +2270,11 @@ var insertEntityMap = func(ctx context.Context, entity IEntityMap) (int, error)
 }
 
 // InsertEntityMapSlice 保存[]IEntityMap对象.使用Map保存数据,用于不方便使用struct的场景,如果主键是自增或者序列,不要entityMap.Set主键的值
+// 自增主键和InsertSlice一样,获取后entityMap.Set主键的值
 // ctx不能为nil,参照使用zorm.Transaction方法传入ctx
 // affected影响的行数,如果异常或者驱动不支持,返回-1
 // InsertEntityMapSlice saves [] IEntityMap objects. Use Map to save data, which is used in scenarios where struct is not convenient. If the primary key is self-incrementing or a sequence, do not entityMap.Set the value of the primary key
+// Like InsertSlice, the auto-incrementing primary keys are fetched and set with entityMap.Set
 // The number of rows affected by affected, if it is abnormal or the driver does not support it, return -1
 func InsertEntityMapSlice(ctx context.Context, entityMapSlice []IEntityMap) (int, error) {
 	return insertEntityMapSlice(ctx, entityMapSlice)
@@ -1836,6 +2297,32 @@ var insertEntityMapSlice = func(ctx context.Context, entityMapSlice []IEntityMap
 	if errConfig != nil {
 		return affected, errConfig
 	}
+	// 租户列赋值
+	for _, entity := range entityMapSlice {
+		if entity == nil {
+			continue
+		}
+		if err := wrapTenantEntityMap(ctx, entity); err != nil {
+			FuncLogError(ctx, err)
+			return affected, err
+		}
+	}
+	// 按照行数和参数数量的限制拆分成多条语句,在同一个事务中执行
+	if len(entityMapSlice) > 0 {
+		batchRows := batchRowCount(config, len(entityMapSlice[0].GetDBFieldMapKey()))
+		if batchRows > 0 && len(entityMapSlice) > batchRows {
+			return execBatchChunks(ctx, len(entityMapSlice), batchRows, func(ctx context.Context, start int, end int) (int, error) {
+				return insertEntityMapSliceStatement(ctx, config, entityMapSlice[start:end])
+			})
+		}
+	}
+	return insertEntityMapSliceStatement(ctx, config, entityMapSlice)
+}
+
+// insertEntityMapSliceStatement 使用一条语句保存entityMapSlice
+// insertEntityMapSliceStatement saves entityMapSlice with one statement
+func insertEntityMapSliceStatement(ctx context.Context, config *DataSourceConfig, entityMapSlice []IEntityMap) (int, error) {
+	affected := -1
 	// SQL语句
 	sqlstr, values, err := wrapInsertEntityMapSliceSQL(ctx, config, entityMapSlice)
 	if err != nil {
@@ -1844,14 +2331,46 @@ var insertEntityMapSlice = func(ctx context.Context, entityMapSlice []IEntityMap
 		return affected, err
 	}
 
-	// 包装update执行,赋值给影响的函数指针变量,返回*sql.Result
-	_, errexec := wrapExecUpdateValuesAffected(ctx, &affected, sqlstr, values, nil)
+	// 没有Set主键的值,认为是自增或者序列
+	// The primary key is not set, it is considered to be auto-increment or sequence
+	entity := entityMapSlice[0]
+	pkColumnName := entity.GetPKColumnName()
+	generatedKeys := GeneratedKeysNone
+	if _, hasPK := entity.GetDBFieldMap()[pkColumnName]; pkColumnName != "" && !hasPK {
+		generatedKeys = wrapAutoIncrementInsertSliceSQL(ctx, config, pkColumnName, sqlstr)
+	}
+	// 逐行插入,使用InsertEntityMap获取每行的自增主键
+	// Insert row by row, InsertEntityMap gets the auto-incrementing primary key of each row
+	if generatedKeys == GeneratedKeysPerRow {
+		affected = 0
+		for _, entityMap := range entityMapSlice {
+			rowAffected, err := insertEntityMap(ctx, entityMap)
+			if err != nil {
+				return affected, err
+			}
+			affected += rowAffected
+		}
+		return affected, nil
+	}
+
+	// 包装insert执行,赋值给影响的函数指针变量,返回每行的自增主键
+	ids, errexec := wrapExecInsertSliceValuesAffected(ctx, &affected, sqlstr, values, generatedKeys, len(entityMapSlice))
 	if errexec != nil {
-		errexec = fmt.Errorf("->InsertEntityMapSlice-->wrapExecUpdateValuesAffected执行保存错误:%w", errexec)
+		errexec = fmt.Errorf("->InsertEntityMapSlice-->wrapExecInsertSliceValuesAffected执行保存错误:%w", errexec)
 		FuncLogError(ctx, errexec)
 		return affected, errexec
 	}
-	return affected, errexec
+	for i, id := range ids {
+		autoIncrementIDInt, errConvert := typeConvertInt64toInt(id)
+		if errConvert != nil {
+			errConvert = fmt.Errorf("->InsertEntityMapSlice-->typeConvertInt64toInt自增主键转换错误:%w", errConvert)
+			FuncLogError(ctx, errConvert)
+			return affected, errConvert
+		}
+		// 设置自增主键的值
+		entityMapSlice[i].Set(pkColumnName, autoIncrementIDInt)
+	}
+	return affected, nil
 }
 
 // UpdateEntityMap 更新IEntityMap对象.用于不方便使用struct的场景,主键必须有值
@@ -1886,14 +2405,30 @@ var updateEntityMap = func(ctx context.Context, entity IEntityMap) (int, error)
 			return affected, errDBConnection
 		}
 	*/
+	// 从contxt中获取数据库连接,可能为nil
+	// Get database connection from contxt, may be nil
+	dbConnection, err := getDBConnectionFromContext(ctx)
+	if err != nil {
+		return affected, err
+	}
+	config, err := getConfigFromConnection(ctx, dbConnection, 1)
+	if err != nil {
+		return affected, err
+	}
 	// SQL语句
 	// SQL statement
-	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, entity)
+	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, config, entity)
 	if err != nil {
 		err = fmt.Errorf("->UpdateEntityMap-->wrapUpdateEntityMapSQL获取SQL语句错误:%w", err)
 		FuncLogError(ctx, err)
 		return affected, err
 	}
+	// 租户条件
+	// Tenant condition
+	if err = wrapTenantWhereSQL(ctx, config, tenantColumn(entity.GetTableName()), sqlstr, values); err != nil {
+		FuncLogError(ctx, err)
+		return affected, err
+	}
 	// 包装update执行,赋值给影响的函数指针变量,返回*sql.Result
 	_, errexec := wrapExecUpdateValuesAffected(ctx, &affected, sqlstr, values, nil)
 	if errexec != nil {
@@ -1988,15 +2523,15 @@ var wrapUpdateSQLValue = func(ctx context.Context, entity IEntityStruct, onlyUpd
 		return nil, nil, err
 	}
 
-	sqlstr, values, err := updateEntityFieldValues(ctx, entity, entityCache, onlyUpdateNotZero)
+	sqlstr, values, err := updateEntityFieldValues(ctx, config, entity, entityCache, onlyUpdateNotZero)
 	return sqlstr, values, err
 }
 
-// selectCount 根据finder查询总条数
+// selectCount 根据finder查询总条数,finder需要已经增加了租户条件
 // context必须传入,不能为空
-// selectCount Query the total number of items according to finder
+// selectCount Query the total number of items according to finder, the finder must already have the tenant condition
 // context must be passed in and cannot be empty
-func selectCount(ctx context.Context, finder *Finder) (int, error) {
+func selectCount(ctx context.Context, config *DataSourceConfig, finder *Finder) (int, error) {
 	if finder == nil {
 		return -1, errors.New("->selectCount-->finder参数为nil")
 	}
@@ -2004,14 +2539,14 @@ func selectCount(ctx context.Context, finder *Finder) (int, error) {
 	// Customized query total number Finder,mainly for the sake of performance in complex situations such as group by, manually write the total number of statements
 	if finder.CountFinder != nil {
 		count := -1
-		_, err := QueryRow(ctx, finder.CountFinder, &count)
+		_, err := queryRow(ctx, finder.CountFinder, &count)
 		if err != nil {
 			return -1, err
 		}
 		return count, nil
 	}
 
-	countsql, counterr := finder.GetSQL()
+	countsql, counterr := finder.getSQL(config)
 	if counterr != nil {
 		return -1, counterr
 	}
@@ -2023,6 +2558,11 @@ func selectCount(ctx context.Context, finder *Finder) (int, error) {
 	if sqlPart.OrderBy.Start != sqlPart.OrderBy.End {
 		countsql = countsql[:sqlPart.OrderBy.Start]
 	}
+	// WITH 子句保留在最前面,只包装主语句.sqlserver等数据库的子查询中不能使用 WITH
+	// The WITH clause stays in front, only the main statement is wrapped. WITH cannot be used in subqueries of databases like sqlserver
+	withSQL := countsql[:sqlPart.Select.Start]
+	countsql = countsql[sqlPart.Select.Start:]
+	fromIndex := sqlPart.From.Start - sqlPart.Select.Start
 
 	// 检查是否有 group by, distinct, union, intersect, except
 	hasGroupBy := sqlPart.GroupBy.Start != sqlPart.GroupBy.End
@@ -2031,32 +2571,31 @@ func selectCount(ctx context.Context, finder *Finder) (int, error) {
 	hasDistinct := sqlPart.Distinct.Start != sqlPart.Distinct.End
 	hasUnion := sqlPart.Union.Start != sqlPart.Union.End
 
-	var sqlBuilder strings.Builder
-	sqlBuilder.Grow(stringBuilderGrowLen)
+	dialect := getDialect(config)
+	if dialect == nil {
+		return -1, errors.New("->selectCount-->不支持的数据库类型:" + config.Dialect)
+	}
 	// 特殊关键字, 包装 SQL
 	// Special keywords, wrap SQL
-	if hasGroupBy || hasDistinct || hasUnion || hasIntersect || hasExcept {
-		// countsql = "SELECT COUNT(*)  temp_zorm_row_count FROM (" + countsql + ") temp_zorm_noob_table_name WHERE 1=1 "
-		sqlBuilder.WriteString("SELECT COUNT(*) AS temp_zorm_row_count FROM (")
-		sqlBuilder.WriteString(countsql)
-		sqlBuilder.WriteString(") temp_zorm_noob_table_name WHERE 1=1 ")
-	} else {
-		// 使用 Finder 中缓存的 FROM 子句位置
-		if sqlPart.From.Start == sqlPart.From.End {
-			return -1, errors.New("->selectCount-->没有 FROM 关键字, 语句错误")
-		}
-		// countsql = "SELECT COUNT(*) " + countsql[sqlPart.From.Start:]
-		sqlBuilder.WriteString("SELECT COUNT(*) ")
-		sqlBuilder.WriteString(countsql[sqlPart.From.Start:])
+	wrapSubQuery := hasGroupBy || hasDistinct || hasUnion || hasIntersect || hasExcept
+	// 使用 Finder 中缓存的 FROM 子句位置
+	if !wrapSubQuery && sqlPart.From.Start == sqlPart.From.End {
+		return -1, errors.New("->selectCount-->没有 FROM 关键字, 语句错误")
+	}
+	countsql, counterr = dialect.WrapCountSQL(countsql, fromIndex, wrapSubQuery)
+	if counterr != nil {
+		return -1, counterr
 	}
-	countsql = sqlBuilder.String()
 	countFinder := NewFinder()
-	countFinder.Append(countsql)
+	countFinder.Append(withSQL + countsql)
 	countFinder.values = finder.values
 	countFinder.InjectionCheck = finder.InjectionCheck
 
+	// finder已经增加了租户条件,COUNT的子查询中不再处理租户表
+	// The finder already has the tenant condition, tenant tables in the COUNT subquery are not processed again
+	countCtx := context.WithValue(ctx, contextIgnoreTenantValueKey, true)
 	count := -1
-	_, cerr := QueryRow(ctx, countFinder, &count)
+	_, cerr := queryRow(countCtx, countFinder, &count)
 	if cerr != nil {
 		return -1, cerr
 	}
@@ -2155,11 +2694,7 @@ func wrapExecUpdateValuesAffected(ctx context.Context, affected *int, sqlstrptr
 	var res *sql.Result
 	var errexec error
 	if lastInsertID != nil {
-		sqlrow, errrow := dbConnection.queryRowContext(ctx, sqlstrptr, values)
-		if errrow != nil {
-			return res, errrow
-		}
-		errexec = sqlrow.Scan(lastInsertID)
+		errexec = dbConnection.queryRowContext(ctx, sqlstrptr, values, lastInsertID)
 		if errexec == nil { // 如果插入成功,返回
 			*affected = 1
 			return res, errexec
@@ -2184,6 +2719,91 @@ func wrapExecUpdateValuesAffected(ctx context.Context, affected *int, sqlstrptr
 	return res, errAffected
 }
 
+// execBatchChunks 把total行按照每批batchRows行拆分,在同一个事务中依次执行execChunk,返回影响的总行数.有批次不支持返回影响的行数时,返回-1
+// execBatchChunks splits total rows into batches of batchRows and executes execChunk in order in one transaction, returns the total affected rows. Returns -1 if a batch does not support the affected rows
+func execBatchChunks(ctx context.Context, total int, batchRows int, execChunk func(ctx context.Context, start int, end int) (int, error)) (int, error) {
+	result, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		affected := 0
+		for start := 0; start < total; start += batchRows {
+			end := start + batchRows
+			if end > total {
+				end = total
+			}
+			chunkAffected, err := execChunk(ctx, start, end)
+			if err != nil {
+				return -1, err
+			}
+			if chunkAffected < 0 || affected < 0 {
+				affected = -1
+			} else {
+				affected += chunkAffected
+			}
+		}
+		return affected, nil
+	})
+	affected, ok := result.(int)
+	if !ok || err != nil {
+		affected = -1
+	}
+	return affected, err
+}
+
+// wrapExecInsertSliceValuesAffected 包装批量insert执行,赋值给影响的函数指针变量,按照generatedKeys返回每行的自增主键,不能获取时返回nil
+// wrapExecInsertSliceValuesAffected Package batch insert execution, assign it to the function pointer variable affected, return the auto-incrementing primary key of each row by generatedKeys, nil if they can not be fetched
+func wrapExecInsertSliceValuesAffected(ctx context.Context, affected *int, sqlstrptr *string, values *[]interface{}, generatedKeys GeneratedKeys, rowCount int) ([]int64, error) {
+	if generatedKeys != GeneratedKeysReturning {
+		res, errexec := wrapExecUpdateValuesAffected(ctx, affected, sqlstrptr, values, nil)
+		if errexec != nil || generatedKeys != GeneratedKeysLastInsertID {
+			return nil, errexec
+		}
+		// 需要数据库支持,第一行的自增主键
+		// Need database support, the auto-incrementing primary key of the first row
+		firstID, err := (*res).LastInsertId()
+		if err != nil || *affected != rowCount {
+			err = fmt.Errorf("->wrapExecInsertSliceValuesAffected-->LastInsertId数据库不支持批量自增主键,不再赋值给struct属性,affected:%d,err:%v", *affected, err)
+			FuncLogError(ctx, err)
+			return nil, nil
+		}
+		ids := make([]int64, rowCount)
+		for i := range ids {
+			ids[i] = firstID + int64(i)
+		}
+		return ids, nil
+	}
+
+	// 必须要有dbConnection和事务
+	// There must be a db Connection and transaction
+	var dbConnectionerr error
+	var dbConnection *dataBaseConnection
+	ctx, dbConnection, dbConnectionerr = checkDBConnection(ctx, dbConnection, true, 1)
+	if dbConnectionerr != nil {
+		return nil, dbConnectionerr
+	}
+	rows, errexec := dbConnection.queryContext(ctx, sqlstrptr, values)
+	if errexec != nil {
+		return nil, errexec
+	}
+	defer rows.Close()
+	ids := make([]int64, 0, rowCount)
+	for rows.Next() {
+		var id int64
+		if errexec = rows.Scan(&id); errexec != nil {
+			return nil, errexec
+		}
+		ids = append(ids, id)
+	}
+	if errexec = rows.Err(); errexec != nil {
+		return nil, errexec
+	}
+	*affected = len(ids)
+	if len(ids) != rowCount {
+		err := fmt.Errorf("->wrapExecInsertSliceValuesAffected-->返回的自增主键数量%d和插入的行数%d不一致,不再赋值给struct属性", len(ids), rowCount)
+		FuncLogError(ctx, err)
+		return nil, nil
+	}
+	return ids, nil
+}
+
 // contextSQLHintValueKey 把sql hint放到context里使用的key
 // contextSQLHintValueKey Put sql hint into context to use the key
 const contextSQLHintValueKey = wrapContextStringKey("contextSQLHintValueKey")
@@ -2203,6 +2823,107 @@ func BindContextSQLHint(parent context.Context, hint string) (context.Context, e
 	return ctx, nil
 }
 
+// contextSQLCommentValueKey 把sql尾部注释的键值放到context里使用的key
+// contextSQLCommentValueKey The key used to put the key-values of the trailing sql comment into context
+const contextSQLCommentValueKey = wrapContextStringKey("contextSQLCommentValueKey")
+
+// BindContextSQLComment context中绑定sql尾部注释的键值,使用这个Context的语句都会在末尾添加注释,例如 /*app='order',route='%2Forders'*/
+// 会保留parent中已经绑定的键值,相同的key会被覆盖.key和value会做URL编码,不会破坏SQL语句
+// BindContextSQLComment binds the key-value of the trailing sql comment to the context, statements using this Context add the comment at the end, e.g. /*app='order',route='%2Forders'*/
+// The key-values already bound in parent are kept, the same key is overwritten. key and value are URL encoded and can not break the SQL statement
+func BindContextSQLComment(parent context.Context, key string, value string) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextSQLComment-->context的parent不能为nil")
+	}
+	if key == "" {
+		return nil, errors.New("->BindContextSQLComment-->key不能为空")
+	}
+	comments := make(map[string]string)
+	if parentComments, ok := parent.Value(contextSQLCommentValueKey).(map[string]string); ok {
+		for k, v := range parentComments {
+			comments[k] = v
+		}
+	}
+	comments[key] = value
+	ctx := context.WithValue(parent, contextSQLCommentValueKey, comments)
+	return ctx, nil
+}
+
+// contextSchemaValueKey 把schema放到context里使用的key
+// contextSchemaValueKey The key used to put the schema into the context
+const contextSchemaValueKey = wrapContextStringKey("contextSchemaValueKey")
+
+// BindContextSchema context中绑定schema(owner),IEntityStruct和IEntityMap生成的语句使用 schema.表名 ,会替换表名中已有的schema.不影响Finder手写的语句
+// BindContextSchema binds the schema (owner) to the context, statements generated from IEntityStruct and IEntityMap use schema.tableName , the existing schema of the table name is replaced. Hand-written Finder statements are not affected
+func BindContextSchema(parent context.Context, schema string) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextSchema-->context的parent不能为nil")
+	}
+	ctx := context.WithValue(parent, contextSchemaValueKey, schema)
+	return ctx, nil
+}
+
+// contextTableNameValueKey 把表名映射放到context里使用的key
+// contextTableNameValueKey The key used to put the table name mapping into the context
+const contextTableNameValueKey = wrapContextStringKey("contextTableNameValueKey")
+
+// BindContextTableName context中绑定逻辑表名和实际表名的映射,例如 orders 映射为 tenant_42.orders_202610 ,用于分库分表.
+// IEntityStruct和IEntityMap生成的语句使用实际表名,不影响Finder手写的语句.tableName和GetTableName()的返回值相同,区分大小写.
+// 会保留parent中已经绑定的映射,相同的tableName会被覆盖.实际表名没有schema时,使用BindContextSchema的schema
+// BindContextTableName binds the mapping from a logical table name to the physical one, e.g. orders to tenant_42.orders_202610 , for sharding.
+// Statements generated from IEntityStruct and IEntityMap use the physical table name, hand-written Finder statements are not affected. tableName is the case sensitive value of GetTableName().
+// The mappings already bound in parent are kept, the same tableName is overwritten. The schema of BindContextSchema is used when the physical table name has no schema
+func BindContextTableName(parent context.Context, tableName string, physicalTableName string) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextTableName-->context的parent不能为nil")
+	}
+	if tableName == "" || physicalTableName == "" {
+		return nil, errors.New("->BindContextTableName-->tableName和physicalTableName不能为空")
+	}
+	tableNames := make(map[string]string)
+	if parentTableNames, ok := parent.Value(contextTableNameValueKey).(map[string]string); ok {
+		for k, v := range parentTableNames {
+			tableNames[k] = v
+		}
+	}
+	tableNames[tableName] = physicalTableName
+	ctx := context.WithValue(parent, contextTableNameValueKey, tableNames)
+	return ctx, nil
+}
+
+// contextTenantIDValueKey 把租户ID放到context里使用的key
+// contextTenantIDValueKey The key used to put the tenant ID into the context
+const contextTenantIDValueKey = wrapContextStringKey("contextTenantIDValueKey")
+
+// BindContextTenantID context中绑定租户ID.租户表的Insert,InsertSlice,InsertEntityMap使用租户ID给租户列赋值,
+// 生成的Update,Delete语句增加 AND 租户列=? 的条件,Finder的SELECT,UPDATE,DELETE语句自动增加租户条件.租户表参见RegisterTenantColumn
+// BindContextTenantID binds the tenant ID to the context. Insert, InsertSlice and InsertEntityMap of tenant tables assign the tenant ID to the tenant column,
+// the generated Update and Delete statements get the AND tenantColumn=? condition, SELECT, UPDATE and DELETE Finder statements get the tenant condition automatically. See RegisterTenantColumn for tenant tables
+func BindContextTenantID(parent context.Context, tenantID interface{}) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextTenantID-->context的parent不能为nil")
+	}
+	if tenantID == nil {
+		return nil, errors.New("->BindContextTenantID-->tenantID不能为nil")
+	}
+	ctx := context.WithValue(parent, contextTenantIDValueKey, tenantID)
+	return ctx, nil
+}
+
+// contextIgnoreTenantValueKey 是否忽略租户条件放到context里使用的key
+// contextIgnoreTenantValueKey Whether to ignore the tenant condition to put into context to use the key
+const contextIgnoreTenantValueKey = wrapContextStringKey("contextIgnoreTenantValueKey")
+
+// BindContextIgnoreTenant context忽略租户,不再赋值租户列,也不再增加租户条件.用于跨租户的管理任务,操作租户表时没有绑定租户ID会返回错误
+// BindContextIgnoreTenant context ignores the tenant, the tenant column is not assigned and the tenant condition is not added. Used for cross-tenant admin jobs, operating tenant tables without a bound tenant ID returns an error
+func BindContextIgnoreTenant(parent context.Context) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextIgnoreTenant-->context的parent不能为nil")
+	}
+	ctx := context.WithValue(parent, contextIgnoreTenantValueKey, true)
+	return ctx, nil
+}
+
 // contextEnableGlobalTransactionValueKey 是否使用分布式事务放到context里使用的key
 // contextEnableGlobalTransactionValueKey Whether to use distributed transactions to put into context to use the key
 const contextEnableGlobalTransactionValueKey = wrapContextStringKey("contextEnableGlobalTransactionValueKey")
@@ -0,0 +1,458 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordFakeDriver 测试用的数据库驱动,记录执行的语句.INSERT 影响 VALUES 的行数,LastInsertId()是11,
+// RETURNING 的语句返回101开始的主键,sql.Out 参数赋值201开始的主键
+// recordFakeDriver test driver recording the executed statements. INSERT affects the rows of VALUES, LastInsertId() is 11,
+// RETURNING statements return keys starting from 101, sql.Out parameters are assigned keys starting from 201
+type recordFakeDriver struct{}
+
+type recordFakeConn struct{ dsn string }
+
+type recordFakeStmt struct {
+	conn  *recordFakeConn
+	query string
+}
+
+type recordFakeTx struct{}
+
+type recordFakeResult struct{ rowsAffected int64 }
+
+type recordFakeRows struct{ values []int64 }
+
+// recordFakeStatements 每个DSN执行的语句
+// recordFakeStatements statements executed for each DSN
+var recordFakeStatements = struct {
+	sync.Mutex
+	m      map[string][]string
+	outIDs map[string]int64
+}{m: map[string][]string{}, outIDs: map[string]int64{}}
+
+func (recordFakeDriver) Open(dsn string) (driver.Conn, error) { return &recordFakeConn{dsn: dsn}, nil }
+func (c *recordFakeConn) Prepare(query string) (driver.Stmt, error) {
+	recordFakeStatements.Lock()
+	recordFakeStatements.m[c.dsn] = append(recordFakeStatements.m[c.dsn], query)
+	recordFakeStatements.Unlock()
+	return &recordFakeStmt{conn: c, query: query}, nil
+}
+func (c *recordFakeConn) Close() error              { return nil }
+func (c *recordFakeConn) Begin() (driver.Tx, error) { return recordFakeTx{}, nil }
+func (recordFakeTx) Commit() error                  { return nil }
+func (recordFakeTx) Rollback() error                { return nil }
+func (s *recordFakeStmt) Close() error              { return nil }
+func (s *recordFakeStmt) NumInput() int             { return -1 }
+func (r recordFakeResult) LastInsertId() (int64, error) {
+	return 11, nil
+}
+func (r recordFakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+func (r *recordFakeRows) Columns() []string             { return []string{"id"} }
+func (r *recordFakeRows) Close() error                  { return nil }
+
+// CheckNamedValue sql.Out参数赋值主键后移除
+// CheckNamedValue sql.Out parameters are assigned a key and removed
+func (c *recordFakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	out, ok := nv.Value.(sql.Out)
+	if !ok {
+		return driver.ErrSkip
+	}
+	recordFakeStatements.Lock()
+	recordFakeStatements.outIDs[c.dsn]++
+	*(out.Dest.(*int64)) = 200 + recordFakeStatements.outIDs[c.dsn]
+	recordFakeStatements.Unlock()
+	return driver.ErrRemoveArgument
+}
+
+// insertRowCount INSERT 语句 VALUES 的行数
+// insertRowCount rows of VALUES in an INSERT statement
+func insertRowCount(query string) int {
+	index := strings.Index(query, " VALUES")
+	if index < 0 {
+		return 1
+	}
+	return strings.Count(query[index:], "(")
+}
+
+func (s *recordFakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return recordFakeResult{rowsAffected: int64(insertRowCount(s.query))}, nil
+}
+
+func (s *recordFakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &recordFakeRows{}
+	if strings.Contains(s.query, " RETURNING ") {
+		for i := 0; i < insertRowCount(s.query); i++ {
+			rows.values = append(rows.values, int64(101+i))
+		}
+	}
+	return rows, nil
+}
+
+func (r *recordFakeRows) Next(dest []driver.Value) error {
+	if len(r.values) < 1 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("zorm_record_fake", recordFakeDriver{})
+}
+
+// newRecordFakeContext 使用recordFakeDriver的dbDao,返回绑定数据库连接的ctx和执行语句的函数
+// newRecordFakeContext dbDao of recordFakeDriver, returns the ctx bound to the database connection and a function returning the executed statements
+func newRecordFakeContext(t *testing.T, config *DataSourceConfig) (context.Context, func() []string) {
+	config.DSN = t.Name()
+	config.DriverName = "zorm_record_fake"
+	dbDao, err := NewDBDao(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordFakeStatements.Lock()
+	delete(recordFakeStatements.m, config.DSN)
+	delete(recordFakeStatements.outIDs, config.DSN)
+	recordFakeStatements.Unlock()
+	return ctx, func() []string {
+		recordFakeStatements.Lock()
+		defer recordFakeStatements.Unlock()
+		return recordFakeStatements.m[config.DSN]
+	}
+}
+
+// testAutoIncrementEntity 自增主键的实体类
+// testAutoIncrementEntity entity with an auto-increment primary key
+type testAutoIncrementEntity struct {
+	EntityStruct
+	ID   int64  `column:"id"`
+	Name string `column:"name"`
+}
+
+func (entity *testAutoIncrementEntity) GetTableName() string {
+	return "t_auto"
+}
+
+func Test_InsertSlice_generatedKeys(t *testing.T) {
+	tests := []struct {
+		dialect    string
+		version    string
+		contiguous bool
+		sqlstr     string
+		ids        []int64
+	}{
+		{dialect: "mysql", contiguous: true, sqlstr: "INSERT INTO t_auto(name) VALUES(?),(?),(?)", ids: []int64{11, 12, 13}},
+		{dialect: "mysql", sqlstr: "INSERT INTO t_auto(name) VALUES(?),(?),(?)", ids: []int64{0, 0, 0}},
+		{dialect: "postgresql", sqlstr: "INSERT INTO t_auto(name) VALUES($1),($2),($3) RETURNING id", ids: []int64{101, 102, 103}},
+		{dialect: "sqlite", sqlstr: "INSERT INTO t_auto(name) VALUES(?),(?),(?) RETURNING id", ids: []int64{101, 102, 103}},
+		{dialect: "sqlite", version: "3.34.1", sqlstr: "INSERT INTO t_auto(name) VALUES(?),(?),(?)", ids: []int64{0, 0, 0}},
+		{dialect: "mssql", sqlstr: "INSERT INTO t_auto(name) VALUES(@p1)", ids: []int64{11, 11, 11}},
+		{dialect: "oracle", sqlstr: "INSERT INTO t_auto(name) VALUES(:1) RETURNING id INTO :2 ", ids: []int64{201, 202, 203}},
+		{dialect: "db2", sqlstr: "INSERT INTO t_auto(name) VALUES(?),(?),(?)", ids: []int64{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect+tt.version, func(t *testing.T) {
+			ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: tt.dialect, DialectVersion: tt.version, ContiguousAutoIncrement: tt.contiguous})
+			entities := []IEntityStruct{&testAutoIncrementEntity{Name: "a"}, &testAutoIncrementEntity{Name: "b"}, &testAutoIncrementEntity{Name: "c"}}
+			affected, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+				return InsertSlice(ctx, entities)
+			})
+			if err != nil || affected != 3 {
+				t.Fatalf("InsertSlice() = %v, %v", affected, err)
+			}
+			if got := statements(); got[len(got)-1] != tt.sqlstr {
+				t.Errorf("InsertSlice() SQL = %q, want %q", got[len(got)-1], tt.sqlstr)
+			}
+			ids := make([]int64, 0, len(entities))
+			for _, entity := range entities {
+				ids = append(ids, entity.(*testAutoIncrementEntity).ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("InsertSlice() ids = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func Test_InsertEntityMapSlice_generatedKeys(t *testing.T) {
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "postgresql"})
+	entityMaps := []IEntityMap{NewEntityMap("t_auto"), NewEntityMap("t_auto")}
+	entityMaps[0].Set("name", "a")
+	entityMaps[1].Set("name", "b")
+	affected, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return InsertEntityMapSlice(ctx, entityMaps)
+	})
+	if err != nil || affected != 2 {
+		t.Fatalf("InsertEntityMapSlice() = %v, %v", affected, err)
+	}
+	if got := statements(); got[len(got)-1] != "INSERT INTO t_auto(name) VALUES ($1), ($2) RETURNING id" {
+		t.Errorf("InsertEntityMapSlice() SQL = %q", got[len(got)-1])
+	}
+	for i, entityMap := range entityMaps {
+		if id := entityMap.GetDBFieldMap()["id"]; id != 101+i {
+			t.Errorf("InsertEntityMapSlice() id = %v, want %d", id, 101+i)
+		}
+	}
+}
+
+// recordSQLHook 记录After的操作类型和错误
+// recordSQLHook records the operation and error of After
+type recordSQLHook struct{ events []SQLHookEvent }
+
+func (hook *recordSQLHook) Before(ctx context.Context, event *SQLHookEvent) context.Context {
+	return ctx
+}
+
+func (hook *recordSQLHook) After(ctx context.Context, event *SQLHookEvent) {
+	hook.events = append(hook.events, *event)
+}
+
+func Test_Insert_queryRowHook(t *testing.T) {
+	hook := &recordSQLHook{}
+	ctx, _ := newRecordFakeContext(t, &DataSourceConfig{Dialect: "postgresql", SQLHooks: []ISQLHook{hook}})
+	entity := &testAutoIncrementEntity{Name: "a"}
+	_, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return Insert(ctx, entity)
+	})
+	if err != nil || entity.ID != 101 {
+		t.Fatalf("Insert() = %v, %v", entity.ID, err)
+	}
+	for _, event := range hook.events {
+		if event.Operation != SQLHookOperationQueryRow {
+			continue
+		}
+		if event.Err != nil || event.SQL != "INSERT INTO t_auto(name) VALUES($1) RETURNING id" {
+			t.Errorf("queryRow hook = %q, %v", event.SQL, event.Err)
+		}
+		return
+	}
+	t.Errorf("queryRow hook not called, events = %+v", hook.events)
+}
+
+func Test_InsertSlice_batch(t *testing.T) {
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "mysql", BatchMaxRows: 2, ContiguousAutoIncrement: true})
+	entities := make([]IEntityStruct, 0, 5)
+	entityMaps := make([]IEntityMap, 0, 5)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		entities = append(entities, &testAutoIncrementEntity{Name: name})
+		entityMap := NewEntityMap("t_auto")
+		entityMap.Set("name", name)
+		entityMaps = append(entityMaps, entityMap)
+	}
+	affected, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return InsertSlice(ctx, entities)
+	})
+	if err != nil || affected != 5 {
+		t.Fatalf("InsertSlice() = %v, %v", affected, err)
+	}
+	affected, err = Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return InsertEntityMapSlice(ctx, entityMaps)
+	})
+	if err != nil || affected != 5 {
+		t.Fatalf("InsertEntityMapSlice() = %v, %v", affected, err)
+	}
+	want := []string{
+		"INSERT INTO t_auto(name) VALUES(?),(?)", "INSERT INTO t_auto(name) VALUES(?),(?)", "INSERT INTO t_auto(name) VALUES(?)",
+		"INSERT INTO t_auto(name) VALUES (?), (?)", "INSERT INTO t_auto(name) VALUES (?), (?)", "INSERT INTO t_auto(name) VALUES (?)",
+	}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+	if id := entities[2].(*testAutoIncrementEntity).ID; id != 11 {
+		t.Errorf("id of the first row of the second batch = %d, want 11", id)
+	}
+}
+
+// testUpdateEntity 字符串主键的实体类
+// testUpdateEntity entity with a string primary key
+type testUpdateEntity struct {
+	EntityStruct
+	ID   string `column:"id"`
+	Name string `column:"name"`
+	Age  int    `column:"age"`
+}
+
+func (entity *testUpdateEntity) GetTableName() string {
+	return "t_update"
+}
+
+func Test_UpdateSlice_DeleteSlice(t *testing.T) {
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "postgresql", BatchMaxRows: 2})
+	entities := []IEntityStruct{&testUpdateEntity{ID: "1", Name: "a"}, &testUpdateEntity{ID: "2", Age: 20}, &testUpdateEntity{ID: "3", Name: "c", Age: 30}}
+	_, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		if _, err := UpdateSlice(ctx, entities); err != nil {
+			return nil, err
+		}
+		if _, err := UpdateNotZeroValueSlice(ctx, entities); err != nil {
+			return nil, err
+		}
+		onlyCtx, _ := BindContextOnlyUpdateCols(ctx, []string{"age"})
+		if _, err := UpdateSlice(onlyCtx, entities[:1]); err != nil {
+			return nil, err
+		}
+		mustCtx, _ := BindContextMustUpdateCols(ctx, []string{"age"})
+		if _, err := UpdateNotZeroValueSlice(mustCtx, entities[:1]); err != nil {
+			return nil, err
+		}
+		return DeleteSlice(ctx, entities)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"UPDATE t_update SET name=CASE id WHEN $1 THEN $2 WHEN $3 THEN $4 ELSE name END,age=CASE id WHEN $5 THEN $6 WHEN $7 THEN $8 ELSE age END WHERE id IN ($9,$10)",
+		"UPDATE t_update SET name=CASE id WHEN $1 THEN $2 ELSE name END,age=CASE id WHEN $3 THEN $4 ELSE age END WHERE id IN ($5)",
+		"UPDATE t_update SET name=CASE id WHEN $1 THEN $2 ELSE name END,age=CASE id WHEN $3 THEN $4 ELSE age END WHERE id IN ($5,$6)",
+		"UPDATE t_update SET name=CASE id WHEN $1 THEN $2 ELSE name END,age=CASE id WHEN $3 THEN $4 ELSE age END WHERE id IN ($5)",
+		"UPDATE t_update SET age=CASE id WHEN $1 THEN $2 ELSE age END WHERE id IN ($3)",
+		"UPDATE t_update SET name=CASE id WHEN $1 THEN $2 ELSE name END,age=CASE id WHEN $3 THEN $4 ELSE age END WHERE id IN ($5)",
+		"DELETE FROM t_update WHERE id IN ($1,$2)",
+		"DELETE FROM t_update WHERE id IN ($1)",
+	}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+// testQuoteEntity 列名是关键字的实体类
+// testQuoteEntity entity with keyword column names
+type testQuoteEntity struct {
+	EntityStruct
+	ID    int    `column:"id"`
+	Order string `column:"order"`
+	Desc  string "column:\"`desc`\""
+}
+
+func (entity *testQuoteEntity) GetTableName() string {
+	return "db.user"
+}
+
+func Test_QuoteIdentifier(t *testing.T) {
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "mysql", QuoteIdentifier: true})
+	_, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		entity := &testQuoteEntity{Order: "a", Desc: "b"}
+		if _, err := Insert(ctx, entity); err != nil {
+			return nil, err
+		}
+		if _, err := Update(ctx, entity); err != nil {
+			return nil, err
+		}
+		if _, err := UpdateSlice(ctx, []IEntityStruct{entity}); err != nil {
+			return nil, err
+		}
+		if _, err := Delete(ctx, entity); err != nil {
+			return nil, err
+		}
+		entityMap := NewEntityMap("user")
+		entityMap.Set("order", "a")
+		if _, err := InsertEntityMap(ctx, entityMap); err != nil {
+			return nil, err
+		}
+		return UpdateEntityMap(ctx, entityMap)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"INSERT INTO `db`.`user`(`order`,`desc`) VALUES(?,?)",
+		"UPDATE `db`.`user` SET `order`=?,`desc`=? WHERE `id`=?",
+		"UPDATE `db`.`user` SET `order`=CASE `id` WHEN ? THEN ? ELSE `order` END,`desc`=CASE `id` WHEN ? THEN ? ELSE `desc` END WHERE `id` IN (?)",
+		"DELETE FROM `db`.`user` WHERE `id`=?",
+		"INSERT INTO `user`(`order`) VALUES (?)",
+		"UPDATE `user` SET `order`=? WHERE `id`=?",
+	}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func Test_BindContextTableName(t *testing.T) {
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "mysql"})
+	_, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		entity := &testUpdateEntity{ID: "1", Name: "a", Age: 1}
+		mapCtx, _ := BindContextTableName(ctx, "t_update", "tenant_42.t_update_202610")
+		if _, err := Insert(mapCtx, entity); err != nil {
+			return nil, err
+		}
+		if _, err := Update(mapCtx, entity); err != nil {
+			return nil, err
+		}
+		if _, err := Delete(mapCtx, entity); err != nil {
+			return nil, err
+		}
+		schemaCtx, _ := BindContextSchema(ctx, "tenant_43")
+		if _, err := DeleteSlice(schemaCtx, []IEntityStruct{entity}); err != nil {
+			return nil, err
+		}
+		entityMap := NewEntityMap("t_map")
+		entityMap.Set("name", "a")
+		if _, err := InsertEntityMap(schemaCtx, entityMap); err != nil {
+			return nil, err
+		}
+		// 同时绑定schema和映射,映射的实际表名有schema时保留
+		// Both the schema and the mapping are bound, the schema of the physical table name is kept
+		bothCtx, _ := BindContextTableName(schemaCtx, "t_update", "tenant_42.t_update_202610")
+		bothCtx, _ = BindContextTableName(bothCtx, "t_map", "t_map_202610")
+		if _, err := Update(bothCtx, entity); err != nil {
+			return nil, err
+		}
+		entityMap = NewEntityMap("t_map")
+		entityMap.Set("name", "b")
+		if _, err := InsertEntityMap(bothCtx, entityMap); err != nil {
+			return nil, err
+		}
+		// 缓存的语句不受映射影响
+		// The cached statements are not affected by the mapping
+		if _, err := Insert(ctx, entity); err != nil {
+			return nil, err
+		}
+		return Delete(ctx, entity)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"INSERT INTO tenant_42.t_update_202610(id,name,age) VALUES(?,?,?)",
+		"UPDATE tenant_42.t_update_202610 SET name=?,age=? WHERE id=?",
+		"DELETE FROM tenant_42.t_update_202610 WHERE id=?",
+		"DELETE FROM tenant_43.t_update WHERE id IN (?)",
+		"INSERT INTO tenant_43.t_map(name) VALUES (?)",
+		"UPDATE tenant_42.t_update_202610 SET name=?,age=? WHERE id=?",
+		"INSERT INTO tenant_43.t_map_202610(name) VALUES (?)",
+		"INSERT INTO t_update(id,name,age) VALUES(?,?,?)",
+		"DELETE FROM t_update WHERE id=?",
+	}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
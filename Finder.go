@@ -21,6 +21,7 @@ package zorm
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -33,8 +34,9 @@ type Finder struct {
 	// SQL的参数值
 	// SQL parameter values.
 	values []interface{}
-	// 注入检查,默认true 不允许SQL注入的 ' 单引号
-	// Injection check, default true does not allow SQL injection  single quote
+	// 注入检查,默认true,使用FinderInjectionRules的规则检查多条语句,注释,恒真条件,字符串后的UNION和OR条件等.词法检查不能发现所有拼接参数的注入,字符串参数请使用?占位符
+	// Injection check, default true, stacked statements, comments, tautologies, UNION and OR conditions after a string etc. are checked with the rules of FinderInjectionRules.
+	// The lexical check cannot find every injection of concatenated parameters, use ? placeholders for string parameters
 	InjectionCheck bool `json:"injectionCheck"`
 	// CountFinder 自定义的查询总条数'Finder',使用指针默认为nil.主要是为了在'group by'等复杂情况下,为了性能,手动编写总条数语句
 	// CountFinder The total number of custom queries is'Finder', and the pointer is nil by default. It is mainly used to manually write the total number of statements for performance in complex situations such as'group by'
@@ -165,9 +167,15 @@ func (finder *Finder) AppendFinder(f *Finder) (*Finder, error) {
 	return finder, nil
 }
 
-// GetSQL 返回Finder封装的SQL语句
-// GetSQL Return the SQL statement encapsulated by the Finder
+// GetSQL 返回Finder封装的SQL语句,注入检查使用defaultDao的数据库方言
+// GetSQL Return the SQL statement encapsulated by the Finder, the injection check uses the database dialect of defaultDao
 func (finder *Finder) GetSQL() (string, error) {
+	return finder.getSQL(nil)
+}
+
+// getSQL 返回Finder封装的SQL语句,注入检查使用config的数据库方言,config为nil时使用defaultDao的
+// getSQL returns the SQL statement encapsulated by the Finder, the injection check uses the database dialect of config, defaultDao's is used when config is nil
+func (finder *Finder) getSQL(config *DataSourceConfig) (string, error) {
 	// 不要自己构建finder,使用NewFinder方法
 	// Don't build finder by yourself, use NewFinder method
 	if finder == nil || finder.values == nil {
@@ -177,10 +185,19 @@ func (finder *Finder) GetSQL() (string, error) {
 		return finder.sqlstr, nil
 	}
 	sqlstr := finder.sqlBuilder.String()
-	// 包含单引号,属于非法字符串
-	// Contains single quotes, which are illegal strings
-	if finder.InjectionCheck && (strings.Contains(sqlstr, "'")) {
-		return "", errors.New(`->finder-->GetSQL()SQL语句请不要直接拼接字符串参数,容易注入!!!请使用问号占位符,例如 finder.Append("and id=?","stringId"),如果必须拼接字符串,请设置 finder.InjectionCheck = false `)
+	// 词法检查注入风险,规则参见FinderInjectionRules
+	// Lexical check of the injection risk, see FinderInjectionRules for the rules
+	if finder.InjectionCheck {
+		if config == nil && defaultDao != nil {
+			config = defaultDao.config
+		}
+		var dialect IDialect
+		if config != nil {
+			dialect = getDialect(config)
+		}
+		if err := CheckDialectSQLInjection(dialect, sqlstr, FinderInjectionRules); err != nil {
+			return "", fmt.Errorf(`->finder-->GetSQL()SQL语句请不要直接拼接参数,容易注入!!!请使用问号占位符,例如 finder.Append("and id=?","stringId"),如果确认安全,请设置 finder.InjectionCheck = false :%w`, err)
+		}
 	}
 	finder.sqlstr = sqlstr
 	finder.sqlPartCache = parseSQL(sqlstr)
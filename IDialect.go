@@ -0,0 +1,1013 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IDialect 数据库方言接口,处理不同数据库的SQL差异.内置了mysql,postgresql,oracle,mssql,sqlite,db2,clickhouse,dm,kingbase,gbase,shentong,tdengine
+// 新的数据库实现这个接口,使用RegisterDialect注册,DataSourceConfig.Dialect配置为Name()的值.可以嵌入BaseDialect,只实现有差异的方法
+// IDialect database dialect interface, handles the SQL differences of databases. Built in: mysql,postgresql,oracle,mssql,sqlite,db2,clickhouse,dm,kingbase,gbase,shentong,tdengine
+// A new database implements this interface and registers it with RegisterDialect, DataSourceConfig.Dialect is configured as the value of Name(). BaseDialect can be embedded and only the different methods implemented
+type IDialect interface {
+	// Name 方言名称,和DataSourceConfig.Dialect对应,例如 mysql
+	// Name of the dialect, corresponds to DataSourceConfig.Dialect, e.g. mysql
+	Name() string
+
+	// Placeholder 第index个参数(从1开始)的占位符,例如 ? $1 @p1 :1 .value是参数的反射值,基础类型的参数value无效
+	// Placeholder placeholder of the index-th parameter (starting from 1), e.g. ? $1 @p1 :1 . value is the reflect value of the parameter, it is invalid for parameters of basic types
+	Placeholder(index int, value reflect.Value) string
+
+	// WrapPageSQL 包装分页语句,offset是跳过的行数,pageSize是每页行数,orderByIndex是sqlstr中 ORDER BY 关键字的位置,没有 order by 为-1
+	// WrapPageSQL wraps the paging statement, offset is the number of rows skipped, pageSize is the rows per page, orderByIndex is the position of the ORDER BY keyword in sqlstr, -1 without order by
+	WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error)
+
+	// WrapCountSQL 包装查询总条数的语句.wrapSubQuery为true时使用子查询包装countSQL,否则使用fromIndex位置开始的 FROM 子句
+	// WrapCountSQL wraps the statement querying the total count. When wrapSubQuery is true countSQL is wrapped in a subquery, otherwise the FROM clause starting at fromIndex is used
+	WrapCountSQL(countSQL string, fromIndex int, wrapSubQuery bool) (string, error)
+
+	// WrapAutoIncrementInsertSQL 自增主键的插入语句,返回主键的获取方式.
+	// lastInsertID不为nil,使用QueryRow获取 RETURNING 的主键;zormSQLOutReturningID不为nil,使用sql.Out参数获取主键;都为nil使用LastInsertId()
+	// WrapAutoIncrementInsertSQL insert statement with auto-increment primary key, returns how the key is fetched.
+	// lastInsertID not nil: QueryRow gets the RETURNING key; zormSQLOutReturningID not nil: the key is got by a sql.Out parameter; both nil: LastInsertId() is used
+	WrapAutoIncrementInsertSQL(pkColumnName string, sqlstr *string, values *[]interface{}) (lastInsertID *int64, zormSQLOutReturningID *int64)
+
+	// WrapAutoIncrementInsertSliceSQL 批量插入自增主键的语句,返回每行主键的获取方式.GeneratedKeysReturning 需要修改sqlstr,返回按照插入顺序的主键结果集
+	// WrapAutoIncrementInsertSliceSQL batch insert statement with auto-increment primary key, returns how the key of each row is fetched. GeneratedKeysReturning modifies sqlstr to return the keys in insert order
+	WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys
+
+	// MaxParams 每条语句的最大参数数量,例如mssql的2100,返回0不限制.批量操作超过时拆分成多条语句
+	// MaxParams maximum parameters of a statement, e.g. 2100 of mssql, 0 means no limit. Batch operations exceeding it are split into several statements
+	MaxParams() int
+
+	// ReBuildUpdateSQL 重建UPDATE和DELETE语句,用于特殊语法,例如clickhouse的 ALTER TABLE ... UPDATE
+	// ReBuildUpdateSQL rebuilds UPDATE and DELETE statements for special syntax, e.g. clickhouse ALTER TABLE ... UPDATE
+	ReBuildUpdateSQL(sqlstr *string) error
+
+	// QuoteIdentifier 包裹表名或者列名,例如mysql的`name`和postgresql的"name"
+	// QuoteIdentifier quotes a table or column name, e.g. `name` of mysql and "name" of postgresql
+	QuoteIdentifier(name string) string
+
+	// UpsertSQL 在insertSQL后拼接主键冲突时更新的语句,updateColumnNames为空时冲突不做处理.不支持的数据库返回错误
+	// UpsertSQL appends the statement updating on primary key conflict to insertSQL, nothing is done on conflict when updateColumnNames is empty. Returns an error for unsupported databases
+	UpsertSQL(insertSQL string, pkColumnNames []string, updateColumnNames []string) (string, error)
+
+	// SavepointSQL 创建保存点的语句,不支持时返回""
+	// SavepointSQL statement creating a savepoint, "" if unsupported
+	SavepointSQL(name string) string
+
+	// RollbackToSavepointSQL 回滚到保存点的语句,不支持时返回""
+	// RollbackToSavepointSQL statement rolling back to a savepoint, "" if unsupported
+	RollbackToSavepointSQL(name string) string
+
+	// ReleaseSavepointSQL 释放保存点的语句,不需要时返回""
+	// ReleaseSavepointSQL statement releasing a savepoint, "" if not needed
+	ReleaseSavepointSQL(name string) string
+
+	// SupportSQLComment 是否支持在语句末尾添加 /* */ 注释
+	// SupportSQLComment whether a /* */ comment can be appended to statements
+	SupportSQLComment() bool
+
+	// SupportBackslashEscape 字符串中的 \ 是否是转义字符,例如mysql的 'it\'s' ,用于SQL注入检查
+	// SupportBackslashEscape whether \ is an escape character in strings, e.g. 'it\'s' of mysql, used by the SQL injection check
+	SupportBackslashEscape() bool
+
+	// ExplainSQLPrefix 执行计划的语句前缀,例如 EXPLAIN FORMAT=JSON ,不支持时返回""
+	// ExplainSQLPrefix statement prefix of the execution plan, e.g. EXPLAIN FORMAT=JSON , "" if unsupported
+	ExplainSQLPrefix() string
+}
+
+// GeneratedKeys 批量插入时自增主键的获取方式
+// GeneratedKeys how the auto-increment primary keys are fetched in a batch insert
+type GeneratedKeys int
+
+const (
+	// GeneratedKeysNone 不获取自增主键,struct的主键属性不赋值
+	// GeneratedKeysNone the auto-increment keys are not fetched, the primary key field of the struct is not assigned
+	GeneratedKeysNone GeneratedKeys = iota
+	// GeneratedKeysReturning 语句按照插入顺序返回主键的结果集,例如 RETURNING id
+	// GeneratedKeysReturning the statement returns a result set of the keys in insert order, e.g. RETURNING id
+	GeneratedKeysReturning
+	// GeneratedKeysLastInsertID LastInsertId()是第一行的主键,后续行连续递增,例如mysql的 auto_increment_increment=1 并且 innodb_autoinc_lock_mode 0 或 1
+	// GeneratedKeysLastInsertID LastInsertId() is the key of the first row and the following rows are contiguous, e.g. mysql with auto_increment_increment=1 and innodb_autoinc_lock_mode 0 or 1
+	GeneratedKeysLastInsertID
+	// GeneratedKeysPerRow 逐行插入,使用WrapAutoIncrementInsertSQL获取每行的主键,例如oracle
+	// GeneratedKeysPerRow rows are inserted one by one, WrapAutoIncrementInsertSQL fetches the key of each row, e.g. oracle
+	GeneratedKeysPerRow
+)
+
+// IDialectVersion 可选实现的接口,根据DataSourceConfig.DialectVersion返回对应数据库版本的方言,例如oracle 11g的ROWNUM分页
+// IDialectVersion optional interface, returns the dialect of the database version of DataSourceConfig.DialectVersion, e.g. ROWNUM paging of oracle 11g
+type IDialectVersion interface {
+	// DialectOfVersion 返回version版本的方言,不需要区分时返回自己
+	// DialectOfVersion returns the dialect of version, returns itself when no distinction is needed
+	DialectOfVersion(version string) IDialect
+}
+
+// IStickyPlaceholder 可选实现的接口,数组参数展开为多个占位符时,返回true的占位符会用于数组后面所有的元素,例如tdengine字符类型的 '?'
+// IStickyPlaceholder optional interface, when an array parameter is expanded into several placeholders, a placeholder returning true is used for all the following elements of the array, e.g. the '?' of tdengine string kind
+type IStickyPlaceholder interface {
+	// StickyPlaceholder placeholder是否用于数组后面所有的元素
+	// StickyPlaceholder whether placeholder is used for all the following elements of the array
+	StickyPlaceholder(placeholder string) bool
+}
+
+// dialectMap 已经注册的数据库方言,key是Name()
+// dialectMap registered database dialects, the key is Name()
+var dialectMap = sync.Map{}
+
+func init() {
+	// 内置的数据库方言
+	// Built-in database dialects
+	dialects := []IDialect{
+		mysqlDialect{},
+		postgresqlDialect{name: "postgresql"},
+		oracleDialect{name: "oracle"},
+		mssqlDialect{},
+		sqliteDialect{},
+		namedDialect{name: "db2"},
+		clickhouseDialect{},
+		namedDialect{name: "dm"},
+		postgresqlDialect{name: "kingbase"},
+		namedDialect{name: "gbase"},
+		shentongDialect{oracleDialect{name: "shentong"}},
+		tdengineDialect{},
+	}
+	for _, dialect := range dialects {
+		dialectMap.Store(dialect.Name(), dialect)
+	}
+}
+
+// RegisterDialect 注册数据库方言,相同Name()的方言会被覆盖,可以用来修改内置的方言.一般是放到init方法里进行注册
+// RegisterDialect registers a database dialect, a dialect with the same Name() is overwritten, which can be used to modify the built-in dialects. It is usually registered in the init method
+func RegisterDialect(dialect IDialect) error {
+	if dialect == nil {
+		return errors.New("->RegisterDialect-->dialect不能为nil")
+	}
+	if dialect.Name() == "" {
+		return errors.New("->RegisterDialect-->dialect.Name()不能为空")
+	}
+	dialectMap.Store(dialect.Name(), dialect)
+	return nil
+}
+
+// GetDialect 根据名称获取已经注册的数据库方言,没有注册返回nil
+// GetDialect gets a registered database dialect by name, nil if not registered
+func GetDialect(name string) IDialect {
+	dialect, ok := dialectMap.Load(name)
+	if !ok {
+		return nil
+	}
+	return dialect.(IDialect)
+}
+
+// dialectNames 已经注册的方言名称,按照名称排序
+// dialectNames names of the registered dialects, sorted by name
+func dialectNames() []string {
+	names := make([]string, 0, 16)
+	dialectMap.Range(func(key, value interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// getDialect 获取config的方言,实现了IDialectVersion时按照DialectVersion获取对应版本的方言,没有注册返回nil
+// getDialect gets the dialect of config, when IDialectVersion is implemented the dialect of DialectVersion is used, nil if not registered
+func getDialect(config *DataSourceConfig) IDialect {
+	dialect := GetDialect(config.Dialect)
+	if dialect == nil {
+		return nil
+	}
+	if config.DialectVersion != "" {
+		if dialectVersion, ok := dialect.(IDialectVersion); ok {
+			return dialectVersion.DialectOfVersion(config.DialectVersion)
+		}
+	}
+	return dialect
+}
+
+// majorVersion 版本号开头的数字,例如 11g 是11, 2008R2 是2008, 10.50 是10,没有数字返回0
+// majorVersion leading digits of the version, e.g. 11 of 11g, 2008 of 2008R2, 10 of 10.50, 0 without digits
+func majorVersion(version string) int {
+	end := 0
+	for end < len(version) && version[end] >= '0' && version[end] <= '9' {
+		end++
+	}
+	major, _ := strconv.Atoi(version[:end])
+	return major
+}
+
+// versionBefore version是否小于major.minor版本,例如 3.34.1 小于 3.35,没有数字返回false
+// versionBefore whether version is less than major.minor, e.g. 3.34.1 is less than 3.35, false without digits
+func versionBefore(version string, major int, minor int) bool {
+	versionMajor := majorVersion(version)
+	if versionMajor == 0 || versionMajor != major {
+		return versionMajor > 0 && versionMajor < major
+	}
+	index := strings.IndexByte(version, '.')
+	if index < 0 {
+		return minor > 0
+	}
+	return majorVersion(version[index+1:]) < minor
+}
+
+// BaseDialect 方言的默认实现,用于嵌入自定义的方言,只需要实现Name()和有差异的方法
+// 默认 ? 占位符, LIMIT offset,pageSize 分页, SELECT COUNT(*) 总条数, 不获取批量插入的自增主键, SQL标准的双引号和保存点, 支持注释, \ 不转义, 不支持upsert和执行计划
+// BaseDialect default implementation of the dialect for embedding into custom dialects, only Name() and the different methods need to be implemented
+// Default ? placeholder, LIMIT offset,pageSize paging, SELECT COUNT(*) total count, no auto-increment keys of batch inserts, SQL standard double quotes and savepoints, comments supported, \ is not an escape, no upsert and execution plan
+type BaseDialect struct{}
+
+// Name 需要自定义的方言实现
+// Name needs to be implemented by the custom dialect
+func (BaseDialect) Name() string {
+	return ""
+}
+
+// Placeholder ? 占位符
+// Placeholder ? placeholder
+func (BaseDialect) Placeholder(index int, value reflect.Value) string {
+	return "?"
+}
+
+// WrapPageSQL LIMIT offset,pageSize 分页
+// WrapPageSQL LIMIT offset,pageSize paging
+func (BaseDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	sqlBuilder.WriteString(sqlstr)
+	sqlBuilder.WriteString(" LIMIT ")
+	sqlBuilder.WriteString(strconv.Itoa(offset))
+	sqlBuilder.WriteByte(',')
+	sqlBuilder.WriteString(strconv.Itoa(pageSize))
+	return sqlBuilder.String(), nil
+}
+
+// WrapCountSQL SELECT COUNT(*) 查询总条数
+// WrapCountSQL SELECT COUNT(*) queries the total count
+func (BaseDialect) WrapCountSQL(countSQL string, fromIndex int, wrapSubQuery bool) (string, error) {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	if wrapSubQuery {
+		// countsql = "SELECT COUNT(*)  temp_zorm_row_count FROM (" + countsql + ") temp_zorm_noob_table_name WHERE 1=1 "
+		sqlBuilder.WriteString("SELECT COUNT(*) AS temp_zorm_row_count FROM (")
+		sqlBuilder.WriteString(countSQL)
+		sqlBuilder.WriteString(") temp_zorm_noob_table_name WHERE 1=1 ")
+	} else {
+		if fromIndex < 0 || fromIndex >= len(countSQL) {
+			return "", errors.New("->WrapCountSQL-->没有 FROM 关键字, 语句错误")
+		}
+		// countsql = "SELECT COUNT(*) " + countsql[sqlPart.From.Start:]
+		sqlBuilder.WriteString("SELECT COUNT(*) ")
+		sqlBuilder.WriteString(countSQL[fromIndex:])
+	}
+	return sqlBuilder.String(), nil
+}
+
+// WrapAutoIncrementInsertSQL 不修改语句,使用LastInsertId()
+// WrapAutoIncrementInsertSQL the statement is not modified, LastInsertId() is used
+func (BaseDialect) WrapAutoIncrementInsertSQL(pkColumnName string, sqlstr *string, values *[]interface{}) (*int64, *int64) {
+	return nil, nil
+}
+
+// WrapAutoIncrementInsertSliceSQL 默认不获取自增主键
+// WrapAutoIncrementInsertSliceSQL auto-increment keys are not fetched by default
+func (BaseDialect) WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys {
+	return GeneratedKeysNone
+}
+
+// MaxParams 默认不限制
+// MaxParams no limit by default
+func (BaseDialect) MaxParams() int {
+	return 0
+}
+
+// ReBuildUpdateSQL 不处理
+// ReBuildUpdateSQL does nothing
+func (BaseDialect) ReBuildUpdateSQL(sqlstr *string) error {
+	return nil
+}
+
+// QuoteIdentifier SQL标准的双引号
+// QuoteIdentifier SQL standard double quotes
+func (BaseDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// UpsertSQL 不支持
+// UpsertSQL is not supported
+func (BaseDialect) UpsertSQL(insertSQL string, pkColumnNames []string, updateColumnNames []string) (string, error) {
+	return "", errors.New("->UpsertSQL-->数据库不支持upsert")
+}
+
+// SavepointSQL SQL标准的 SAVEPOINT name
+// SavepointSQL SQL standard SAVEPOINT name
+func (BaseDialect) SavepointSQL(name string) string {
+	return "SAVEPOINT " + name
+}
+
+// RollbackToSavepointSQL SQL标准的 ROLLBACK TO SAVEPOINT name
+// RollbackToSavepointSQL SQL standard ROLLBACK TO SAVEPOINT name
+func (BaseDialect) RollbackToSavepointSQL(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+// ReleaseSavepointSQL SQL标准的 RELEASE SAVEPOINT name
+// ReleaseSavepointSQL SQL standard RELEASE SAVEPOINT name
+func (BaseDialect) ReleaseSavepointSQL(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
+// SupportSQLComment 支持注释
+// SupportSQLComment comments are supported
+func (BaseDialect) SupportSQLComment() bool {
+	return true
+}
+
+// SupportBackslashEscape SQL标准的字符串, \ 不是转义字符
+// SupportBackslashEscape standard SQL strings, \ is not an escape character
+func (BaseDialect) SupportBackslashEscape() bool {
+	return false
+}
+
+// ExplainSQLPrefix 不支持执行计划
+// ExplainSQLPrefix execution plan is not supported
+func (BaseDialect) ExplainSQLPrefix() string {
+	return ""
+}
+
+// namedDialect 和BaseDialect行为一致的方言,例如 dm,gbase,db2 7.2+
+// namedDialect dialects behaving like BaseDialect, e.g. dm,gbase,db2 7.2+
+type namedDialect struct {
+	BaseDialect
+	name string
+}
+
+func (d namedDialect) Name() string {
+	return d.name
+}
+
+// mysqlDialect MySQL
+type mysqlDialect struct {
+	BaseDialect
+}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) SupportBackslashEscape() bool {
+	return true
+}
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (mysqlDialect) UpsertSQL(insertSQL string, pkColumnNames []string, updateColumnNames []string) (string, error) {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(len(insertSQL) + stringBuilderGrowLen)
+	sqlBuilder.WriteString(insertSQL)
+	sqlBuilder.WriteString(" ON DUPLICATE KEY UPDATE ")
+	if len(updateColumnNames) < 1 {
+		if len(pkColumnNames) < 1 {
+			return "", errors.New("->UpsertSQL-->pkColumnNames不能为空")
+		}
+		// 冲突时不做处理
+		// Nothing is done on conflict
+		updateColumnNames = pkColumnNames[:1]
+	}
+	for i, column := range updateColumnNames {
+		if i > 0 {
+			sqlBuilder.WriteByte(',')
+		}
+		sqlBuilder.WriteString(column)
+		sqlBuilder.WriteString("=VALUES(")
+		sqlBuilder.WriteString(column)
+		sqlBuilder.WriteByte(')')
+	}
+	return sqlBuilder.String(), nil
+}
+
+// WrapAutoIncrementInsertSliceSQL 多行插入的LastInsertId()是第一行的主键,DataSourceConfig.ContiguousAutoIncrement为true时后续行按照LastInsertId()+i赋值.
+// 要求 auto_increment_increment=1 并且 innodb_autoinc_lock_mode 是0或者1,否则赋值的主键和数据库不一致
+// WrapAutoIncrementInsertSliceSQL LastInsertId() of a multi-row insert is the key of the first row, the following rows are assigned LastInsertId()+i when DataSourceConfig.ContiguousAutoIncrement is true.
+// auto_increment_increment=1 and innodb_autoinc_lock_mode 0 or 1 are required, otherwise the assigned keys differ from the database
+func (mysqlDialect) WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys {
+	return GeneratedKeysLastInsertID
+}
+
+// MaxParams 预处理语句最多65535个参数
+// MaxParams prepared statements have at most 65535 parameters
+func (mysqlDialect) MaxParams() int {
+	return 65535
+}
+
+func (mysqlDialect) ExplainSQLPrefix() string {
+	return "EXPLAIN FORMAT=JSON "
+}
+
+// sqliteDialect sqlite3
+type sqliteDialect struct {
+	BaseDialect
+	// noReturning 3.35之前的版本不支持 RETURNING
+	// noReturning versions before 3.35 do not support RETURNING
+	noReturning bool
+	// maxVariableNumber 版本对应的SQLITE_MAX_VARIABLE_NUMBER,0使用默认值
+	// maxVariableNumber SQLITE_MAX_VARIABLE_NUMBER of the version, 0 uses the default
+	maxVariableNumber int
+}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+// DialectOfVersion 3.35之前的版本批量插入不获取自增主键,3.32之前的版本最多999个参数
+// DialectOfVersion versions before 3.35 do not fetch auto-increment keys in batch inserts, versions before 3.32 have at most 999 parameters
+func (d sqliteDialect) DialectOfVersion(version string) IDialect {
+	d.noReturning = versionBefore(version, 3, 35)
+	if versionBefore(version, 3, 32) {
+		d.maxVariableNumber = 999
+	}
+	return d
+}
+
+// MaxParams SQLITE_MAX_VARIABLE_NUMBER,3.32之前的版本默认999,之后默认32766
+// MaxParams SQLITE_MAX_VARIABLE_NUMBER, 999 by default before 3.32, 32766 after
+func (d sqliteDialect) MaxParams() int {
+	if d.maxVariableNumber > 0 {
+		return d.maxVariableNumber
+	}
+	return 32766
+}
+
+// WrapAutoIncrementInsertSliceSQL 3.35+ 使用 RETURNING 获取主键
+// WrapAutoIncrementInsertSliceSQL 3.35+ RETURNING gets the primary keys
+func (d sqliteDialect) WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys {
+	if d.noReturning {
+		return GeneratedKeysNone
+	}
+	appendReturningSQL(pkColumnName, sqlstr)
+	return GeneratedKeysReturning
+}
+
+func (sqliteDialect) UpsertSQL(insertSQL string, pkColumnNames []string, updateColumnNames []string) (string, error) {
+	return onConflictUpsertSQL(insertSQL, pkColumnNames, updateColumnNames)
+}
+
+func (sqliteDialect) ExplainSQLPrefix() string {
+	return "EXPLAIN QUERY PLAN "
+}
+
+// clickhouseDialect ClickHouse
+type clickhouseDialect struct {
+	BaseDialect
+}
+
+func (clickhouseDialect) Name() string {
+	return "clickhouse"
+}
+
+func (clickhouseDialect) SupportBackslashEscape() bool {
+	return true
+}
+
+// ReBuildUpdateSQL 处理clickhouse的 ALTER TABLE tableName UPDATE 和 ALTER TABLE tableName DELETE 语法
+// ReBuildUpdateSQL handles the ALTER TABLE tableName UPDATE and ALTER TABLE tableName DELETE syntax of clickhouse
+func (clickhouseDialect) ReBuildUpdateSQL(sqlstr *string) error {
+	// 处理clickhouse的特殊更新语法
+	sqlByte := []byte(*sqlstr)
+	// 获取第一个单词
+	firstWord, start, end, err := firstOneWord(0, sqlByte)
+	if err != nil {
+		return err
+	}
+	if start == -1 || end == -1 { // 未取到字符串
+		return nil
+	}
+	// SQL语句的构造器
+	// SQL statement constructor
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	sqlBuilder.WriteString((*sqlstr)[:start])
+	sqlBuilder.WriteString("ALTER TABLE ")
+	firstWord = strings.ToUpper(firstWord)
+	tableName := ""
+	switch firstWord {
+	case "UPDATE": // 更新  update tableName set
+		tableName, _, end, err = firstOneWord(end, sqlByte)
+		if err != nil {
+			return err
+		}
+		// 拿到 set
+		_, start, end, err = firstOneWord(end, sqlByte)
+
+	case "DELETE": // 删除 delete from tableName
+		// 拿到from
+		_, _, end, err = firstOneWord(end, sqlByte)
+		if err != nil {
+			return err
+		}
+		// 拿到 tableName
+		tableName, start, end, err = firstOneWord(end, sqlByte)
+	default: // 只处理UPDATE 和 DELETE 语法
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if start == -1 || end == -1 { // 获取的位置异常
+		return errors.New("->reBuildUpdateSQL中clickhouse语法异常,请检查sql语句是否标准,-->zormErrorExecSQL:" + *sqlstr)
+	}
+	sqlBuilder.WriteString(tableName)
+	sqlBuilder.WriteByte(' ')
+	sqlBuilder.WriteString(firstWord)
+	// sqlBuilder.WriteByte(' ')
+	sqlBuilder.WriteString((*sqlstr)[end:])
+	*sqlstr = sqlBuilder.String()
+	return nil
+}
+
+func (clickhouseDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (clickhouseDialect) SavepointSQL(name string) string {
+	return ""
+}
+
+func (clickhouseDialect) RollbackToSavepointSQL(name string) string {
+	return ""
+}
+
+func (clickhouseDialect) ReleaseSavepointSQL(name string) string {
+	return ""
+}
+
+func (clickhouseDialect) ExplainSQLPrefix() string {
+	return "EXPLAIN "
+}
+
+// tdengineDialect TDengine
+type tdengineDialect struct {
+	BaseDialect
+}
+
+func (tdengineDialect) Name() string {
+	return "tdengine"
+}
+
+// Placeholder 字符类型的参数使用 '?'
+// Placeholder parameters of string kind use '?'
+func (tdengineDialect) Placeholder(index int, value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return "'?'"
+	}
+	return "?"
+}
+
+// StickyPlaceholder 字符类型的 '?' 出现后,数组后面的元素都使用 '?'
+// StickyPlaceholder once the '?' of string kind appears, the following elements of the array all use '?'
+func (tdengineDialect) StickyPlaceholder(placeholder string) bool {
+	return placeholder == "'?'"
+}
+
+func (tdengineDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (tdengineDialect) SavepointSQL(name string) string {
+	return ""
+}
+
+func (tdengineDialect) RollbackToSavepointSQL(name string) string {
+	return ""
+}
+
+func (tdengineDialect) ReleaseSavepointSQL(name string) string {
+	return ""
+}
+
+func (tdengineDialect) SupportSQLComment() bool {
+	return false
+}
+
+func (tdengineDialect) SupportBackslashEscape() bool {
+	return true
+}
+
+// postgresqlDialect postgresql,kingbase
+type postgresqlDialect struct {
+	BaseDialect
+	name string
+}
+
+func (d postgresqlDialect) Name() string {
+	return d.name
+}
+
+func (postgresqlDialect) Placeholder(index int, value reflect.Value) string {
+	return "$" + strconv.Itoa(index)
+}
+
+// WrapPageSQL LIMIT pageSize OFFSET offset 分页
+// WrapPageSQL LIMIT pageSize OFFSET offset paging
+func (postgresqlDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	return limitOffsetPageSQL(sqlstr, offset, pageSize), nil
+}
+
+// WrapAutoIncrementInsertSQL 使用 RETURNING 获取主键
+// WrapAutoIncrementInsertSQL RETURNING gets the primary key
+func (postgresqlDialect) WrapAutoIncrementInsertSQL(pkColumnName string, sqlstr *string, values *[]interface{}) (*int64, *int64) {
+	var p int64 = 0
+	appendReturningSQL(pkColumnName, sqlstr)
+	return &p, nil
+}
+
+// WrapAutoIncrementInsertSliceSQL 使用 RETURNING 获取主键
+// WrapAutoIncrementInsertSliceSQL RETURNING gets the primary keys
+func (postgresqlDialect) WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys {
+	appendReturningSQL(pkColumnName, sqlstr)
+	return GeneratedKeysReturning
+}
+
+func (postgresqlDialect) UpsertSQL(insertSQL string, pkColumnNames []string, updateColumnNames []string) (string, error) {
+	return onConflictUpsertSQL(insertSQL, pkColumnNames, updateColumnNames)
+}
+
+// MaxParams 协议限制最多65535个参数
+// MaxParams the protocol allows at most 65535 parameters
+func (postgresqlDialect) MaxParams() int {
+	return 65535
+}
+
+func (postgresqlDialect) ExplainSQLPrefix() string {
+	return "EXPLAIN (FORMAT JSON) "
+}
+
+// oracleDialect oracle 12c+
+type oracleDialect struct {
+	BaseDialect
+	name string
+}
+
+func (d oracleDialect) Name() string {
+	return d.name
+}
+
+func (oracleDialect) Placeholder(index int, value reflect.Value) string {
+	return ":" + strconv.Itoa(index)
+}
+
+// WrapPageSQL OFFSET offset ROWS FETCH NEXT pageSize ROWS ONLY 分页,没有 order by 时增加默认的排序
+// WrapPageSQL OFFSET offset ROWS FETCH NEXT pageSize ROWS ONLY paging, a default order is added without order by
+func (oracleDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	return offsetFetchPageSQL(sqlstr, offset, pageSize, orderByIndex >= 0, " ORDER BY NULL "), nil
+}
+
+// WrapAutoIncrementInsertSQL 使用 RETURNING pk INTO 的sql.Out参数获取主键
+// oracle 12c+ 支持IDENTITY属性的自增列,因为分页也要求12c+的语法,所以数据库就IDENTITY创建自增吧
+// WrapAutoIncrementInsertSQL the sql.Out parameter of RETURNING pk INTO gets the primary key
+func (oracleDialect) WrapAutoIncrementInsertSQL(pkColumnName string, sqlstr *string, values *[]interface{}) (*int64, *int64) {
+	var p int64 = 0
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	sqlBuilder.WriteString(*sqlstr)
+	// 不要使用命名参数,统一使用占位符
+	// sqlstr = sqlstr + " RETURNING " + pkColumnName + " INTO :zormSQLOutReturningID "
+	sqlBuilder.WriteString(" RETURNING ")
+	sqlBuilder.WriteString(pkColumnName)
+	sqlBuilder.WriteString(" INTO ? ")
+	v := sql.Out{Dest: &p}
+	*values = append(*values, v)
+	*sqlstr = sqlBuilder.String()
+	return nil, &p
+}
+
+// WrapAutoIncrementInsertSliceSQL RETURNING INTO 只能返回一行,逐行插入
+// WrapAutoIncrementInsertSliceSQL RETURNING INTO returns only one row, rows are inserted one by one
+func (oracleDialect) WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys {
+	return GeneratedKeysPerRow
+}
+
+// MaxParams 最多65535个绑定变量
+// MaxParams at most 65535 bind variables
+func (oracleDialect) MaxParams() int {
+	return 65535
+}
+
+// DialectOfVersion 12c之前的版本使用ROWNUM分页
+// DialectOfVersion versions before 12c use ROWNUM paging
+func (d oracleDialect) DialectOfVersion(version string) IDialect {
+	if major := majorVersion(version); major > 0 && major < 12 {
+		return oracleRownumDialect{d}
+	}
+	return d
+}
+
+// ReleaseSavepointSQL oracle不需要释放保存点
+// ReleaseSavepointSQL oracle does not need to release savepoints
+func (oracleDialect) ReleaseSavepointSQL(name string) string {
+	return ""
+}
+
+// oracleRownumDialect oracle 11g及之前的版本,使用ROWNUM嵌套分页,结果集会多一列 temp_zorm_rownum ,Query和QueryMap忽略这一列
+// oracleRownumDialect oracle 11g and earlier, nested ROWNUM paging, the result set has an extra column temp_zorm_rownum , which is ignored by Query and QueryMap
+type oracleRownumDialect struct {
+	oracleDialect
+}
+
+// WrapPageSQL SELECT * FROM (SELECT t.*,ROWNUM rn FROM (sqlstr) t WHERE ROWNUM <= offset+pageSize) WHERE rn > offset
+func (oracleRownumDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(len(sqlstr) + stringBuilderGrowLen)
+	sqlBuilder.WriteString("SELECT * FROM (SELECT temp_zorm_page_table.*,ROWNUM temp_zorm_rownum FROM (")
+	sqlBuilder.WriteString(sqlstr)
+	sqlBuilder.WriteString(") temp_zorm_page_table WHERE ROWNUM <= ")
+	sqlBuilder.WriteString(strconv.Itoa(offset + pageSize))
+	sqlBuilder.WriteString(") WHERE temp_zorm_rownum > ")
+	sqlBuilder.WriteString(strconv.Itoa(offset))
+	return sqlBuilder.String(), nil
+}
+
+func (d oracleRownumDialect) DialectOfVersion(version string) IDialect {
+	return d.oracleDialect.DialectOfVersion(version)
+}
+
+// shentongDialect 神通数据库,占位符和主键和oracle一致,分页和postgresql一致
+// shentongDialect shentong database, placeholder and primary key like oracle, paging like postgresql
+type shentongDialect struct {
+	oracleDialect
+}
+
+// DialectOfVersion 神通数据库不区分版本
+// DialectOfVersion shentong does not distinguish versions
+func (d shentongDialect) DialectOfVersion(version string) IDialect {
+	return d
+}
+
+func (shentongDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	return limitOffsetPageSQL(sqlstr, offset, pageSize), nil
+}
+
+// mssqlDialect sqlserver 2012+
+type mssqlDialect struct {
+	BaseDialect
+}
+
+func (mssqlDialect) Name() string {
+	return "mssql"
+}
+
+func (mssqlDialect) Placeholder(index int, value reflect.Value) string {
+	return "@p" + strconv.Itoa(index)
+}
+
+func (mssqlDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	return offsetFetchPageSQL(sqlstr, offset, pageSize, orderByIndex >= 0, " ORDER BY (SELECT NULL) "), nil
+}
+
+// DialectOfVersion 2012之前的版本(例如 2008,2008R2,10.50)使用ROW_NUMBER()分页
+// DialectOfVersion versions before 2012 (e.g. 2008,2008R2,10.50) use ROW_NUMBER() paging
+func (d mssqlDialect) DialectOfVersion(version string) IDialect {
+	major := majorVersion(version)
+	if (major >= 2000 && major < 2012) || (major > 0 && major < 11) {
+		return mssqlRowNumberDialect{d}
+	}
+	return d
+}
+
+// WrapAutoIncrementInsertSliceSQL OUTPUT INSERTED 返回的顺序不保证和 VALUES 的顺序一致,无法对应到每行,逐行插入,和Insert一样获取每行的主键
+// WrapAutoIncrementInsertSliceSQL the order returned by OUTPUT INSERTED is not guaranteed to match the order of VALUES and can not be mapped to each row, rows are inserted one by one and the key of each row is fetched like Insert
+func (mssqlDialect) WrapAutoIncrementInsertSliceSQL(pkColumnName string, sqlstr *string) GeneratedKeys {
+	return GeneratedKeysPerRow
+}
+
+// MaxParams 最多2100个参数
+// MaxParams at most 2100 parameters
+func (mssqlDialect) MaxParams() int {
+	return 2100
+}
+
+func (mssqlDialect) QuoteIdentifier(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+func (mssqlDialect) SavepointSQL(name string) string {
+	return "SAVE TRANSACTION " + name
+}
+
+func (mssqlDialect) RollbackToSavepointSQL(name string) string {
+	return "ROLLBACK TRANSACTION " + name
+}
+
+func (mssqlDialect) ReleaseSavepointSQL(name string) string {
+	return ""
+}
+
+// mssqlRowNumberDialect sqlserver 2008,使用ROW_NUMBER() OVER(ORDER BY ...)分页,结果集会多一列 temp_zorm_rownum ,Query和QueryMap忽略这一列
+// mssqlRowNumberDialect sqlserver 2008, ROW_NUMBER() OVER(ORDER BY ...) paging, the result set has an extra column temp_zorm_rownum , which is ignored by Query and QueryMap
+type mssqlRowNumberDialect struct {
+	mssqlDialect
+}
+
+// WrapPageSQL 去掉 order by 后包装为子查询,在外层增加 ROW_NUMBER() OVER(ORDER BY ...) 列并按照行号过滤.
+// ORDER BY 的列去掉表别名,引用子查询的列,可以使用SELECT列表中的别名,兼容 DISTINCT,TOP,UNION,INTERSECT,EXCEPT.ORDER BY 的列需要在SELECT列表中
+// WrapPageSQL removes order by and wraps the SQL in a subquery, the outer query adds the ROW_NUMBER() OVER(ORDER BY ...) column and filters by row number.
+// Table aliases are removed from the ORDER BY columns, which reference the columns of the subquery, so aliases of the select list can be used, compatible with DISTINCT,TOP,UNION,INTERSECT,EXCEPT. The ORDER BY columns need to be in the select list
+func (mssqlRowNumberDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	orderBy := "(SELECT NULL)"
+	if orderByIndex >= 0 {
+		// 跳过 ORDER BY 两个单词
+		// Skip the two words ORDER BY
+		sqlByte := []byte(sqlstr)
+		_, _, end, err := firstOneWord(orderByIndex, sqlByte)
+		if err != nil {
+			return "", err
+		}
+		_, _, end, err = firstOneWord(end, sqlByte)
+		if err != nil {
+			return "", err
+		}
+		if end < 0 {
+			return "", errors.New("->WrapPageSQL-->ORDER BY 语句错误:" + sqlstr)
+		}
+		orderBy = strings.TrimSpace(sqlstr[end:])
+		sqlstr = sqlstr[:orderByIndex]
+	}
+
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(len(sqlstr) + len(orderBy) + stringBuilderGrowLen)
+	sqlBuilder.WriteString("SELECT * FROM (SELECT temp_zorm_page_table.*,ROW_NUMBER() OVER(ORDER BY ")
+	sqlBuilder.WriteString(unqualifyColumns(orderBy))
+	sqlBuilder.WriteString(") AS temp_zorm_rownum FROM (")
+	sqlBuilder.WriteString(sqlstr)
+	sqlBuilder.WriteString(") temp_zorm_page_table) temp_zorm_page_table2 WHERE temp_zorm_rownum BETWEEN ")
+	sqlBuilder.WriteString(strconv.Itoa(offset + 1))
+	sqlBuilder.WriteString(" AND ")
+	sqlBuilder.WriteString(strconv.Itoa(offset + pageSize))
+	sqlBuilder.WriteString(" ORDER BY temp_zorm_rownum")
+	return sqlBuilder.String(), nil
+}
+
+func (d mssqlRowNumberDialect) DialectOfVersion(version string) IDialect {
+	return d.mssqlDialect.DialectOfVersion(version)
+}
+
+// pageRownumColumnIndex 分页语句增加的行号列 temp_zorm_rownum 在结果集中的位置,没有分页或者没有这一列时返回-1
+// pageRownumColumnIndex position of the row number column temp_zorm_rownum added by the page statement in the result set, -1 without paging or without the column
+func pageRownumColumnIndex(columnTypes []*sql.ColumnType, page *Page) int {
+	if page == nil {
+		return -1
+	}
+	for i, columnType := range columnTypes {
+		if strings.EqualFold(columnType.Name(), "temp_zorm_rownum") {
+			return i
+		}
+	}
+	return -1
+}
+
+// unqualifyColumns 去掉列名的表别名,例如 u.name DESC 转为 name DESC ,字符串中的内容不处理
+// unqualifyColumns removes table aliases of columns, e.g. u.name DESC becomes name DESC , content of strings is not processed
+func unqualifyColumns(columns string) string {
+	sc := &sqlScanner{sqlStr: columns, sqlLen: len(columns)}
+	var builder strings.Builder
+	builder.Grow(len(columns))
+	for sc.index < sc.sqlLen {
+		c := columns[sc.index]
+		if c == '\'' {
+			start := sc.index
+			sc.skipString()
+			builder.WriteString(columns[start:sc.index])
+			continue
+		}
+		if !isIdentChar(c) && c != '[' && c != '"' {
+			builder.WriteByte(c)
+			sc.index++
+			continue
+		}
+		// 标识符,可能带包裹符号
+		// Identifier, may be quoted
+		start := sc.index
+		if c == '[' || c == '"' {
+			end := byte(']')
+			if c == '"' {
+				end = '"'
+			}
+			sc.index++
+			for sc.index < sc.sqlLen && columns[sc.index] != end {
+				sc.index++
+			}
+			sc.index++
+		} else {
+			for sc.index < sc.sqlLen && isIdentChar(columns[sc.index]) {
+				sc.index++
+			}
+		}
+		if sc.index < sc.sqlLen && columns[sc.index] == '.' {
+			// 表别名,跳过
+			// Table alias, skipped
+			sc.index++
+			continue
+		}
+		if sc.index > sc.sqlLen {
+			sc.index = sc.sqlLen
+		}
+		builder.WriteString(columns[start:sc.index])
+	}
+	return builder.String()
+}
+
+// appendReturningSQL 在sqlstr后拼接 RETURNING pkColumnName
+// appendReturningSQL appends RETURNING pkColumnName to sqlstr
+func appendReturningSQL(pkColumnName string, sqlstr *string) {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	sqlBuilder.WriteString(*sqlstr)
+	// sqlstr = sqlstr + " RETURNING " + pkColumnName
+	sqlBuilder.WriteString(" RETURNING ")
+	sqlBuilder.WriteString(pkColumnName)
+	*sqlstr = sqlBuilder.String()
+}
+
+// limitOffsetPageSQL LIMIT pageSize OFFSET offset 分页
+// limitOffsetPageSQL LIMIT pageSize OFFSET offset paging
+func limitOffsetPageSQL(sqlstr string, offset int, pageSize int) string {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	sqlBuilder.WriteString(sqlstr)
+	sqlBuilder.WriteString(" LIMIT ")
+	sqlBuilder.WriteString(strconv.Itoa(pageSize))
+	sqlBuilder.WriteString(" OFFSET ")
+	sqlBuilder.WriteString(strconv.Itoa(offset))
+	return sqlBuilder.String()
+}
+
+// offsetFetchPageSQL OFFSET offset ROWS FETCH NEXT pageSize ROWS ONLY 分页,没有 order by 时增加defaultOrderBy
+// offsetFetchPageSQL OFFSET offset ROWS FETCH NEXT pageSize ROWS ONLY paging, defaultOrderBy is added without order by
+func offsetFetchPageSQL(sqlstr string, offset int, pageSize int, hasOrderBy bool, defaultOrderBy string) string {
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(stringBuilderGrowLen)
+	sqlBuilder.WriteString(sqlstr)
+	if !hasOrderBy { // 如果没有 order by,增加默认的排序
+		sqlBuilder.WriteString(defaultOrderBy)
+	}
+	sqlBuilder.WriteString(" OFFSET ")
+	sqlBuilder.WriteString(strconv.Itoa(offset))
+	sqlBuilder.WriteString(" ROWS FETCH NEXT ")
+	sqlBuilder.WriteString(strconv.Itoa(pageSize))
+	sqlBuilder.WriteString(" ROWS ONLY ")
+	return sqlBuilder.String()
+}
+
+// onConflictUpsertSQL postgresql和sqlite的 ON CONFLICT 语法
+// onConflictUpsertSQL ON CONFLICT syntax of postgresql and sqlite
+func onConflictUpsertSQL(insertSQL string, pkColumnNames []string, updateColumnNames []string) (string, error) {
+	if len(pkColumnNames) < 1 {
+		return "", errors.New("->UpsertSQL-->pkColumnNames不能为空")
+	}
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(len(insertSQL) + stringBuilderGrowLen)
+	sqlBuilder.WriteString(insertSQL)
+	sqlBuilder.WriteString(" ON CONFLICT (")
+	sqlBuilder.WriteString(strings.Join(pkColumnNames, ","))
+	if len(updateColumnNames) < 1 {
+		sqlBuilder.WriteString(") DO NOTHING")
+		return sqlBuilder.String(), nil
+	}
+	sqlBuilder.WriteString(") DO UPDATE SET ")
+	for i, column := range updateColumnNames {
+		if i > 0 {
+			sqlBuilder.WriteByte(',')
+		}
+		sqlBuilder.WriteString(column)
+		sqlBuilder.WriteString("=EXCLUDED.")
+		sqlBuilder.WriteString(column)
+	}
+	return sqlBuilder.String(), nil
+}
@@ -0,0 +1,248 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Test_IDialect_builtin 内置方言的SQL和重构前保持一致
+// Test_IDialect_builtin SQL of the built-in dialects is the same as before the refactoring
+func Test_IDialect_builtin(t *testing.T) {
+	type myString string
+	tests := []struct {
+		dialect    string
+		pageSQL    string
+		rebuildSQL string
+		insertSQL  string
+	}{
+		{dialect: "mysql", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN (?,?) AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "sqlite", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN (?,?) AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "dm", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN (?,?) AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "gbase", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN (?,?) AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "db2", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN (?,?) AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "clickhouse", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN (?,?) AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "tdengine", pageSQL: "SELECT * FROM t LIMIT 20,10", rebuildSQL: "a=? AND b IN ('?','?') AND c=?", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+		{dialect: "postgresql", pageSQL: "SELECT * FROM t LIMIT 10 OFFSET 20", rebuildSQL: "a=$1 AND b IN ($2,$3) AND c=$4", insertSQL: "INSERT INTO t(a) VALUES (?) RETURNING id"},
+		{dialect: "kingbase", pageSQL: "SELECT * FROM t LIMIT 10 OFFSET 20", rebuildSQL: "a=$1 AND b IN ($2,$3) AND c=$4", insertSQL: "INSERT INTO t(a) VALUES (?) RETURNING id"},
+		{dialect: "shentong", pageSQL: "SELECT * FROM t LIMIT 10 OFFSET 20", rebuildSQL: "a=:1 AND b IN (:2,:3) AND c=:4", insertSQL: "INSERT INTO t(a) VALUES (?) RETURNING id INTO ? "},
+		{dialect: "oracle", pageSQL: "SELECT * FROM t ORDER BY NULL  OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY ", rebuildSQL: "a=:1 AND b IN (:2,:3) AND c=:4", insertSQL: "INSERT INTO t(a) VALUES (?) RETURNING id INTO ? "},
+		{dialect: "mssql", pageSQL: "SELECT * FROM t ORDER BY (SELECT NULL)  OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY ", rebuildSQL: "a=@p1 AND b IN (@p2,@p3) AND c=@p4", insertSQL: "INSERT INTO t(a) VALUES (?)"},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			config := &DataSourceConfig{Dialect: tt.dialect}
+			finder := NewSelectFinder("t")
+			page := &Page{PageNo: 3, PageSize: 10}
+			pageSQL, err := wrapPageSQL(ctx, config, finder, page)
+			if err != nil || pageSQL != tt.pageSQL {
+				t.Errorf("wrapPageSQL() = %q, %v, want %q", pageSQL, err, tt.pageSQL)
+			}
+
+			sqlstr := "a=? AND b IN (?) AND c=?"
+			args := []interface{}{1, []myString{"x", "y"}, "z"}
+			rebuildSQL, newArgs, err := reBuildSQL(ctx, config, &sqlstr, &args)
+			if err != nil || *rebuildSQL != tt.rebuildSQL {
+				t.Errorf("reBuildSQL() = %q, %v, want %q", *rebuildSQL, err, tt.rebuildSQL)
+			}
+			if !reflect.DeepEqual(*newArgs, []interface{}{1, myString("x"), myString("y"), "z"}) {
+				t.Errorf("reBuildSQL() args = %v", *newArgs)
+			}
+
+			insertSQL := "INSERT INTO t(a) VALUES (?)"
+			values := []interface{}{1}
+			lastInsertID, zormSQLOutReturningID := wrapAutoIncrementInsertSQL(ctx, config, "id", &insertSQL, &values)
+			if insertSQL != tt.insertSQL {
+				t.Errorf("wrapAutoIncrementInsertSQL() = %q, want %q", insertSQL, tt.insertSQL)
+			}
+			switch tt.dialect {
+			case "postgresql", "kingbase":
+				if lastInsertID == nil || zormSQLOutReturningID != nil || len(values) != 1 {
+					t.Errorf("RETURNING should use lastInsertID")
+				}
+			case "oracle", "shentong":
+				if lastInsertID != nil || zormSQLOutReturningID == nil || len(values) != 2 {
+					t.Errorf("RETURNING INTO should use zormSQLOutReturningID")
+				} else if out, ok := values[1].(sql.Out); !ok || out.Dest != zormSQLOutReturningID {
+					t.Errorf("sql.Out should point to zormSQLOutReturningID")
+				}
+			default:
+				if lastInsertID != nil || zormSQLOutReturningID != nil || len(values) != 1 {
+					t.Errorf("LastInsertId() should be used")
+				}
+			}
+		})
+	}
+}
+
+func Test_IDialect_reBuildUpdateSQL(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		dialect string
+		sqlstr  string
+		want    string
+	}{
+		{dialect: "clickhouse", sqlstr: "UPDATE t SET a=1 WHERE id=2", want: "ALTER TABLE t UPDATE a=1 WHERE id=2"},
+		{dialect: "clickhouse", sqlstr: "DELETE FROM t WHERE id=2", want: "ALTER TABLE t DELETE WHERE id=2"},
+		{dialect: "mysql", sqlstr: "UPDATE t SET a=1 WHERE id=2", want: "UPDATE t SET a=1 WHERE id=2"},
+	}
+	for _, tt := range tests {
+		sqlstr := tt.sqlstr
+		if err := reBuildUpdateSQL(ctx, &DataSourceConfig{Dialect: tt.dialect}, &sqlstr); err != nil || sqlstr != tt.want {
+			t.Errorf("reBuildUpdateSQL(%s) = %q, %v, want %q", tt.dialect, sqlstr, err, tt.want)
+		}
+	}
+}
+
+// testDialect 自定义方言,只修改分页
+// testDialect custom dialect, only paging is changed
+type testDialect struct {
+	BaseDialect
+}
+
+func (testDialect) Name() string {
+	return "zorm_test_dialect"
+}
+
+func (testDialect) WrapPageSQL(sqlstr string, offset int, pageSize int, orderByIndex int) (string, error) {
+	return sqlstr + " TOP", nil
+}
+
+func Test_RegisterDialect(t *testing.T) {
+	if err := RegisterDialect(BaseDialect{}); err == nil {
+		t.Errorf("dialect without name should not be registered")
+	}
+	if err := RegisterDialect(testDialect{}); err != nil {
+		t.Fatal(err)
+	}
+	defer dialectMap.Delete("zorm_test_dialect")
+	if GetDialect("zorm_test_dialect") == nil {
+		t.Fatal("dialect is not registered")
+	}
+	if names := strings.Join(dialectNames(), ","); !strings.Contains(names, "tdengine,zorm_test_dialect") {
+		t.Errorf("dialectNames() = %s", names)
+	}
+	ctx := context.Background()
+	config := &DataSourceConfig{Dialect: "zorm_test_dialect"}
+	pageSQL, err := wrapPageSQL(ctx, config, NewSelectFinder("t"), &Page{PageNo: 1, PageSize: 10})
+	if err != nil || pageSQL != "SELECT * FROM t TOP" {
+		t.Errorf("wrapPageSQL() = %q, %v", pageSQL, err)
+	}
+	sqlstr := "a=? AND b=?"
+	args := []interface{}{1, 2}
+	rebuildSQL, _, err := reBuildSQL(ctx, config, &sqlstr, &args)
+	if err != nil || *rebuildSQL != "a=? AND b=?" {
+		t.Errorf("reBuildSQL() = %q, %v", *rebuildSQL, err)
+	}
+	upsertSQL, err := GetDialect("postgresql").UpsertSQL("INSERT INTO t(id,a) VALUES ($1,$2)", []string{"id"}, []string{"a"})
+	if err != nil || upsertSQL != "INSERT INTO t(id,a) VALUES ($1,$2) ON CONFLICT (id) DO UPDATE SET a=EXCLUDED.a" {
+		t.Errorf("UpsertSQL() = %q, %v", upsertSQL, err)
+	}
+}
+
+// Test_IDialect_legacyPage oracle 11g 和 sqlserver 2008 的分页语句
+// Test_IDialect_legacyPage paging statements of oracle 11g and sqlserver 2008
+func Test_IDialect_legacyPage(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		dialect string
+		version string
+		sqlstr  string
+		want    string
+	}{
+		{name: "oracle 11g", dialect: "oracle", version: "11g", sqlstr: "SELECT * FROM t ORDER BY id DESC",
+			want: "SELECT * FROM (SELECT temp_zorm_page_table.*,ROWNUM temp_zorm_rownum FROM ( SELECT * FROM t ORDER BY id DESC) temp_zorm_page_table WHERE ROWNUM <= 30) WHERE temp_zorm_rownum > 20"},
+		{name: "oracle 19c", dialect: "oracle", version: "19c", sqlstr: "SELECT * FROM t",
+			want: " SELECT * FROM t ORDER BY NULL  OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY "},
+		{name: "shentong", dialect: "shentong", version: "7", sqlstr: "SELECT * FROM t",
+			want: " SELECT * FROM t LIMIT 10 OFFSET 20"},
+		{name: "mssql 2008R2", dialect: "mssql", version: "2008R2", sqlstr: "SELECT u.id,u.name FROM t_user u WHERE u.age>? ORDER BY u.name DESC,u.id",
+			want: "SELECT * FROM (SELECT temp_zorm_page_table.*,ROW_NUMBER() OVER(ORDER BY name DESC,id) AS temp_zorm_rownum FROM ( SELECT u.id,u.name FROM t_user u WHERE u.age>? ) temp_zorm_page_table) temp_zorm_page_table2 WHERE temp_zorm_rownum BETWEEN 21 AND 30 ORDER BY temp_zorm_rownum"},
+		{name: "mssql 10.50 without order by", dialect: "mssql", version: "10.50", sqlstr: "SELECT id FROM t",
+			want: "SELECT * FROM (SELECT temp_zorm_page_table.*,ROW_NUMBER() OVER(ORDER BY (SELECT NULL)) AS temp_zorm_rownum FROM ( SELECT id FROM t) temp_zorm_page_table) temp_zorm_page_table2 WHERE temp_zorm_rownum BETWEEN 21 AND 30 ORDER BY temp_zorm_rownum"},
+		{name: "mssql 2008 top and alias", dialect: "mssql", version: "2008", sqlstr: "SELECT TOP 100 u.id,u.age*2 AS age2 FROM t_user u ORDER BY age2 DESC",
+			want: "SELECT * FROM (SELECT temp_zorm_page_table.*,ROW_NUMBER() OVER(ORDER BY age2 DESC) AS temp_zorm_rownum FROM ( SELECT TOP 100 u.id,u.age*2 AS age2 FROM t_user u ) temp_zorm_page_table) temp_zorm_page_table2 WHERE temp_zorm_rownum BETWEEN 21 AND 30 ORDER BY temp_zorm_rownum"},
+		{name: "mssql 2008 distinct", dialect: "mssql", version: "2008", sqlstr: "SELECT DISTINCT u.name FROM t_user u ORDER BY u.name",
+			want: "SELECT * FROM (SELECT temp_zorm_page_table.*,ROW_NUMBER() OVER(ORDER BY name) AS temp_zorm_rownum FROM ( SELECT DISTINCT u.name FROM t_user u ) temp_zorm_page_table) temp_zorm_page_table2 WHERE temp_zorm_rownum BETWEEN 21 AND 30 ORDER BY temp_zorm_rownum"},
+		{name: "mssql 2019", dialect: "mssql", version: "2019", sqlstr: "SELECT * FROM t ORDER BY id",
+			want: " SELECT * FROM t ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &DataSourceConfig{Dialect: tt.dialect, DialectVersion: tt.version}
+			finder := NewFinder().Append(tt.sqlstr)
+			pageSQL, err := wrapPageSQL(ctx, config, finder, &Page{PageNo: 3, PageSize: 10})
+			if err != nil || pageSQL != tt.want {
+				t.Errorf("wrapPageSQL() = %q, %v, want %q", pageSQL, err, tt.want)
+			}
+		})
+	}
+}
+
+// Test_IDialect_legacyPage_rownumColumn oracle 11g 和 sqlserver 2008 分页增加的行号列不返回给Query和QueryMap
+// Test_IDialect_legacyPage_rownumColumn the row number column added by the paging of oracle 11g and sqlserver 2008 is not returned to Query and QueryMap
+func Test_IDialect_legacyPage_rownumColumn(t *testing.T) {
+	tests := []struct {
+		dialect string
+		version string
+		table   *scanFakeTable
+	}{
+		// oracle的行号列在最后,列名是大写 | The row number column of oracle is the last one, the column name is upper case
+		{dialect: "oracle", version: "11g", table: &scanFakeTable{
+			name:        "oracleRownum",
+			columns:     []string{"NAME", "TEMP_ZORM_ROWNUM"},
+			columnTypes: []string{"VARCHAR2", "NUMBER"},
+			row: func(index int, values []driver.Value) {
+				values[0], values[1] = "name"+strconv.Itoa(index), int64(index)
+			},
+		}},
+		// sqlserver的行号列也在最后 | The row number column of sqlserver is the last one too
+		{dialect: "mssql", version: "2008", table: &scanFakeTable{
+			name:        "mssqlRowNumber",
+			columns:     []string{"name", "temp_zorm_rownum"},
+			columnTypes: []string{"NVARCHAR", "BIGINT"},
+			row: func(index int, values []driver.Value) {
+				values[0], values[1] = "name"+strconv.Itoa(index), int64(index)
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			ctx := newScanFakeConfigContext(t, tt.table, &DataSourceConfig{DSN: "2", Dialect: tt.dialect, DialectVersion: tt.version})
+			finder := NewFinder().Append("SELECT name FROM t_user ORDER BY name")
+			finder.SelectTotalCount = false
+			names := make([]string, 0)
+			if err := Query(ctx, finder, &names, NewPage()); err != nil || !reflect.DeepEqual(names, []string{"name1", "name2"}) {
+				t.Errorf("Query = %q, %v", names, err)
+			}
+			maps, columns, err := QueryMapColumns(ctx, finder, NewPage())
+			if err != nil || len(maps) != 2 || len(maps[0]) != 1 || len(columns) != 1 || !strings.EqualFold(columns[0], "name") {
+				t.Errorf("QueryMapColumns = %v, %v, %v", maps, columns, err)
+			}
+		})
+	}
+}
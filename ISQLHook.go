@@ -0,0 +1,180 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"time"
+)
+
+// SQLHook的操作类型
+// Operation types of SQLHook
+const (
+	// SQLHookOperationExec 执行更新语句 | Execute update statement
+	SQLHookOperationExec = "exec"
+	// SQLHookOperationQuery 查询结果集 | Query result set
+	SQLHookOperationQuery = "query"
+	// SQLHookOperationQueryRow 查询单行 | Query single row
+	SQLHookOperationQueryRow = "queryRow"
+	// SQLHookOperationTransaction zorm.Transaction 事务方法 | zorm.Transaction method
+	SQLHookOperationTransaction = "transaction"
+	// SQLHookOperationBeginGTX 开启全局分布式事务 | Begin global distributed transaction
+	SQLHookOperationBeginGTX = "beginGTX"
+	// SQLHookOperationCommitGTX 提交全局分布式事务 | Commit global distributed transaction
+	SQLHookOperationCommitGTX = "commitGTX"
+	// SQLHookOperationRollbackGTX 回滚全局分布式事务 | Rollback global distributed transaction
+	SQLHookOperationRollbackGTX = "rollbackGTX"
+)
+
+// SQLHookEvent 一次SQL执行或者事务操作的信息,Before和After使用同一个对象
+// SQLHookEvent Information of one SQL execution or transaction operation, Before and After share the same object
+type SQLHookEvent struct {
+	// Operation 操作类型,例如 SQLHookOperationExec
+	// Operation type, e.g. SQLHookOperationExec
+	Operation string
+
+	// Config 数据源的配置,可以获取Dialect等信息
+	// Config of the datasource, Dialect etc. can be read from it
+	Config *DataSourceConfig
+
+	// SQL reBuildSQL 之后实际执行的语句,事务操作为空
+	// SQL the statement actually executed after reBuildSQL, empty for transaction operations
+	SQL string
+
+	// Args SQL的参数值
+	// Args SQL parameter values
+	Args []interface{}
+
+	// InTransaction 是否在事务中执行
+	// InTransaction whether it is executed in a transaction
+	InTransaction bool
+
+	// StartTime 开始时间
+	// StartTime start time
+	StartTime time.Time
+
+	// Duration 执行耗时,After中有值
+	// Duration elapsed time, available in After
+	Duration time.Duration
+
+	// RowsAffected 影响的行数,只有exec有值,-1表示未知
+	// RowsAffected number of rows affected, only for exec, -1 means unknown
+	RowsAffected int64
+
+	// Err 执行的错误,After中有值
+	// Err execution error, available in After
+	Err error
+
+	// hookContexts 记录每个hook的Before返回的ctx,After时传回给对应的hook
+	// hookContexts records the ctx returned by each hook's Before, passed back to the same hook in After
+	hookContexts []context.Context
+}
+
+// ISQLHook SQL执行的钩子,用于链路追踪,监控指标,日志等扩展.配置到 DataSourceConfig.SQLHooks
+// Before返回的ctx会用于执行SQL,并在After时传回,可以用来传递span等对象.钩子不能修改SQL语句和参数
+// queryRow 的After在Scan之后调用,Err包含Scan的错误,query 的After在获取到rows时调用,不包含遍历结果集的时间
+// ISQLHook Hook of SQL execution, used for tracing, metrics, logging and other extensions. Configure it in DataSourceConfig.SQLHooks
+// The ctx returned by Before is used to execute SQL and is passed back in After, it can carry objects such as span. Hooks cannot modify SQL or args
+// After of queryRow is called after Scan and Err includes the Scan error, After of query is called when rows are returned, excluding the time of iterating the result set
+type ISQLHook interface {
+	// Before 执行前调用
+	// Before is called before execution
+	Before(ctx context.Context, event *SQLHookEvent) context.Context
+
+	// After 执行后调用,ctx是本钩子Before返回的ctx
+	// After is called after execution, ctx is the one returned by Before of this hook
+	After(ctx context.Context, event *SQLHookEvent)
+}
+
+// sqlHookConfigCount NewDBDao时配置了SQLHooks的数据源数量,为0时Transaction不需要解析数据源配置
+// sqlHookConfigCount number of data sources configured with SQLHooks at NewDBDao, Transaction does not need to resolve the config when it is 0
+var sqlHookConfigCount int32
+
+// beforeSQLHooks 调用所有钩子的Before,返回新的ctx和事件对象.没有钩子时返回nil事件
+// beforeSQLHooks calls Before of all hooks, returns the new ctx and the event. Returns nil event when there are no hooks
+func beforeSQLHooks(ctx context.Context, config *DataSourceConfig, operation string, sqlstr string, args []interface{}, inTransaction bool) (context.Context, *SQLHookEvent) {
+	if config == nil || len(config.SQLHooks) < 1 {
+		return ctx, nil
+	}
+	event := &SQLHookEvent{
+		Operation:     operation,
+		Config:        config,
+		SQL:           sqlstr,
+		Args:          args,
+		InTransaction: inTransaction,
+		RowsAffected:  -1,
+		hookContexts:  make([]context.Context, len(config.SQLHooks)),
+	}
+	event.StartTime = time.Now()
+	for i, hook := range config.SQLHooks {
+		ctx = hook.Before(ctx, event)
+		event.hookContexts[i] = ctx
+	}
+	return ctx, event
+}
+
+// afterSQLHooks 倒序调用所有钩子的After
+// afterSQLHooks calls After of all hooks in reverse order
+func afterSQLHooks(event *SQLHookEvent, err error) {
+	if event == nil {
+		return
+	}
+	event.Duration = time.Since(event.StartTime)
+	event.Err = err
+	hooks := event.Config.SQLHooks
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].After(event.hookContexts[i], event)
+	}
+}
+
+// hookGlobalTransaction 包装IGlobalTransaction,调用钩子记录分布式事务的操作
+// hookGlobalTransaction wraps IGlobalTransaction and calls hooks for the distributed transaction operations
+type hookGlobalTransaction struct {
+	globalTransaction IGlobalTransaction
+	config            *DataSourceConfig
+}
+
+// BeginGTX 开启全局分布式事务
+func (gtx *hookGlobalTransaction) BeginGTX(ctx context.Context, globalRootContext context.Context) error {
+	ctx, event := beforeSQLHooks(ctx, gtx.config, SQLHookOperationBeginGTX, "", nil, false)
+	err := gtx.globalTransaction.BeginGTX(ctx, globalRootContext)
+	afterSQLHooks(event, err)
+	return err
+}
+
+// CommitGTX 提交全局分布式事务
+func (gtx *hookGlobalTransaction) CommitGTX(ctx context.Context, globalRootContext context.Context) error {
+	ctx, event := beforeSQLHooks(ctx, gtx.config, SQLHookOperationCommitGTX, "", nil, true)
+	err := gtx.globalTransaction.CommitGTX(ctx, globalRootContext)
+	afterSQLHooks(event, err)
+	return err
+}
+
+// RollbackGTX 回滚全局分布式事务
+func (gtx *hookGlobalTransaction) RollbackGTX(ctx context.Context, globalRootContext context.Context) error {
+	ctx, event := beforeSQLHooks(ctx, gtx.config, SQLHookOperationRollbackGTX, "", nil, true)
+	err := gtx.globalTransaction.RollbackGTX(ctx, globalRootContext)
+	afterSQLHooks(event, err)
+	return err
+}
+
+// GetGTXID 获取全局分布式事务的XID
+func (gtx *hookGlobalTransaction) GetGTXID(ctx context.Context, globalRootContext context.Context) (string, error) {
+	return gtx.globalTransaction.GetGTXID(ctx, globalRootContext)
+}
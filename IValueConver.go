@@ -0,0 +1,301 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// IValueConver Go类型的值转换接口,用于枚举,金额,加密字符串,protobuf时间戳等类型.
+// 和按照数据库列类型注册的ICustomDriverValueConver不同,IValueConver按照Go类型或者实体类的字段注册,保存和查询双向转换
+// IValueConver value conversion interface of Go types, for enums, money types, encrypted strings, protobuf timestamps, etc.
+// Unlike ICustomDriverValueConver registered by database column type, IValueConver is registered by Go type or entity field and converts in both directions when saving and querying
+type IValueConver interface {
+	// ToDriverValue 保存时把Go的值转换为数据库驱动支持的值,在reBuildSQL之前执行.nil值(NULL)不会调用
+	// ToDriverValue converts the Go value to a value supported by the database driver when saving, executed before reBuildSQL. nil values (NULL) are not passed
+	ToDriverValue(ctx context.Context, value interface{}) (interface{}, error)
+
+	// FromDriverValue 查询时把数据库驱动的值src转换为注册的Go类型的值,NULL不会调用,接收的字段保持零值.
+	// src是[]byte时,数据库驱动在下一行可能复用,需要保留时请复制
+	// FromDriverValue converts the database driver value src to a value of the registered Go type when querying, NULL is not passed and the receiving field keeps the zero value.
+	// When src is []byte, the database driver may reuse it for the next row, copy it if it needs to be retained
+	FromDriverValue(ctx context.Context, src interface{}) (interface{}, error)
+}
+
+// valueConverFieldKey 实体类字段的注册key
+// valueConverFieldKey registration key of the entity field
+type valueConverFieldKey struct {
+	structType reflect.Type
+	fieldName  string
+}
+
+// typeValueConverMap Go类型的IValueConver,fieldValueConverMap 实体类字段的IValueConver
+// typeValueConverMap IValueConver of Go types, fieldValueConverMap IValueConver of entity fields
+var (
+	typeValueConverMap  = sync.Map{}
+	fieldValueConverMap = sync.Map{}
+	// typeValueConverCount 和 fieldValueConverCount 注册的数量,没有注册时不处理
+	// typeValueConverCount and fieldValueConverCount number of registrations, nothing is processed without registrations
+	typeValueConverCount  int32
+	fieldValueConverCount int32
+)
+
+// RegisterTypeValueConver 注册Go类型的IValueConver,注册时传入该类型的值,例如 type Status int 传入 Status(0),
+// protobuf时间戳传入 (*timestamppb.Timestamp)(nil).注册的是指针类型时,同样适用于指向的值.一般是放到init方法里进行注册
+// RegisterTypeValueConver registers IValueConver of a Go type, pass a value of the type when registering, e.g. Status(0) for type Status int,
+// (*timestamppb.Timestamp)(nil) for protobuf timestamps. When a pointer type is registered, it also applies to the pointed value. Usually registered in the init method
+func RegisterTypeValueConver(value interface{}, valueConver IValueConver) error {
+	typeOf := reflect.TypeOf(value)
+	if typeOf == nil {
+		return errors.New("->RegisterTypeValueConver-->value不能为nil")
+	}
+	if valueConver == nil {
+		return errors.New("->RegisterTypeValueConver-->valueConver不能为nil")
+	}
+	if _, loaded := typeValueConverMap.Load(typeOf); !loaded {
+		atomic.AddInt32(&typeValueConverCount, 1)
+	}
+	typeValueConverMap.Store(typeOf, valueConver)
+	return nil
+}
+
+// RegisterFieldValueConver 注册实体类字段的IValueConver,优先于RegisterTypeValueConver.entity是struct或者struct的指针,fieldName是struct的字段名
+// RegisterFieldValueConver registers IValueConver of an entity field, takes precedence over RegisterTypeValueConver. entity is a struct or a pointer to struct, fieldName is the struct field name
+func RegisterFieldValueConver(entity interface{}, fieldName string, valueConver IValueConver) error {
+	typeOf := reflect.TypeOf(entity)
+	if typeOf != nil && typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf == nil || typeOf.Kind() != reflect.Struct {
+		return errors.New("->RegisterFieldValueConver-->entity必须是struct或者struct的指针")
+	}
+	if _, ok := typeOf.FieldByName(fieldName); !ok {
+		return fmt.Errorf("->RegisterFieldValueConver-->%s没有字段%s", typeOf.String(), fieldName)
+	}
+	if valueConver == nil {
+		return errors.New("->RegisterFieldValueConver-->valueConver不能为nil")
+	}
+	key := valueConverFieldKey{structType: typeOf, fieldName: fieldName}
+	if _, loaded := fieldValueConverMap.Load(key); !loaded {
+		atomic.AddInt32(&fieldValueConverCount, 1)
+	}
+	fieldValueConverMap.Store(key, valueConver)
+	return nil
+}
+
+// typeValueConver 获取typeOf注册的IValueConver,指针类型没有注册时使用指向的类型
+// typeValueConver gets the IValueConver registered for typeOf, the pointed type is used when the pointer type is not registered
+func typeValueConver(typeOf reflect.Type) IValueConver {
+	if typeOf == nil || atomic.LoadInt32(&typeValueConverCount) < 1 {
+		return nil
+	}
+	if valueConver, ok := typeValueConverMap.Load(typeOf); ok {
+		return valueConver.(IValueConver)
+	}
+	if typeOf.Kind() == reflect.Ptr {
+		if valueConver, ok := typeValueConverMap.Load(typeOf.Elem()); ok {
+			return valueConver.(IValueConver)
+		}
+	}
+	return nil
+}
+
+// registeredFieldValueConver 获取RegisterFieldValueConver注册的实体类字段的IValueConver
+// registeredFieldValueConver gets the IValueConver of an entity field registered by RegisterFieldValueConver
+func registeredFieldValueConver(structType reflect.Type, field *fieldColumnCache) IValueConver {
+	if structType == nil || field == nil || field.structField == nil || atomic.LoadInt32(&fieldValueConverCount) < 1 {
+		return nil
+	}
+	if valueConver, ok := fieldValueConverMap.Load(valueConverFieldKey{structType: structType, fieldName: field.structField.Name}); ok {
+		return valueConver.(IValueConver)
+	}
+	return nil
+}
+
+// fieldValueConver 获取查询时实体类字段的IValueConver,优先使用字段注册的,然后是字段类型注册的
+// fieldValueConver gets the IValueConver of an entity field when querying, the field registration first, then the registration of the field type
+func fieldValueConver(structType reflect.Type, field *fieldColumnCache) IValueConver {
+	if valueConver := registeredFieldValueConver(structType, field); valueConver != nil {
+		return valueConver
+	}
+	if field == nil || field.structField == nil {
+		return nil
+	}
+	return typeValueConver(field.structField.Type)
+}
+
+// convertedValue 实体类字段已经使用IValueConver转换的值,wrapValueConverArgs不再按照类型转换
+// convertedValue value of an entity field already converted by IValueConver, wrapValueConverArgs does not convert it by type again
+type convertedValue struct {
+	value interface{}
+}
+
+// fieldConverValue 使用字段注册的IValueConver转换字段的值,nil值不转换
+// fieldConverValue converts the field value with the IValueConver registered for the field, nil values are not converted
+func fieldConverValue(ctx context.Context, valueConver IValueConver, fieldValue reflect.Value) (interface{}, error) {
+	if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+		return nil, nil
+	}
+	value, err := valueConver.ToDriverValue(ctx, fieldValue.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("->fieldConverValue-->ToDriverValue错误:%w", err)
+	}
+	return convertedValue{value: value}, nil
+}
+
+// wrapValueConverArgs 在reBuildSQL之前使用IValueConver转换SQL参数,包括数组参数的元素.没有需要转换的参数时返回原args,不修改传入的args
+// wrapValueConverArgs converts SQL parameters with IValueConver before reBuildSQL, including elements of array parameters. The original args is returned when nothing is converted, args is not modified
+func wrapValueConverArgs(ctx context.Context, args *[]interface{}) (*[]interface{}, error) {
+	if args == nil || len(*args) < 1 || (atomic.LoadInt32(&typeValueConverCount) < 1 && atomic.LoadInt32(&fieldValueConverCount) < 1) {
+		return args, nil
+	}
+	var newArgs []interface{}
+	for i, arg := range *args {
+		value, converted, err := converArgValue(ctx, arg)
+		if err != nil {
+			return args, err
+		}
+		if !converted {
+			continue
+		}
+		if newArgs == nil {
+			newArgs = make([]interface{}, len(*args))
+			copy(newArgs, *args)
+		}
+		newArgs[i] = value
+	}
+	if newArgs == nil {
+		return args, nil
+	}
+	return &newArgs, nil
+}
+
+// converArgValue 转换一个SQL参数,返回转换后的值和是否转换
+// converArgValue converts a SQL parameter, returns the converted value and whether it is converted
+func converArgValue(ctx context.Context, arg interface{}) (interface{}, bool, error) {
+	switch v := arg.(type) {
+	case nil:
+		return arg, false, nil
+	case convertedValue:
+		return v.value, true, nil
+	}
+	valueOf := reflect.ValueOf(arg)
+	if valueConver := typeValueConver(valueOf.Type()); valueConver != nil {
+		if valueOf.Kind() == reflect.Ptr {
+			if valueOf.IsNil() {
+				return nil, true, nil
+			}
+			if _, ok := typeValueConverMap.Load(valueOf.Type()); !ok { // 注册的是指向的类型 | The pointed type is registered
+				valueOf = valueOf.Elem()
+			}
+		}
+		value, err := valueConver.ToDriverValue(ctx, valueOf.Interface())
+		if err != nil {
+			return nil, false, fmt.Errorf("->converArgValue-->ToDriverValue错误:%w", err)
+		}
+		return value, true, nil
+	}
+	// 注册的是指针类型,参数是指向的值
+	// The pointer type is registered, the parameter is the pointed value
+	if valueOf.Kind() != reflect.Ptr {
+		if valueConver, ok := typeValueConverMap.Load(reflect.PtrTo(valueOf.Type())); ok {
+			ptr := reflect.New(valueOf.Type())
+			ptr.Elem().Set(valueOf)
+			value, err := valueConver.(IValueConver).ToDriverValue(ctx, ptr.Interface())
+			if err != nil {
+				return nil, false, fmt.Errorf("->converArgValue-->ToDriverValue错误:%w", err)
+			}
+			return value, true, nil
+		}
+	}
+	// 数组参数的元素,转换为[]interface{},[]byte不处理
+	// Elements of array parameters are converted to []interface{}, []byte is not processed
+	if (valueOf.Kind() == reflect.Slice || valueOf.Kind() == reflect.Array) && valueOf.Type().Elem().Kind() != reflect.Uint8 {
+		elemType := valueOf.Type().Elem()
+		if typeValueConver(elemType) == nil && typeValueConver(reflect.PtrTo(elemType)) == nil {
+			return arg, false, nil
+		}
+		values := make([]interface{}, valueOf.Len())
+		for i := range values {
+			value, _, err := converArgValue(ctx, valueOf.Index(i).Interface())
+			if err != nil {
+				return nil, false, err
+			}
+			values[i] = value
+		}
+		return values, true, nil
+	}
+	return arg, false, nil
+}
+
+// valueConverScanner 查询时接收数据库驱动的值,使用IValueConver转换,每个查询的列复用
+// valueConverScanner receives the database driver value when querying and converts it with IValueConver, reused for each column of a query
+type valueConverScanner struct {
+	ctx         context.Context
+	valueConver IValueConver
+	// value 转换后的值
+	// value the converted value
+	value interface{}
+}
+
+// Scan 实现sql.Scanner接口,NULL值不转换
+// Scan implements the sql.Scanner interface, NULL values are not converted
+func (scanner *valueConverScanner) Scan(src interface{}) error {
+	scanner.value = nil
+	if src == nil {
+		return nil
+	}
+	value, err := scanner.valueConver.FromDriverValue(scanner.ctx, src)
+	if err != nil {
+		return fmt.Errorf("->valueConverScanner.Scan-->FromDriverValue错误:%w", err)
+	}
+	scanner.value = value
+	return nil
+}
+
+// setConverValue 把IValueConver转换后的值赋值给字段,字段是指针类型时创建指向的值
+// setConverValue assigns the value converted by IValueConver to the field, the pointed value is created when the field is a pointer type
+func setConverValue(fieldValue reflect.Value, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	valueOf := reflect.ValueOf(value)
+	fieldType := fieldValue.Type()
+	if fieldType.Kind() == reflect.Ptr && !valueOf.Type().AssignableTo(fieldType) {
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.New(fieldType.Elem()))
+		}
+		fieldValue = fieldValue.Elem()
+		fieldType = fieldType.Elem()
+	}
+	if valueOf.Type().AssignableTo(fieldType) {
+		fieldValue.Set(valueOf)
+		return nil
+	}
+	if valueOf.Type().ConvertibleTo(fieldType) {
+		fieldValue.Set(valueOf.Convert(fieldType))
+		return nil
+	}
+	return fmt.Errorf("->setConverValue-->FromDriverValue返回的%s类型不能赋值给%s", valueOf.Type().String(), fieldType.String())
+}
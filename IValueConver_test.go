@@ -0,0 +1,209 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// testStatus 枚举类型,数据库保存名称,查询时兼容数字
+// testStatus enum type, the database saves the name, numbers are also accepted when querying
+type testStatus int
+
+var testStatusNames = map[testStatus]string{1: "active", 2: "inactive"}
+
+// testStatusConver testStatus的IValueConver
+// testStatusConver IValueConver of testStatus
+type testStatusConver struct{}
+
+func (testStatusConver) ToDriverValue(ctx context.Context, value interface{}) (interface{}, error) {
+	name, ok := testStatusNames[value.(testStatus)]
+	if !ok {
+		return nil, fmt.Errorf("unknown status %v", value)
+	}
+	return name, nil
+}
+
+func (testStatusConver) FromDriverValue(ctx context.Context, src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case int64:
+		return testStatus(v), nil
+	case []byte:
+		for status, name := range testStatusNames {
+			if name == string(v) {
+				return status, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown status %v", src)
+}
+
+// testReverseConver 反转字符串,模拟加密的字段
+// testReverseConver reverses strings, simulates an encrypted field
+type testReverseConver struct{}
+
+func (testReverseConver) ToDriverValue(ctx context.Context, value interface{}) (interface{}, error) {
+	return testReverse(fmt.Sprint(value)), nil
+}
+
+func (testReverseConver) FromDriverValue(ctx context.Context, src interface{}) (interface{}, error) {
+	if b, ok := src.([]byte); ok {
+		return testReverse(string(b)), nil
+	}
+	return testReverse(fmt.Sprint(src)), nil
+}
+
+func testReverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// testConverEntity 使用IValueConver的实体类
+// testConverEntity entity using IValueConver
+type testConverEntity struct {
+	EntityStruct
+	ID       string      `column:"id"`
+	Status   testStatus  `column:"status"`
+	Previous *testStatus `column:"previous"`
+	Secret   string      `column:"secret"`
+}
+
+func (entity *testConverEntity) GetTableName() string {
+	return "t_conver"
+}
+
+func init() {
+	RegisterTypeValueConver(testStatus(0), testStatusConver{})
+	RegisterFieldValueConver(&testConverEntity{}, "Secret", testReverseConver{})
+}
+
+func Test_RegisterValueConver(t *testing.T) {
+	if err := RegisterFieldValueConver(&testConverEntity{}, "NotExist", testReverseConver{}); err == nil {
+		t.Error("expected error for a missing field")
+	}
+	if err := RegisterTypeValueConver(nil, testStatusConver{}); err == nil {
+		t.Error("expected error for a nil value")
+	}
+}
+
+func Test_wrapValueConverArgs(t *testing.T) {
+	ctx := context.Background()
+	status := testStatus(2)
+	var nilStatus *testStatus
+	args := []interface{}{"a", testStatus(1), &status, nilStatus, []testStatus{1, 2}, []int{1}, convertedValue{value: "x"}}
+	got, err := wrapValueConverArgs(ctx, &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"a", "active", "inactive", nil, []interface{}{"active", "inactive"}, []int{1}, "x"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("args = %v, want %v", *got, want)
+	}
+	if args[1] != testStatus(1) {
+		t.Error("args must not be modified")
+	}
+	plain := []interface{}{"a", 1}
+	if got, _ := wrapValueConverArgs(ctx, &plain); got != &plain {
+		t.Error("args without conversion must be returned as is")
+	}
+	bad := []interface{}{testStatus(9)}
+	if _, err := wrapValueConverArgs(ctx, &bad); err == nil {
+		t.Error("expected ToDriverValue error")
+	}
+
+	// 实体类字段注册的转换优先,类型注册的转换在reBuildSQL之前执行
+	// The field registration takes precedence, type registrations are applied before reBuildSQL
+	entity := &testConverEntity{ID: "1", Status: 1, Previous: &status, Secret: "abc"}
+	entityCache, err := getEntityStructCache(ctx, entity, &DataSourceConfig{Dialect: "mysql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]interface{}, 0)
+	if err = insertEntityFieldValues(ctx, entity, entityCache, true, &values); err != nil {
+		t.Fatal(err)
+	}
+	got, err = wrapValueConverArgs(ctx, &values)
+	if err != nil || !reflect.DeepEqual(*got, []interface{}{"1", "active", "inactive", "cba"}) {
+		t.Errorf("insert values = %v, %v", *got, err)
+	}
+}
+
+// scanFakeConverTable IValueConver的结果集,偶数行的user_name和age是NULL
+// scanFakeConverTable result set of IValueConver, user_name and age of even rows are NULL
+var scanFakeConverTable = &scanFakeTable{
+	name:        "conver",
+	columns:     []string{"id", "user_name", "age", "is_active"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT", "BOOLEAN"},
+	row: func(index int, values []driver.Value) {
+		scanFakeUserValues(index, values)
+		values[3] = true
+	},
+}
+
+func Test_IValueConver_read(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeConverTable, 2)
+	type converRow struct {
+		UserName string     `column:"user_name"`
+		Age      testStatus `column:"age"`
+	}
+	if err := RegisterFieldValueConver(converRow{}, "UserName", testReverseConver{}); err != nil {
+		t.Fatal(err)
+	}
+	list := make([]converRow, 0)
+	if err := Query(ctx, NewSelectFinder("test_table"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].UserName != "eman" || list[0].Age != 1 || list[1].UserName != "" || list[1].Age != 0 {
+		t.Errorf("Query = %+v", list)
+	}
+
+	// 单列查询注册了IValueConver的类型
+	// Single column query of a type with IValueConver registered
+	statuses := make([]testStatus, 0)
+	if err := Query(ctx, NewFinder().Append("SELECT age FROM test_table"), &statuses, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(statuses, []testStatus{1, 0}) {
+		t.Errorf("statuses = %v", statuses)
+	}
+
+	// QueryMap使用接收类型注册的IValueConver,测试结束删除注册
+	// QueryMap uses the IValueConver registered for the receiving type, the registration is removed after the test
+	boolType := reflect.TypeOf(true)
+	RegisterTypeValueConver(true, testReverseConver{})
+	defer func() {
+		typeValueConverMap.Delete(boolType)
+		atomic.AddInt32(&typeValueConverCount, -1)
+	}()
+	maps, err := QueryMap(ctx, NewSelectFinder("test_table"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 2 || maps[0]["is_active"] != "eurt" || maps[0]["user_name"] != "name" {
+		t.Errorf("QueryMap = %v", maps)
+	}
+}
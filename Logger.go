@@ -21,8 +21,13 @@ package zorm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 func init() {
@@ -55,6 +60,425 @@ func defaultLogPanic(ctx context.Context, err error) {
 	defaultLogError(ctx, err)
 }
 
+// FuncRedactSQLValue 自定义SQL参数值的脱敏,用于FuncPrintSQL的输出和错误信息中的zormErrorSQLValues,默认nil.
+// column是参数对应的列名(小写,不包含表名),无法识别时为"";返回替换后的值和是否脱敏,返回false时继续使用RegisterRedactType和RegisterRedactColumn的规则
+// FuncRedactSQLValue custom redaction of SQL parameter values, used by the FuncPrintSQL output and zormErrorSQLValues in errors, nil by default.
+// column is the lower case column name of the parameter without the table name, "" if it can not be recognized; returns the replacement and whether it is redacted, when false the RegisterRedactType and RegisterRedactColumn rules still apply
+var FuncRedactSQLValue func(ctx context.Context, column string, value interface{}) (interface{}, bool) = nil
+
+// RedactMask 脱敏后显示的值
+// RedactMask the value displayed after redaction
+var RedactMask = "******"
+
+// tagRedactName 脱敏的tag标签名称,例如 `column:"password" redact:"true"`,使用RegisterRedactEntity注册
+// tagRedactName tag name of redaction, e.g. `column:"password" redact:"true"`, registered with RegisterRedactEntity
+const tagRedactName = "redact"
+
+// redactColumnMap 需要脱敏的 表名.列名 或者 列名(小写),redactTypeMap 需要脱敏的参数类型
+// redactColumnMap lower case table.column or column names to redact, redactTypeMap parameter types to redact
+var (
+	redactColumnMap = sync.Map{}
+	redactTypeMap   = sync.Map{}
+	// redactColumnCount 和 redactTypeCount 脱敏规则的数量,没有规则时不处理参数
+	// redactColumnCount and redactTypeCount number of redaction rules, parameters are not processed when there are no rules
+	redactColumnCount int32
+	redactTypeCount   int32
+)
+
+// RegisterRedactColumn 注册需要脱敏的列,不区分大小写.格式是 表名.列名 ,例如 t_user.password ,只有列名时对所有表的同名列生效.一般是放到init方法里进行注册
+// RegisterRedactColumn registers columns to redact, case insensitive. The format is table.column , e.g. t_user.password , a column without table applies to the column of the same name in every table. It is usually registered in the init method
+func RegisterRedactColumn(columns ...string) {
+	for _, column := range columns {
+		if _, loaded := redactColumnMap.LoadOrStore(strings.ToLower(column), true); !loaded {
+			atomic.AddInt32(&redactColumnCount, 1)
+		}
+	}
+}
+
+// RegisterRedactEntity 注册实体类中 redact:"true" 的字段,按照 GetTableName().列名 脱敏.一般是放到init方法里进行注册,例如 zorm.RegisterRedactEntity(&User{})
+// RegisterRedactEntity registers the redact:"true" fields of the entities, redacted as GetTableName().column . It is usually registered in the init method, e.g. zorm.RegisterRedactEntity(&User{})
+func RegisterRedactEntity(entities ...IEntityStruct) error {
+	for _, entity := range entities {
+		if entity == nil {
+			return errors.New("->RegisterRedactEntity-->entity不能为nil")
+		}
+		typeOf := reflect.TypeOf(entity)
+		if typeOf.Kind() != reflect.Ptr || typeOf.Elem().Kind() != reflect.Struct {
+			return errors.New("->RegisterRedactEntity-->entity必须是struct的指针")
+		}
+		entityCache, err := buildStructCache(context.Background(), typeOf.Elem())
+		if err != nil {
+			return fmt.Errorf("->RegisterRedactEntity-->buildStructCache错误:%w", err)
+		}
+		for _, column := range entityCache.columns {
+			if column.isRedact {
+				RegisterRedactColumn(entity.GetTableName() + "." + column.columnName)
+			}
+		}
+	}
+	return nil
+}
+
+// RegisterRedactType 注册需要脱敏的参数类型,例如 type Password string,注册时传入该类型的值 Password("")
+// RegisterRedactType registers parameter types to redact, e.g. type Password string, pass a value of the type when registering, Password("")
+func RegisterRedactType(values ...interface{}) {
+	for _, value := range values {
+		typeOf := reflect.TypeOf(value)
+		if typeOf == nil {
+			continue
+		}
+		if _, loaded := redactTypeMap.LoadOrStore(typeOf, true); !loaded {
+			atomic.AddInt32(&redactTypeCount, 1)
+		}
+	}
+}
+
+// RedactSQLValues 按照脱敏规则处理SQL的参数值,用于记录日志,不修改传入的args.没有需要脱敏的值时返回原args
+// 列名通过 col=? 和 INSERT 的列顺序识别,占位符数量和参数不一致时(例如tdengine)只使用类型和FuncRedactSQLValue的规则
+// RedactSQLValues applies the redaction rules to the SQL parameter values for logging, args is not modified. The original args is returned when nothing is redacted
+// Column names are recognized by col=? and the column order of INSERT, when the placeholders do not match the parameters (e.g. tdengine) only the type and FuncRedactSQLValue rules are used
+func RedactSQLValues(ctx context.Context, sqlstr string, args []interface{}) []interface{} {
+	hasColumn := atomic.LoadInt32(&redactColumnCount) > 0
+	if len(args) < 1 || (FuncRedactSQLValue == nil && !hasColumn && atomic.LoadInt32(&redactTypeCount) < 1) {
+		return args
+	}
+	var columns, qualifiers, tables []string
+	var aliases map[string]string
+	if hasColumn || FuncRedactSQLValue != nil {
+		columns, qualifiers = sqlParamColumns(sqlstr)
+		if len(columns) != len(args) {
+			columns = nil
+		} else if hasColumn {
+			aliases, tables = sqlTableAliases(sqlstr)
+		}
+	}
+
+	var newArgs []interface{}
+	for i, value := range args {
+		column := ""
+		redactColumn := false
+		if columns != nil {
+			column = columns[i]
+			redactColumn = hasColumn && isRedactColumn(aliases, tables, qualifiers[i], column)
+		}
+		newValue, redacted := redactSQLValue(ctx, column, redactColumn, value)
+		if !redacted {
+			continue
+		}
+		if newArgs == nil {
+			newArgs = make([]interface{}, len(args))
+			copy(newArgs, args)
+		}
+		newArgs[i] = newValue
+	}
+	if newArgs == nil {
+		return args
+	}
+	return newArgs
+}
+
+// redactSQLValue 脱敏一个参数值,redactColumn是参数的列是否注册了脱敏
+// redactSQLValue redacts one parameter value, redactColumn is whether the column of the parameter is registered for redaction
+func redactSQLValue(ctx context.Context, column string, redactColumn bool, value interface{}) (interface{}, bool) {
+	if FuncRedactSQLValue != nil {
+		if newValue, ok := FuncRedactSQLValue(ctx, column, value); ok {
+			return newValue, true
+		}
+	}
+	if typeOf := reflect.TypeOf(value); typeOf != nil {
+		if _, ok := redactTypeMap.Load(typeOf); ok {
+			return RedactMask, true
+		}
+		if typeOf.Kind() == reflect.Ptr {
+			if _, ok := redactTypeMap.Load(typeOf.Elem()); ok {
+				return RedactMask, true
+			}
+		}
+	}
+	if redactColumn {
+		return RedactMask, true
+	}
+	return value, false
+}
+
+// isRedactColumn 列是否注册了脱敏.qualifier是列前面的表名或者别名,没有时使用语句中所有的表
+// isRedactColumn whether the column is registered for redaction. qualifier is the table name or alias before the column, all the tables of the statement are used without it
+func isRedactColumn(aliases map[string]string, tables []string, qualifier string, column string) bool {
+	if column == "" {
+		return false
+	}
+	if _, ok := redactColumnMap.Load(column); ok {
+		return true
+	}
+	if qualifier != "" {
+		table, ok := aliases[qualifier]
+		if !ok {
+			table = qualifier
+		}
+		tables = []string{table}
+	}
+	for _, table := range tables {
+		if _, ok := redactColumnMap.Load(table + "." + column); ok {
+			return true
+		}
+		// 去掉schema的表名
+		// Table name without schema
+		if index := strings.LastIndexByte(table, '.'); index >= 0 {
+			if _, ok := redactColumnMap.Load(table[index+1:] + "." + column); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactNotAliasWords 表名后面不是别名的关键字
+// redactNotAliasWords keywords after the table name that are not aliases
+var redactNotAliasWords = map[string]bool{"VALUES": true, "SELECT": true, "DEFAULT": true, "OUTPUT": true, "OVERRIDING": true}
+
+// sqlTableAliases 识别SQL中 FROM,JOIN,UPDATE,INTO 后面的表,返回别名(小写)到表名(小写)的映射和所有的表名
+// sqlTableAliases recognizes the tables after FROM, JOIN, UPDATE, INTO in the SQL, returns the mapping from lower case aliases to lower case table names and all the table names
+func sqlTableAliases(sqlstr string) (map[string]string, []string) {
+	aliases := make(map[string]string)
+	tables := make([]string, 0, 2)
+	sc := &sqlScanner{sqlStr: sqlstr, sqlLen: len(sqlstr)}
+	for sc.index < sc.sqlLen {
+		c := sqlstr[sc.index]
+		if c == '\'' {
+			sc.skipString()
+			continue
+		}
+		if sc.skipComment() {
+			continue
+		}
+		if !isIdentChar(c) {
+			sc.index++
+			continue
+		}
+		start := sc.index
+		for sc.index < sc.sqlLen && isIdentChar(sqlstr[sc.index]) {
+			sc.index++
+		}
+		word := strings.ToUpper(sqlstr[start:sc.index])
+		if word != "FROM" && word != "JOIN" && word != "UPDATE" && word != "INTO" {
+			continue
+		}
+		// 表列表,FROM 后面可以是逗号分隔的多个表
+		// Table list, several tables separated by commas may follow FROM
+		for {
+			tokenStart := sc.index
+			token, ok := nextTenantSQLToken(sc)
+			if !ok || token.kind != 'w' || tenantStopWords[token.word] || redactNotAliasWords[token.word] {
+				sc.index = tokenStart
+				break
+			}
+			table := strings.ToLower(unquoteIdentifier(sqlstr[token.Start:token.End]))
+			tables = append(tables, table)
+			aliases[table] = table
+			if index := strings.LastIndexByte(table, '.'); index >= 0 {
+				aliases[table[index+1:]] = table
+			}
+			// 别名
+			// Alias
+			tokenStart = sc.index
+			token, ok = nextTenantSQLToken(sc)
+			if ok && token.kind == 'w' && token.word == "AS" {
+				token, ok = nextTenantSQLToken(sc)
+			}
+			if ok && token.kind == 'w' && !tenantStopWords[token.word] && !tenantJoinWords[token.word] && !tenantNotAliasWords[token.word] && !redactNotAliasWords[token.word] {
+				aliases[strings.ToLower(unquoteIdentifier(sqlstr[token.Start:token.End]))] = table
+				tokenStart = sc.index
+				token, ok = nextTenantSQLToken(sc)
+			}
+			if word == "FROM" && ok && token.kind == ',' {
+				continue
+			}
+			sc.index = tokenStart
+			break
+		}
+	}
+	return aliases, tables
+}
+
+// redactSkipWords 识别参数列名时跳过的关键字,例如 name LIKE ? , age BETWEEN ? AND ?
+// redactSkipWords keywords skipped when recognizing the column of a parameter, e.g. name LIKE ? , age BETWEEN ? AND ?
+var redactSkipWords = map[string]bool{"LIKE": true, "ILIKE": true, "NOT": true, "IN": true, "BETWEEN": true, "AND": true, "IS": true}
+
+// redactCase SQL中的 CASE 表达式,例如UpdateSlice的 a=CASE id WHEN ? THEN ? ELSE a END
+// redactCase CASE expression in the SQL, e.g. a=CASE id WHEN ? THEN ? ELSE a END of UpdateSlice
+type redactCase struct {
+	// column CASE 结果对应的列,THEN 和 ELSE 的值属于这一列
+	// column the column of the CASE result, the values of THEN and ELSE belong to it
+	column, columnQualifier string
+	// operand CASE 后面的列,WHEN 的值属于这一列.CASE WHEN 格式为""
+	// operand the column after CASE, the values of WHEN belong to it. "" for the CASE WHEN form
+	operand, operandQualifier string
+	// expectOperand 下一个名称是 CASE 后面的列
+	// expectOperand the next name is the column after CASE
+	expectOperand bool
+}
+
+// sqlParamColumns 识别SQL中每个占位符对应的列名(小写)和列前面的表名或者别名(小写),支持 ? $1 :1 @p1 ,识别不到的为""
+// CASE 表达式中 THEN 和 ELSE 的值属于 CASE 前面的列, WHEN 的值属于 CASE 后面的列
+// sqlParamColumns recognizes the lower case column name of every placeholder in the SQL and the lower case table name or alias before the column, supports ? $1 :1 @p1 , "" if it can not be recognized
+// In a CASE expression the values of THEN and ELSE belong to the column before CASE, the values of WHEN belong to the column after CASE
+func sqlParamColumns(sqlstr string) ([]string, []string) {
+	sc := &sqlScanner{sqlStr: sqlstr, sqlLen: len(sqlstr)}
+	columns := make([]string, 0)
+	qualifiers := make([]string, 0)
+	// lastIdent 最近的列名,lastQualifier 列前面的表名或者别名,qualifier 正在读取的表名或者别名
+	// lastIdent the latest column name, lastQualifier the table name or alias before the column, qualifier the table name or alias being read
+	lastIdent, lastQualifier, qualifier := "", "", ""
+	// insertColumns INSERT语句的列,valuesDepth VALUES中元组的括号深度,valueIndex 元组中值的下标
+	// insertColumns columns of the INSERT statement, valuesDepth parentheses depth of the tuple in VALUES, valueIndex index of the value in the tuple
+	var insertColumns []string
+	isInsert := matchKeyword(strings.TrimSpace(sqlstr), 0, "insert")
+	inColumnList := false
+	inValues := false
+	valuesDepth := 0
+	valueIndex := 0
+	// cases 嵌套的 CASE 表达式
+	// cases nested CASE expressions
+	cases := make([]redactCase, 0)
+
+	for sc.index < sc.sqlLen {
+		c := sqlstr[sc.index]
+		if c == '\'' {
+			sc.skipString()
+			continue
+		}
+		if sc.skipComment() {
+			continue
+		}
+		ident := ""
+		switch {
+		case c == '"' || c == '`' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			start := sc.index + 1
+			sc.index = start
+			for sc.index < sc.sqlLen && sqlstr[sc.index] != end {
+				sc.index++
+			}
+			ident = sqlstr[start:sc.index]
+			sc.index++
+		case isIdentChar(c):
+			start := sc.index
+			for sc.index < sc.sqlLen && isIdentChar(sqlstr[sc.index]) {
+				sc.index++
+			}
+			ident = sqlstr[start:sc.index]
+			if c >= '0' && c <= '9' {
+				continue
+			}
+			word := strings.ToUpper(ident)
+			if isInsert && word == "VALUES" {
+				inColumnList = false
+				inValues = true
+				continue
+			}
+			if redactSkipWords[word] {
+				continue
+			}
+			switch word {
+			case "CASE":
+				cases = append(cases, redactCase{column: lastIdent, columnQualifier: lastQualifier, expectOperand: true})
+				continue
+			case "WHEN":
+				if len(cases) > 0 {
+					top := &cases[len(cases)-1]
+					top.expectOperand = false
+					if top.operand != "" {
+						lastIdent, lastQualifier = top.operand, top.operandQualifier
+					}
+				}
+				continue
+			case "THEN", "ELSE":
+				if len(cases) > 0 {
+					lastIdent, lastQualifier = cases[len(cases)-1].column, cases[len(cases)-1].columnQualifier
+				}
+				continue
+			case "END":
+				if len(cases) > 0 {
+					lastIdent, lastQualifier = cases[len(cases)-1].column, cases[len(cases)-1].columnQualifier
+					cases = cases[:len(cases)-1]
+				}
+				continue
+			}
+		case c == '?' || ((c == '$' || c == ':') && sc.index+1 < sc.sqlLen && sqlstr[sc.index+1] >= '0' && sqlstr[sc.index+1] <= '9' && (c != ':' || sc.index == 0 || sqlstr[sc.index-1] != ':')) ||
+			(c == '@' && sc.index+2 < sc.sqlLen && (sqlstr[sc.index+1] == 'p' || sqlstr[sc.index+1] == 'P') && sqlstr[sc.index+2] >= '0' && sqlstr[sc.index+2] <= '9'):
+			// 占位符
+			// placeholder
+			sc.index++
+			for sc.index < sc.sqlLen && (sqlstr[sc.index] == 'p' || sqlstr[sc.index] == 'P' || (sqlstr[sc.index] >= '0' && sqlstr[sc.index] <= '9')) {
+				sc.index++
+			}
+			column, columnQualifier := lastIdent, lastQualifier
+			if inValues {
+				column, columnQualifier = "", ""
+				if valuesDepth == 1 && valueIndex < len(insertColumns) {
+					column = insertColumns[valueIndex]
+				}
+			}
+			columns = append(columns, column)
+			qualifiers = append(qualifiers, columnQualifier)
+			continue
+		case c == '(':
+			if isInsert && !inValues && insertColumns == nil {
+				inColumnList = true
+				insertColumns = make([]string, 0)
+			} else if inValues {
+				valuesDepth++
+				if valuesDepth == 1 {
+					valueIndex = 0
+				}
+			}
+			sc.index++
+			continue
+		case c == ')':
+			if inColumnList {
+				inColumnList = false
+			} else if inValues {
+				valuesDepth--
+			}
+			sc.index++
+			continue
+		case c == ',':
+			if inValues && valuesDepth == 1 {
+				valueIndex++
+			}
+			sc.index++
+			continue
+		default:
+			sc.index++
+			continue
+		}
+		ident = strings.ToLower(ident)
+		// 带表别名的列,例如 t.name ,记录表别名
+		// Column with table alias, e.g. t.name , the table alias is recorded
+		if sc.index < sc.sqlLen && sqlstr[sc.index] == '.' {
+			if qualifier != "" {
+				qualifier += "."
+			}
+			qualifier += ident
+			continue
+		}
+		if inColumnList {
+			insertColumns = append(insertColumns, ident)
+		} else {
+			lastIdent, lastQualifier = ident, qualifier
+			if len(cases) > 0 && cases[len(cases)-1].expectOperand {
+				cases[len(cases)-1].operand, cases[len(cases)-1].operandQualifier = ident, qualifier
+				cases[len(cases)-1].expectOperand = false
+			}
+		}
+		qualifier = ""
+	}
+	return columns, qualifiers
+}
+
 func defaultPrintSQL(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {
 	if args != nil {
 		_ = log.Output(LogCallDepth, fmt.Sprintln("sql:", sqlstr, ",args:", args, ",execSQLMillis:", execSQLMillis))
@@ -63,12 +487,13 @@ func defaultPrintSQL(ctx context.Context, sqlstr string, args []interface{}, exe
 	}
 }
 
-// sqlErrorValues2String 处理values值日志记录格式
-func sqlErrorValues2String(values []interface{}) string {
+// sqlErrorValues2String 处理values值日志记录格式,按照脱敏规则处理参数值
+func sqlErrorValues2String(ctx context.Context, sqlstr string, values []interface{}) string {
 	jsonStr := "[]"
 	if len(values) < 1 {
 		return jsonStr
 	}
+	values = RedactSQLValues(ctx, sqlstr, values)
 	bytes, err := json.Marshal(values)
 	if err == nil {
 		jsonStr = string(bytes)
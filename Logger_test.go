@@ -0,0 +1,185 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resetRedactRules 清空脱敏规则,避免测试之间相互影响
+// resetRedactRules clears the redaction rules so tests do not affect each other
+func resetRedactRules() {
+	redactColumnMap = sync.Map{}
+	redactTypeMap = sync.Map{}
+	redactColumnCount = 0
+	redactTypeCount = 0
+	FuncRedactSQLValue = nil
+}
+
+func Test_sqlParamColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		sqlstr string
+		want   []string
+	}{
+		{
+			name:   "update",
+			sqlstr: "UPDATE t_user SET name=?,`Password` = ? WHERE id=?",
+			want:   []string{"name", "password", "id"},
+		},
+		{
+			name:   "insert batch",
+			sqlstr: `INSERT INTO t_user ("id",name,password) VALUES (?,?,?),($4,lower($5),$6)`,
+			want:   []string{"id", "name", "password", "id", "", "password"},
+		},
+		{
+			name:   "where keywords",
+			sqlstr: "SELECT * FROM t_user u WHERE u.name LIKE :1 AND age BETWEEN :2 AND :3 AND status IN (@p4,@p5) AND note='a=?'",
+			want:   []string{"name", "age", "age", "status", "status"},
+		},
+		{
+			name:   "case",
+			sqlstr: "UPDATE t_user SET pwd=CASE id WHEN ? THEN ? WHEN ? THEN ? ELSE pwd END,name=CASE WHEN age>? THEN ? ELSE ? END WHERE id IN (?,?)",
+			want:   []string{"id", "pwd", "id", "pwd", "age", "name", "name", "id", "id"},
+		},
+		{
+			name:   "comment and cast",
+			sqlstr: "SELECT /* pwd=? */ id::text FROM t WHERE -- x=?\n pwd <> ?",
+			want:   []string{"pwd"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := sqlParamColumns(tt.sqlstr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sqlParamColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testPassword string
+
+type testRedactEntity struct {
+	EntityStruct
+	ID       string `column:"id"`
+	Password string `column:"pwd" redact:"true"`
+}
+
+func (entity *testRedactEntity) GetTableName() string {
+	return "t_redact"
+}
+
+func (entity *testRedactEntity) GetPKColumnName() string {
+	return "id"
+}
+
+func Test_RedactSQLValues(t *testing.T) {
+	resetRedactRules()
+	defer resetRedactRules()
+	ctx := context.Background()
+	args := []interface{}{"u1", "secret", testPassword("p"), "110101199001011234"}
+	sqlstr := "UPDATE t_redact SET id=?,pwd=?,pwd2=?,id_card=?"
+
+	if got := RedactSQLValues(ctx, sqlstr, args); &got[0] != &args[0] {
+		t.Errorf("args should be returned as is without rules")
+	}
+
+	// struct tag 注册 表名.列名 | struct tag registers table.column
+	if err := RegisterRedactEntity(&testRedactEntity{}); err != nil {
+		t.Fatal(err)
+	}
+	RegisterRedactType(testPassword(""))
+	FuncRedactSQLValue = func(ctx context.Context, column string, value interface{}) (interface{}, bool) {
+		if column == "id_card" {
+			s := value.(string)
+			return s[:3] + "***", true
+		}
+		return nil, false
+	}
+	got := RedactSQLValues(ctx, sqlstr, args)
+	want := []interface{}{"u1", RedactMask, RedactMask, "110***"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactSQLValues() = %v, want %v", got, want)
+	}
+	if args[1] != "secret" {
+		t.Errorf("args should not be modified")
+	}
+
+	errStr := sqlErrorValues2String(ctx, sqlstr, args)
+	if strings.Contains(errStr, "secret") || strings.Contains(errStr, "1234") {
+		t.Errorf("sqlErrorValues2String() = %s, sensitive values are not redacted", errStr)
+	}
+}
+
+// Test_RedactSQLValues_table 按照 表名.列名 脱敏,使用表名或者别名识别列的表
+// Test_RedactSQLValues_table redaction by table.column, the table of the column is recognized by the table name or alias
+func Test_RedactSQLValues_table(t *testing.T) {
+	resetRedactRules()
+	defer resetRedactRules()
+	ctx := context.Background()
+	if err := RegisterRedactEntity(&testRedactEntity{}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		sqlstr string
+		args   []interface{}
+		want   []interface{}
+	}{
+		{"UPDATE t_user SET pwd=? WHERE id=?", []interface{}{"p1", 1}, []interface{}{"p1", 1}},
+		{"INSERT INTO db.t_redact (id,pwd) VALUES (?,?)", []interface{}{1, "p1"}, []interface{}{1, RedactMask}},
+		{"SELECT * FROM t_user u JOIN t_redact AS r ON r.id=u.id WHERE u.pwd=? AND r.pwd=?", []interface{}{"p1", "p2"}, []interface{}{"p1", RedactMask}},
+		{"SELECT * FROM t_user u, t_redact r WHERE pwd=?", []interface{}{"p1"}, []interface{}{RedactMask}},
+		{"DELETE FROM t_user WHERE id IN (SELECT id FROM t_redact WHERE pwd=?)", []interface{}{"p1"}, []interface{}{RedactMask}},
+	}
+	for _, tt := range tests {
+		if got := RedactSQLValues(ctx, tt.sqlstr, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RedactSQLValues(%s) = %v, want %v", tt.sqlstr, got, tt.want)
+		}
+	}
+}
+
+// Test_RedactSQLValues_updateSlice UpdateSlice的 CASE WHEN 语句也要脱敏
+// Test_RedactSQLValues_updateSlice the CASE WHEN statement of UpdateSlice is also redacted
+func Test_RedactSQLValues_updateSlice(t *testing.T) {
+	resetRedactRules()
+	defer resetRedactRules()
+	ctx := context.Background()
+	config := &DataSourceConfig{Dialect: "mysql"}
+	if err := RegisterRedactEntity(&testRedactEntity{}); err != nil {
+		t.Fatal(err)
+	}
+	entities := []IEntityStruct{&testRedactEntity{ID: "1", Password: "secret1"}, &testRedactEntity{ID: "2", Password: "secret2"}}
+	entityCache, err := getEntityStructCache(ctx, entities[0], config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlstr, values, err := updateSliceFieldValues(ctx, config, entities, entityCache, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := RedactSQLValues(ctx, *sqlstr, *values)
+	want := []interface{}{"1", RedactMask, "2", RedactMask, "1", "2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactSQLValues(%s) = %v, want %v", *sqlstr, got, want)
+	}
+}
@@ -204,17 +204,38 @@ func OverrideFunc(funcName string, funcObject interface{}) (bool, interface{}, e
 		}
 
 	case "wrapDeleteSQL": //删除 IEntityStruct 的SQL
-		newFunc, ok := funcObject.(func(ctx context.Context, entity IEntityStruct) (string, error))
-		if ok {
+		// 兼容没有config参数的旧函数
+		// Compatible with the old function without the config parameter
+		switch newFunc := funcObject.(type) {
+		case func(ctx context.Context, config *DataSourceConfig, entity IEntityStruct) (string, error):
 			oldFunc = wrapDeleteSQL
 			wrapDeleteSQL = newFunc
+		case func(ctx context.Context, entity IEntityStruct) (string, error):
+			oldFunc = wrapDeleteSQL
+			wrapDeleteSQL = func(ctx context.Context, config *DataSourceConfig, entity IEntityStruct) (string, error) {
+				return newFunc(ctx, entity)
+			}
 		}
 
 	case "wrapUpdateEntityMapSQL": //更新 IEntityMap 的SQL
-		newFunc, ok := funcObject.(func(ctx context.Context, entity IEntityMap) (*string, *[]interface{}, error))
-		if ok {
+		// 兼容没有config参数的旧函数
+		// Compatible with the old function without the config parameter
+		switch newFunc := funcObject.(type) {
+		case func(ctx context.Context, config *DataSourceConfig, entity IEntityMap) (*string, *[]interface{}, error):
 			oldFunc = wrapUpdateEntityMapSQL
 			wrapUpdateEntityMapSQL = newFunc
+		case func(ctx context.Context, entity IEntityMap) (*string, *[]interface{}, error):
+			oldFunc = wrapUpdateEntityMapSQL
+			wrapUpdateEntityMapSQL = func(ctx context.Context, config *DataSourceConfig, entity IEntityMap) (*string, *[]interface{}, error) {
+				return newFunc(ctx, entity)
+			}
+		}
+
+	case "wrapTenantFinder": //Finder 语句增加租户条件
+		newFunc, ok := funcObject.(func(ctx context.Context, config *DataSourceConfig, finder *Finder) (*Finder, error))
+		if ok {
+			oldFunc = wrapTenantFinder
+			wrapTenantFinder = newFunc
 		}
 
 	default:
@@ -0,0 +1,501 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zormTagOptionArray 字段是postgresql和kingbase的数组列,例如 `column:"tags" zorm:"array"`,适用于slice字段,保存和查询使用数组的文本格式
+// zormTagOptionArray the field is an array column of postgresql and kingbase, e.g. `column:"tags" zorm:"array"`, for slice fields, the text format of arrays is used when saving and querying
+const zormTagOptionArray = "array"
+
+// Array postgresql和kingbase的数组参数和接收对象,reBuildSQL不会把Array展开为IN的多个参数.
+// 保存时V是slice,例如 []int64,[]string,[]decimal.Decimal;查询时V是slice的指针.支持嵌套的slice(多维数组),NULL元素使用指针类型的元素接收
+// Array parameter and receiver of postgresql and kingbase arrays, reBuildSQL does not expand Array into multiple IN parameters.
+// V is a slice when saving, e.g. []int64, []string, []decimal.Decimal; V is a pointer to a slice when querying. Nested slices (multidimensional arrays) are supported, NULL elements are received with pointer elements
+type Array struct {
+	V interface{}
+}
+
+// NewArray 创建数组参数或者接收对象,例如 finder.Append("WHERE tags && ?", zorm.NewArray([]string{"a", "b"}))
+// NewArray creates an array parameter or receiver, e.g. finder.Append("WHERE tags && ?", zorm.NewArray([]string{"a", "b"}))
+func NewArray(v interface{}) *Array {
+	return &Array{V: v}
+}
+
+// Value 实现driver.Valuer接口,返回数组的文本格式,例如 {"1","2"},nil的slice是NULL
+// Value implements the driver.Valuer interface, returns the text format of the array, e.g. {"1","2"}, a nil slice is NULL
+func (array Array) Value() (driver.Value, error) {
+	valueOf := reflect.ValueOf(array.V)
+	if valueOf.Kind() == reflect.Ptr {
+		valueOf = valueOf.Elem()
+	}
+	if !valueOf.IsValid() || (valueOf.Kind() == reflect.Slice && valueOf.IsNil()) {
+		return nil, nil
+	}
+	var builder strings.Builder
+	if err := writeArrayText(&builder, valueOf); err != nil {
+		return nil, err
+	}
+	return builder.String(), nil
+}
+
+// Scan 实现sql.Scanner接口,解析数组的文本格式到V指向的slice,NULL时slice设置为nil
+// Scan implements the sql.Scanner interface, parses the text format of the array into the slice pointed to by V, the slice is set to nil for NULL
+func (array *Array) Scan(src interface{}) error {
+	valueOf := reflect.ValueOf(array.V)
+	if valueOf.Kind() != reflect.Ptr || valueOf.IsNil() || valueOf.Elem().Kind() != reflect.Slice {
+		return errors.New("->Array.Scan-->V必须是slice的指针")
+	}
+	valueOf = valueOf.Elem()
+	text, isNull, err := driverValueText(src)
+	if err != nil {
+		return err
+	}
+	if isNull {
+		valueOf.Set(reflect.Zero(valueOf.Type()))
+		return nil
+	}
+	// 去掉维度声明,例如 [1:3]={1,2,3}
+	// Remove the dimension decoration, e.g. [1:3]={1,2,3}
+	if strings.HasPrefix(text, "[") {
+		if index := strings.Index(text, "="); index > 0 {
+			text = text[index+1:]
+		}
+	}
+	items, end, err := parseArrayText(text, 0)
+	if err != nil {
+		return err
+	}
+	if end != len(text) {
+		return fmt.Errorf("->Array.Scan-->数组格式错误:%s", text)
+	}
+	return setArrayItems(valueOf, items)
+}
+
+// writeArrayText 写入数组的文本格式,元素都使用双引号包裹,数据库会转换为数组元素的类型
+// writeArrayText writes the text format of the array, all elements are wrapped in double quotes, the database converts them to the element type
+func writeArrayText(builder *strings.Builder, valueOf reflect.Value) error {
+	if valueOf.Kind() != reflect.Slice && valueOf.Kind() != reflect.Array {
+		return fmt.Errorf("->writeArrayText-->不支持的数组类型:%s", valueOf.Type().String())
+	}
+	builder.WriteByte('{')
+	for i := 0; i < valueOf.Len(); i++ {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		elem := valueOf.Index(i)
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			if elem.IsNil() {
+				break
+			}
+			elem = elem.Elem()
+		}
+		if (elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface) && elem.IsNil() {
+			builder.WriteString("NULL")
+			continue
+		}
+		if (elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() != reflect.Uint8) || elem.Kind() == reflect.Array {
+			if err := writeArrayText(builder, elem); err != nil {
+				return err
+			}
+			continue
+		}
+		text, isNull, err := elementText(elem.Interface())
+		if err != nil {
+			return err
+		}
+		if isNull {
+			builder.WriteString("NULL")
+			continue
+		}
+		writeQuotedText(builder, text)
+	}
+	builder.WriteByte('}')
+	return nil
+}
+
+// writeQuotedText 使用双引号包裹文本,转义双引号和反斜杠
+// writeQuotedText wraps the text in double quotes, escaping double quotes and backslashes
+func writeQuotedText(builder *strings.Builder, text string) {
+	builder.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		if text[i] == '"' || text[i] == '\\' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(text[i])
+	}
+	builder.WriteByte('"')
+}
+
+// elementText 数组元素和范围边界的文本,driver.Valuer使用Value()的值
+// elementText text of array elements and range bounds, driver.Valuer uses the value of Value()
+func elementText(value interface{}) (string, bool, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		if err != nil {
+			return "", false, err
+		}
+		value = driverValue
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", true, nil
+	case string:
+		return v, false, nil
+	case []byte:
+		return string(v), false, nil
+	case bool:
+		if v {
+			return "t", false, nil
+		}
+		return "f", false, nil
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999Z07:00"), false, nil
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.String:
+		return valueOf.String(), false, nil
+	case reflect.Bool:
+		return elementText(valueOf.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(valueOf.Int(), 10), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(valueOf.Uint(), 10), false, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(valueOf.Float(), 'g', -1, valueOf.Type().Bits()), false, nil
+	}
+	return "", false, fmt.Errorf("->elementText-->不支持的元素类型:%T", value)
+}
+
+// arrayItem 解析的数组元素,items不为nil时是嵌套的数组
+// arrayItem parsed array element, a nested array when items is not nil
+type arrayItem struct {
+	text   string
+	isNull bool
+	items  []arrayItem
+}
+
+// parseArrayText 从start解析数组的文本格式,返回元素和结束的下标
+// parseArrayText parses the text format of an array from start, returns the elements and the end index
+func parseArrayText(text string, start int) ([]arrayItem, int, error) {
+	if start >= len(text) || text[start] != '{' {
+		return nil, start, fmt.Errorf("->parseArrayText-->数组格式错误:%s", text)
+	}
+	items := make([]arrayItem, 0)
+	i := start + 1
+	if i < len(text) && text[i] == '}' {
+		return items, i + 1, nil
+	}
+	for i < len(text) {
+		var item arrayItem
+		switch text[i] {
+		case '{': // 嵌套的数组 | Nested array
+			children, end, err := parseArrayText(text, i)
+			if err != nil {
+				return nil, end, err
+			}
+			item.items = children
+			i = end
+		case '"': // 双引号包裹的元素 | Element wrapped in double quotes
+			var builder strings.Builder
+			i++
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				builder.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, i, fmt.Errorf("->parseArrayText-->双引号没有闭合:%s", text)
+			}
+			item.text = builder.String()
+			i++
+		default:
+			end := i
+			for end < len(text) && text[end] != ',' && text[end] != '}' {
+				end++
+			}
+			item.text = strings.TrimSpace(text[i:end])
+			item.isNull = strings.EqualFold(item.text, "NULL")
+			i = end
+		}
+		items = append(items, item)
+		if i >= len(text) {
+			break
+		}
+		if text[i] == '}' {
+			return items, i + 1, nil
+		}
+		if text[i] != ',' {
+			return nil, i, fmt.Errorf("->parseArrayText-->数组格式错误:%s", text)
+		}
+		i++
+	}
+	return nil, i, fmt.Errorf("->parseArrayText-->数组没有闭合:%s", text)
+}
+
+// setArrayItems 把解析的元素赋值给slice
+// setArrayItems assigns the parsed elements to the slice
+func setArrayItems(sliceValue reflect.Value, items []arrayItem) error {
+	newSlice := reflect.MakeSlice(sliceValue.Type(), len(items), len(items))
+	for i, item := range items {
+		elem := newSlice.Index(i)
+		if item.items != nil {
+			if elem.Kind() != reflect.Slice {
+				return fmt.Errorf("->setArrayItems-->多维数组不能赋值给%s", sliceValue.Type().String())
+			}
+			if err := setArrayItems(elem, item.items); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setElementText(elem, item.text, item.isNull); err != nil {
+			return err
+		}
+	}
+	sliceValue.Set(newSlice)
+	return nil
+}
+
+// setElementText 把数组元素或者范围边界的文本赋值给dest,NULL时dest保持零值
+// setElementText assigns the text of an array element or range bound to dest, dest keeps the zero value for NULL
+func setElementText(dest reflect.Value, text string, isNull bool) error {
+	if isNull {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Ptr {
+		if dest.IsNil() {
+			dest.Set(reflect.New(dest.Type().Elem()))
+		}
+		return setElementText(dest.Elem(), text, false)
+	}
+	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(text)
+	}
+	if dest.Type() == timeType {
+		t, err := parseElementTime(text)
+		if err != nil {
+			return err
+		}
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch dest.Kind() {
+	case reflect.String:
+		dest.SetString(text)
+	case reflect.Bool:
+		dest.SetBool(text == "t" || text == "true" || text == "TRUE")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(text, 10, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(text, 10, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(text, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetFloat(v)
+	case reflect.Interface:
+		dest.Set(reflect.ValueOf(text))
+	default:
+		return fmt.Errorf("->setElementText-->不支持的元素类型:%s", dest.Type().String())
+	}
+	return nil
+}
+
+// elementTimeLayouts 数组元素和范围边界的时间格式
+// elementTimeLayouts time layouts of array elements and range bounds
+var elementTimeLayouts = []string{"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+// parseElementTime 解析数组元素和范围边界的时间
+// parseElementTime parses the time of array elements and range bounds
+func parseElementTime(text string) (time.Time, error) {
+	for _, layout := range elementTimeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("->parseElementTime-->不支持的时间格式:%s", text)
+}
+
+// driverValueText 数据库驱动返回的数组和范围的文本
+// driverValueText text of arrays and ranges returned by the database driver
+func driverValueText(src interface{}) (string, bool, error) {
+	switch v := src.(type) {
+	case nil:
+		return "", true, nil
+	case []byte:
+		return string(v), false, nil
+	case string:
+		return v, false, nil
+	}
+	return "", false, fmt.Errorf("->driverValueText-->不支持的值类型:%T", src)
+}
+
+// Range postgresql和kingbase的范围类型,例如 int4range,numrange,tstzrange,daterange.
+// 保存时Lower和Upper是边界的值;查询时Lower和Upper是接收边界的指针,为nil时使用string接收.nil的边界是无限
+// Range range type of postgresql and kingbase, e.g. int4range, numrange, tstzrange, daterange.
+// Lower and Upper are the bound values when saving; when querying Lower and Upper are pointers receiving the bounds, string is used when nil. A nil bound is unbounded
+type Range struct {
+	// Lower 下界,nil是无限
+	// Lower lower bound, nil is unbounded
+	Lower interface{}
+	// Upper 上界,nil是无限
+	// Upper upper bound, nil is unbounded
+	Upper interface{}
+	// LowerInclusive 是否包含下界,[ 或者 (
+	// LowerInclusive whether the lower bound is inclusive, [ or (
+	LowerInclusive bool
+	// UpperInclusive 是否包含上界,] 或者 )
+	// UpperInclusive whether the upper bound is inclusive, ] or )
+	UpperInclusive bool
+	// Empty 是否是空范围
+	// Empty whether it is an empty range
+	Empty bool
+}
+
+// Value 实现driver.Valuer接口,返回范围的文本格式,例如 [1,10)
+// Value implements the driver.Valuer interface, returns the text format of the range, e.g. [1,10)
+func (r Range) Value() (driver.Value, error) {
+	if r.Empty {
+		return "empty", nil
+	}
+	var builder strings.Builder
+	if r.LowerInclusive {
+		builder.WriteByte('[')
+	} else {
+		builder.WriteByte('(')
+	}
+	for i, bound := range []interface{}{r.Lower, r.Upper} {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		if valueOf := reflect.ValueOf(bound); valueOf.Kind() == reflect.Ptr {
+			if valueOf.IsNil() {
+				continue
+			}
+			bound = valueOf.Elem().Interface()
+		}
+		text, isNull, err := elementText(bound)
+		if err != nil {
+			return nil, err
+		}
+		if !isNull {
+			writeQuotedText(&builder, text)
+		}
+	}
+	if r.UpperInclusive {
+		builder.WriteByte(']')
+	} else {
+		builder.WriteByte(')')
+	}
+	return builder.String(), nil
+}
+
+// Scan 实现sql.Scanner接口,解析范围的文本格式
+// Scan implements the sql.Scanner interface, parses the text format of the range
+func (r *Range) Scan(src interface{}) error {
+	text, isNull, err := driverValueText(src)
+	if err != nil || isNull {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	if strings.EqualFold(text, "empty") {
+		r.Empty, r.Lower, r.Upper = true, nil, nil
+		return nil
+	}
+	if len(text) < 3 || (text[0] != '[' && text[0] != '(') || (text[len(text)-1] != ']' && text[len(text)-1] != ')') {
+		return fmt.Errorf("->Range.Scan-->范围格式错误:%s", text)
+	}
+	r.Empty = false
+	r.LowerInclusive = text[0] == '['
+	r.UpperInclusive = text[len(text)-1] == ']'
+	bounds, err := splitRangeBounds(text[1 : len(text)-1])
+	if err != nil {
+		return err
+	}
+	for i, dest := range []*interface{}{&r.Lower, &r.Upper} {
+		if bounds[i] == nil { // 无限 | Unbounded
+			*dest = nil
+			continue
+		}
+		valueOf := reflect.ValueOf(*dest)
+		if valueOf.Kind() != reflect.Ptr || valueOf.IsNil() {
+			*dest = *bounds[i]
+			continue
+		}
+		if err = setElementText(valueOf.Elem(), *bounds[i], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitRangeBounds 拆分范围的上下界,nil是无限
+// splitRangeBounds splits the lower and upper bounds of a range, nil is unbounded
+func splitRangeBounds(text string) ([2]*string, error) {
+	var bounds [2]*string
+	index := 0
+	i := 0
+	for index < 2 {
+		var builder strings.Builder
+		quoted := false
+		for ; i < len(text) && text[i] != ','; i++ {
+			if text[i] != '"' {
+				builder.WriteByte(text[i])
+				continue
+			}
+			quoted = true
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				builder.WriteByte(text[i])
+			}
+		}
+		if bound := builder.String(); bound != "" || quoted {
+			bounds[index] = &bound
+		}
+		index++
+		if index == 1 && (i >= len(text) || text[i] != ',') {
+			return bounds, fmt.Errorf("->splitRangeBounds-->范围格式错误:%s", text)
+		}
+		i++
+	}
+	return bounds, nil
+}
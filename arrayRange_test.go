@@ -0,0 +1,206 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"gitee.com/chunanyong/zorm/decimal"
+)
+
+// testArrayEntity 使用 zorm:"array" tag的实体类
+// testArrayEntity entity using the zorm:"array" tag
+type testArrayEntity struct {
+	EntityStruct
+	ID     string            `column:"id"`
+	Scores []int64           `column:"scores" zorm:"array"`
+	Prices []decimal.Decimal `column:"prices" zorm:"array"`
+	Tags   *[]string         `column:"tags" zorm:"array"`
+}
+
+func (entity *testArrayEntity) GetTableName() string {
+	return "t_array"
+}
+
+func Test_Array_Value(t *testing.T) {
+	one := 1
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{[]int64{1, -2}, `{"1","-2"}`},
+		{[]string{`a"b`, `c\d`, ""}, `{"a\"b","c\\d",""}`},
+		{[]*int{&one, nil}, `{"1",NULL}`},
+		{[][]int{{1, 2}, {3, 4}}, `{{"1","2"},{"3","4"}}`},
+		{[]bool{true, false}, `{"t","f"}`},
+		{[]decimal.Decimal{decimal.RequireFromString("1.50")}, `{"1.5"}`},
+		{[]int{}, `{}`},
+		{[]int(nil), nil},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := Array{V: tt.value}.Value()
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Array{%v}.Value() = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := (Array{V: 1}).Value(); err == nil {
+		t.Error("expected error for a non slice value")
+	}
+}
+
+func Test_Array_Scan(t *testing.T) {
+	var ints []int64
+	if err := NewArray(&ints).Scan([]byte(`{1,-2, 3}`)); err != nil || !reflect.DeepEqual(ints, []int64{1, -2, 3}) {
+		t.Errorf("ints = %v, %v", ints, err)
+	}
+	var strs []*string
+	if err := NewArray(&strs).Scan(`{a,"b,c","d\"e",NULL,"NULL"}`); err != nil || len(strs) != 5 {
+		t.Fatalf("strs = %v, %v", strs, err)
+	}
+	if *strs[0] != "a" || *strs[1] != "b,c" || *strs[2] != `d"e` || strs[3] != nil || *strs[4] != "NULL" {
+		t.Errorf("strs = %q %q %q %v %q", *strs[0], *strs[1], *strs[2], strs[3], *strs[4])
+	}
+	var matrix [][]int
+	if err := NewArray(&matrix).Scan(`[1:2][1:2]={{1,2},{3,4}}`); err != nil || !reflect.DeepEqual(matrix, [][]int{{1, 2}, {3, 4}}) {
+		t.Errorf("matrix = %v, %v", matrix, err)
+	}
+	var prices []decimal.Decimal
+	if err := NewArray(&prices).Scan(`{1.50,2}`); err != nil || len(prices) != 2 || prices[0].String() != "1.5" {
+		t.Errorf("prices = %v, %v", prices, err)
+	}
+	var bools []bool
+	if err := NewArray(&bools).Scan(`{t,f}`); err != nil || !reflect.DeepEqual(bools, []bool{true, false}) {
+		t.Errorf("bools = %v, %v", bools, err)
+	}
+	empty := []int64{1}
+	if err := NewArray(&empty).Scan(`{}`); err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("empty = %v, %v", empty, err)
+	}
+	if err := NewArray(&empty).Scan(nil); err != nil || empty != nil {
+		t.Errorf("NULL = %v, %v", empty, err)
+	}
+	for _, src := range []string{`{1,2`, `1,2`, `{"a}`, `{1}x`, `{x}`} {
+		if err := NewArray(&ints).Scan(src); err == nil {
+			t.Errorf("expected error for %s", src)
+		}
+	}
+	if err := NewArray(ints).Scan(`{1}`); err == nil {
+		t.Error("expected error for a non pointer V")
+	}
+}
+
+func Test_Range(t *testing.T) {
+	lower, upper := 1, 10
+	tests := []struct {
+		value Range
+		want  string
+	}{
+		{Range{Lower: lower, Upper: &upper, LowerInclusive: true}, `["1","10")`},
+		{Range{Upper: "2024-01-01", UpperInclusive: true}, `(,"2024-01-01"]`},
+		{Range{Lower: decimal.RequireFromString("1.5")}, `("1.5",)`},
+		{Range{Empty: true}, `empty`},
+	}
+	for _, tt := range tests {
+		got, err := tt.value.Value()
+		if err != nil || got != tt.want {
+			t.Errorf("Range.Value() = %v, %v, want %v", got, err, tt.want)
+		}
+	}
+
+	var from, to int
+	r := Range{Lower: &from, Upper: &to}
+	if err := r.Scan([]byte(`[1,10)`)); err != nil || from != 1 || to != 10 || !r.LowerInclusive || r.UpperInclusive {
+		t.Errorf("Range.Scan() = %+v %d %d, %v", r, from, to, err)
+	}
+	r = Range{}
+	if err := r.Scan(`("2024-01-01 00:00:00+08",]`); err != nil || r.Lower != "2024-01-01 00:00:00+08" || r.Upper != nil || !r.UpperInclusive {
+		t.Errorf("Range.Scan() = %+v, %v", r, err)
+	}
+	if err := r.Scan(`empty`); err != nil || !r.Empty {
+		t.Errorf("Range.Scan(empty) = %+v, %v", r, err)
+	}
+	if err := r.Scan(`[1`); err == nil {
+		t.Error("expected error for an invalid range")
+	}
+}
+
+func Test_Array_write(t *testing.T) {
+	// Array不会被reBuildSQL展开为IN的多个参数
+	// Array is not expanded into multiple IN parameters by reBuildSQL
+	ctx := context.Background()
+	config := &DataSourceConfig{Dialect: "postgresql"}
+	sqlstr := "tags && ? AND id IN (?)"
+	args := []interface{}{NewArray([]string{"a", "b"}), []int{1, 2}}
+	rebuildSQL, newArgs, err := reBuildSQL(ctx, config, &sqlstr, &args)
+	if err != nil || *rebuildSQL != "tags && $1 AND id IN ($2,$3)" || len(*newArgs) != 3 {
+		t.Errorf("reBuildSQL() = %q, %v, %v", *rebuildSQL, *newArgs, err)
+	}
+
+	tags := []string{"x", "y z"}
+	entity := &testArrayEntity{ID: "1", Scores: []int64{1, 2}, Tags: &tags}
+	entityCache, err := getEntityStructCache(ctx, entity, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]interface{}, 0)
+	if err = insertEntityFieldValues(ctx, entity, entityCache, true, &values); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"1", `{"1","2"}`, nil, `{"x","y z"}`}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("insert values = %v, want %v", values, want)
+	}
+}
+
+// scanFakeArrayTable 数组列的结果集,tags是postgresql的text[]列
+// scanFakeArrayTable result set of the array column, tags is a text[] column of postgresql
+var scanFakeArrayTable = &scanFakeTable{
+	name:        "array",
+	columns:     []string{"tags"},
+	columnTypes: []string{"_TEXT"},
+	row: func(index int, values []driver.Value) {
+		values[0] = []byte(`{a,"b c",NULL}`)
+	},
+}
+
+func Test_Array_read(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeArrayTable, 2)
+	type arrayRow struct {
+		Tags []*string `column:"tags" zorm:"array"`
+	}
+	list := make([]arrayRow, 0)
+	if err := Query(ctx, NewSelectFinder("test_table"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || len(list[1].Tags) != 3 || *list[1].Tags[1] != "b c" || list[1].Tags[2] != nil {
+		t.Errorf("Query = %+v", list)
+	}
+
+	// 单列查询使用Array接收
+	// Single column query received with Array
+	var tags []string
+	has, err := QueryRow(newScanFakeContext(t, scanFakeArrayTable, 1), NewFinder().Append("SELECT tags FROM test_table"), NewArray(&tags))
+	if err != nil || !has || !reflect.DeepEqual(tags, []string{"a", "b c", ""}) {
+		t.Errorf("QueryRow = %v, %v, %v", tags, has, err)
+	}
+}
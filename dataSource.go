@@ -23,6 +23,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -37,23 +38,6 @@ type dataSource struct {
 	// config *DataSourceConfig
 }
 
-// dbDialectMap 数据库方言的map,用来检查Dialect设置是否正确
-// dbDialectMap is a map of database dialects, used to check whether the Dialect setting is correct
-var dbDialectMap = map[string]string{
-	"mysql":      "mysql",
-	"postgresql": "postgres",
-	"oracle":     "oracle",
-	"mssql":      "sqlserver",
-	"sqlite":     "sqlite3",
-	"db2":        "go_ibm_db",
-	"clickhouse": "clickhouse",
-	"dm":         "dm",
-	"kingbase":   "kingbase",
-	"gbase":      "odbc",
-	"shentong":   "aci",
-	"tdengine":   "taosSql",
-}
-
 // newDataSource 创建一个新的datasource,内部调用,避免外部直接使用datasource
 // newDAtaSource Create a new datasource and call it internally to avoid direct external use of the datasource
 func newDataSource(config *DataSourceConfig) (*dataSource, error) {
@@ -69,9 +53,8 @@ func newDataSource(config *DataSourceConfig) (*dataSource, error) {
 		return nil, errors.New("->newDataSource-->Dialect cannot be empty")
 	}
 
-	_, has := dbDialectMap[config.Dialect]
-	if !has {
-		return nil, errors.New("->newDataSource-->Dialect not supported, please check the Dialect configuration. Supported Dialect include mysql,postgresql,oracle,mssql,sqlite,db2,clickhouse,dm,kingbase,shentong,tdengine ")
+	if GetDialect(config.Dialect) == nil {
+		return nil, errors.New("->newDataSource-->Dialect not supported, please check the Dialect configuration. Supported Dialect include " + strings.Join(dialectNames(), ","))
 	}
 
 	var db *sql.DB
@@ -203,6 +186,13 @@ func (dbConnection *dataBaseConnection) commit() error {
 // execContext 执行sql语句,如果已经开启事务,就以事务方式执行,如果没有开启事务,就以非事务方式执行
 // execContext Execute sql statement,If the transaction has been opened,it will be executed in transaction mode, if the transaction is not opened,it will be executed in non-transactional mode
 func (dbConnection *dataBaseConnection) execContext(ctx context.Context, sqlstr *string, argsValues *[]interface{}) (*sql.Result, error) {
+	// IValueConver 转换参数的值
+	argsValues, err := wrapValueConverArgs(ctx, argsValues)
+	if err != nil {
+		return nil, err
+	}
+	// TimePolicy 转换时间参数的时区和精度
+	argsValues = wrapTimePolicyArgs(dbConnection.config.TimePolicy, argsValues)
 	// reBuildSQL 重新处理参数代入方式
 	execsql, args, err := reBuildSQL(ctx, dbConnection.config, sqlstr, argsValues)
 	if err != nil {
@@ -218,6 +208,11 @@ func (dbConnection *dataBaseConnection) execContext(ctx context.Context, sqlstr
 	if err != nil {
 		return nil, err
 	}
+	// 执行前加入 sql 注释
+	err = wrapSQLComment(ctx, dbConnection.config, execsql)
+	if err != nil {
+		return nil, err
+	}
 
 	var start *time.Time
 	var res sql.Result
@@ -229,7 +224,7 @@ func (dbConnection *dataBaseConnection) execContext(ctx context.Context, sqlstr
 		} else {
 			stmt, err = dbConnection.db.PrepareContext(ctx, *execsql)
 			if err != nil {
-				err = fmt.Errorf("->execContext-->db.PrepareContext:%w,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", err, *execsql, sqlErrorValues2String(*args))
+				err = fmt.Errorf("->execContext-->db.PrepareContext:%w,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", err, *execsql, sqlErrorValues2String(ctx, *execsql, *args))
 			}
 			stmtSQLCacheMap.Store(*execsql, stmt)
 		}
@@ -237,72 +232,112 @@ func (dbConnection *dataBaseConnection) execContext(ctx context.Context, sqlstr
 	// 小于0是禁用日志输出;等于0是只输出日志,不计算SQ执行时间;大于0是计算执行时间,并且大于指定值
 	slowSQLMillis := dbConnection.config.SlowSQLMillis
 	if slowSQLMillis == 0 {
-		FuncPrintSQL(ctx, *execsql, *args, 0)
+		FuncPrintSQL(ctx, *execsql, RedactSQLValues(ctx, *execsql, *args), 0)
 	} else if slowSQLMillis > 0 {
 		now := time.Now() // 获取当前时间
 		start = &now
 	}
 
+	// 执行前调用钩子
+	// Call hooks before execution
+	hookCtx, hookEvent := beforeSQLHooks(ctx, dbConnection.config, SQLHookOperationExec, *execsql, *args, dbConnection.tx != nil)
 	if dbConnection.tx != nil {
 		//txStmt := dbConnection.tx.StmtContext(ctx, stmt)
 		//res, err = txStmt.ExecContext(ctx, *args...)
-		res, err = dbConnection.tx.ExecContext(ctx, *execsql, *args...)
+		res, err = dbConnection.tx.ExecContext(hookCtx, *execsql, *args...)
 	} else {
 		//res, err = stmt.ExecContext(ctx, *args...)
-		res, err = dbConnection.db.ExecContext(ctx, *execsql, *args...)
+		res, err = dbConnection.db.ExecContext(hookCtx, *execsql, *args...)
+	}
+	if hookEvent != nil {
+		if err == nil {
+			if rowsAffected, errAffected := res.RowsAffected(); errAffected == nil {
+				hookEvent.RowsAffected = rowsAffected
+			}
+		}
+		afterSQLHooks(hookEvent, err)
 	}
 	if slowSQLMillis > 0 {
 		slow := time.Since(*start).Milliseconds()
 		if slow-int64(slowSQLMillis) >= 0 {
-			FuncPrintSQL(ctx, *execsql, *args, slow)
+			FuncPrintSQL(ctx, *execsql, RedactSQLValues(ctx, *execsql, *args), slow)
+			dbConnection.slowSQLExplain(ctx, *execsql, *args, slow)
 		}
 	}
 	if err != nil {
-		err = fmt.Errorf("->execContext执行错误:%w,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", err, *execsql, sqlErrorValues2String(*args))
+		err = fmt.Errorf("->execContext执行错误:%w,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", err, *execsql, sqlErrorValues2String(ctx, *execsql, *args))
 	}
 	return &res, err
 }
 
-// queryRowContext 如果已经开启事务,就以事务方式执行,如果没有开启事务,就以非事务方式执行
-func (dbConnection *dataBaseConnection) queryRowContext(ctx context.Context, sqlstr *string, argsValues *[]interface{}) (*sql.Row, error) {
+// queryRowContext 如果已经开启事务,就以事务方式执行,如果没有开启事务,就以非事务方式执行.在方法内Scan到dest,钩子记录Scan的错误
+// queryRowContext Execute sql row statement and Scan into dest, the hooks record the Scan error
+func (dbConnection *dataBaseConnection) queryRowContext(ctx context.Context, sqlstr *string, argsValues *[]interface{}, dest ...interface{}) error {
+	// IValueConver 转换参数的值
+	argsValues, err := wrapValueConverArgs(ctx, argsValues)
+	if err != nil {
+		return err
+	}
+	// TimePolicy 转换时间参数的时区和精度
+	argsValues = wrapTimePolicyArgs(dbConnection.config.TimePolicy, argsValues)
 	// reBuildSQL 重新处理参数代入方式
 	query, args, err := reBuildSQL(ctx, dbConnection.config, sqlstr, argsValues)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	// 执行前加入 hint
 	err = wrapSQLHint(ctx, query)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	// 执行前加入 sql 注释
+	err = wrapSQLComment(ctx, dbConnection.config, query)
+	if err != nil {
+		return err
 	}
 	var start *time.Time
 	var row *sql.Row
 	// 小于0是禁用日志输出;等于0是只输出日志,不计算SQ执行时间;大于0是计算执行时间,并且大于指定值
 	slowSQLMillis := dbConnection.config.SlowSQLMillis
 	if slowSQLMillis == 0 {
-		FuncPrintSQL(ctx, *query, *args, 0)
+		FuncPrintSQL(ctx, *query, RedactSQLValues(ctx, *query, *args), 0)
 	} else if slowSQLMillis > 0 {
 		now := time.Now() // 获取当前时间
 		start = &now
 	}
 
+	// 执行前调用钩子
+	// Call hooks before execution
+	hookCtx, hookEvent := beforeSQLHooks(ctx, dbConnection.config, SQLHookOperationQueryRow, *query, *args, dbConnection.tx != nil)
 	if dbConnection.tx != nil {
-		row = dbConnection.tx.QueryRowContext(ctx, *query, *args...)
+		row = dbConnection.tx.QueryRowContext(hookCtx, *query, *args...)
 	} else {
-		row = dbConnection.db.QueryRowContext(ctx, *query, *args...)
+		row = dbConnection.db.QueryRowContext(hookCtx, *query, *args...)
+	}
+	err = row.Scan(dest...)
+	if hookEvent != nil {
+		afterSQLHooks(hookEvent, err)
 	}
 	if slowSQLMillis > 0 {
 		slow := time.Since(*start).Milliseconds()
 		if slow-int64(slowSQLMillis) >= 0 {
-			FuncPrintSQL(ctx, *query, *args, slow)
+			FuncPrintSQL(ctx, *query, RedactSQLValues(ctx, *query, *args), slow)
+			dbConnection.slowSQLExplain(ctx, *query, *args, slow)
 		}
 	}
-	return row, nil
+	return err
 }
 
 // queryContext 查询数据,如果已经开启事务,就以事务方式执行,如果没有开启事务,就以非事务方式执行
 // queryRowContext Execute sql  row statement,If the transaction has been opened,it will be executed in transaction mode, if the transaction is not opened,it will be executed in non-transactional mode
 func (dbConnection *dataBaseConnection) queryContext(ctx context.Context, sqlstr *string, argsValues *[]interface{}) (*sql.Rows, error) {
+	// IValueConver 转换参数的值
+	argsValues, err := wrapValueConverArgs(ctx, argsValues)
+	if err != nil {
+		return nil, err
+	}
+	// TimePolicy 转换时间参数的时区和精度
+	argsValues = wrapTimePolicyArgs(dbConnection.config.TimePolicy, argsValues)
 	// reBuildSQL 重新处理参数代入方式
 	query, args, err := reBuildSQL(ctx, dbConnection.config, sqlstr, argsValues)
 	if err != nil {
@@ -313,30 +348,42 @@ func (dbConnection *dataBaseConnection) queryContext(ctx context.Context, sqlstr
 	if err != nil {
 		return nil, err
 	}
+	// 执行前加入 sql 注释
+	err = wrapSQLComment(ctx, dbConnection.config, query)
+	if err != nil {
+		return nil, err
+	}
 	var start *time.Time
 	var rows *sql.Rows
 	// 小于0是禁用日志输出;等于0是只输出日志,不计算SQ执行时间;大于0是计算执行时间,并且大于指定值
 	slowSQLMillis := dbConnection.config.SlowSQLMillis
 	if slowSQLMillis == 0 {
-		FuncPrintSQL(ctx, *query, *args, 0)
+		FuncPrintSQL(ctx, *query, RedactSQLValues(ctx, *query, *args), 0)
 	} else if slowSQLMillis > 0 {
 		now := time.Now() // 获取当前时间
 		start = &now
 	}
 
+	// 执行前调用钩子
+	// Call hooks before execution
+	hookCtx, hookEvent := beforeSQLHooks(ctx, dbConnection.config, SQLHookOperationQuery, *query, *args, dbConnection.tx != nil)
 	if dbConnection.tx != nil {
-		rows, err = dbConnection.tx.QueryContext(ctx, *query, *args...)
+		rows, err = dbConnection.tx.QueryContext(hookCtx, *query, *args...)
 	} else {
-		rows, err = dbConnection.db.QueryContext(ctx, *query, *args...)
+		rows, err = dbConnection.db.QueryContext(hookCtx, *query, *args...)
+	}
+	if hookEvent != nil {
+		afterSQLHooks(hookEvent, err)
 	}
 	if slowSQLMillis > 0 {
 		slow := time.Since(*start).Milliseconds()
 		if slow-int64(slowSQLMillis) >= 0 {
-			FuncPrintSQL(ctx, *query, *args, slow)
+			FuncPrintSQL(ctx, *query, RedactSQLValues(ctx, *query, *args), slow)
+			dbConnection.slowSQLExplain(ctx, *query, *args, slow)
 		}
 	}
 	if err != nil {
-		err = fmt.Errorf("->queryContext执行错误:%w,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", err, *query, sqlErrorValues2String(*args))
+		err = fmt.Errorf("->queryContext执行错误:%w,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", err, *query, sqlErrorValues2String(ctx, *query, *args))
 	}
 	return rows, err
 }
@@ -25,8 +25,9 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"net/url"
 	"reflect"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 )
@@ -36,7 +37,7 @@ import (
 var wrapPageSQL = func(ctx context.Context, config *DataSourceConfig, finder *Finder, page *Page) (string, error) {
 	// 获取到没有page的sql的语句
 	// Get the SQL statement without page.
-	sqlstr, err := finder.GetSQL()
+	sqlstr, err := finder.getSQL(config)
 	if err != nil {
 		return "", err
 	}
@@ -44,48 +45,24 @@ var wrapPageSQL = func(ctx context.Context, config *DataSourceConfig, finder *Fi
 	if page.PageNo < 1 { // 默认第一页
 		page.PageNo = 1
 	}
-	var sqlbuilder strings.Builder
-	sqlbuilder.Grow(stringBuilderGrowLen)
-	sqlbuilder.WriteString(sqlstr)
-	switch config.Dialect {
-	case "mysql", "sqlite", "dm", "gbase", "clickhouse", "tdengine", "db2": // MySQL,sqlite3,dm,南通,clickhouse,TDengine,db2 7.2+
-		sqlbuilder.WriteString(" LIMIT ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize * (page.PageNo - 1)))
-		sqlbuilder.WriteByte(',')
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize))
-
-	case "postgresql", "kingbase", "shentong": // postgresql,kingbase,神通数据库
-		sqlbuilder.WriteString(" LIMIT ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize))
-		sqlbuilder.WriteString(" OFFSET ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize * (page.PageNo - 1)))
-	case "mssql": // sqlserver 2012+
-		sqlPart := finder.sqlPartCache
-		if sqlPart.OrderBy.Start == sqlPart.OrderBy.End { // 如果没有 order by,增加默认的排序
-			sqlbuilder.WriteString(" ORDER BY (SELECT NULL) ")
-		}
-		sqlbuilder.WriteString(" OFFSET ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize * (page.PageNo - 1)))
-		sqlbuilder.WriteString(" ROWS FETCH NEXT ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize))
-		sqlbuilder.WriteString(" ROWS ONLY ")
-	case "oracle": // oracle 12c+
-		sqlPart := finder.sqlPartCache
-		if sqlPart.OrderBy.Start == sqlPart.OrderBy.End { // 如果没有 order by,增加默认的排序
-			sqlbuilder.WriteString(" ORDER BY NULL ")
-		}
-		sqlbuilder.WriteString(" OFFSET ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize * (page.PageNo - 1)))
-		sqlbuilder.WriteString(" ROWS FETCH NEXT ")
-		sqlbuilder.WriteString(strconv.Itoa(page.PageSize))
-		sqlbuilder.WriteString(" ROWS ONLY ")
-	default:
+	dialect := getDialect(config)
+	if dialect == nil {
 		return "", errors.New("->wrapPageSQL-->不支持的数据库类型:" + config.Dialect)
-
 	}
-	sqlstr = sqlbuilder.String()
-	// return reBuildSQL(dialect, sqlstr)
-	return sqlstr, nil
+	sqlPart := finder.sqlPartCache
+	// WITH 子句保留在最前面,只分页主语句.sqlserver等数据库的子查询中不能使用 WITH
+	// The WITH clause stays in front, only the main statement is paged. WITH cannot be used in subqueries of databases like sqlserver
+	withSQL := sqlstr[:sqlPart.Select.Start]
+	sqlstr = sqlstr[sqlPart.Select.Start:]
+	orderByIndex := -1
+	if sqlPart.OrderBy.Start != sqlPart.OrderBy.End {
+		orderByIndex = sqlPart.OrderBy.Start - sqlPart.Select.Start
+	}
+	pageSQL, err := dialect.WrapPageSQL(sqlstr, page.PageSize*(page.PageNo-1), page.PageSize, orderByIndex)
+	if err != nil || withSQL == "" {
+		return pageSQL, err
+	}
+	return withSQL + pageSQL, nil
 }
 
 // wrapInsertSQL  包装保存Struct语句.返回语句,是否自增,错误信息
@@ -120,7 +97,7 @@ var wrapInsertSQL = func(ctx context.Context, entityCache *entityStructCache, co
 			entityCache.columns = append(entityCache.columns[:i], entityCache.columns[i+1:]...)
 			// 构造SQL语句
 			if !config.InsertSQLNoColumn {
-				insertSQLBuilder.WriteString(column.columnTag)
+				insertSQLBuilder.WriteString(wrapQuoteIdentifier(config, column.columnTag))
 			}
 			valueSQLBuilder.WriteString(entityCache.pkSequence)
 			i = i - 1
@@ -141,7 +118,7 @@ var wrapInsertSQL = func(ctx context.Context, entityCache *entityStructCache, co
 		}
 		// 构造SQL语句
 		if !config.InsertSQLNoColumn {
-			insertSQLBuilder.WriteString(column.columnTag)
+			insertSQLBuilder.WriteString(wrapQuoteIdentifier(config, column.columnTag))
 		}
 		valueSQLBuilder.WriteByte('?')
 	}
@@ -181,7 +158,7 @@ var wrapInsertEntityMapSliceSQL = func(ctx context.Context, config *DataSourceCo
 	}
 	dbFieldMapKey := entity.GetDBFieldMapKey()
 	// SQL语句
-	inserColumnName, valuesql, values, _, err := wrapInsertValueEntityMapSQL(entity)
+	inserColumnName, valuesql, values, _, err := wrapInsertValueEntityMapSQL(config, entity)
 	if err != nil {
 		return &sqlstr, values, err
 	}
@@ -193,7 +170,7 @@ var wrapInsertEntityMapSliceSQL = func(ctx context.Context, config *DataSourceCo
 	// sqlBuilder.Grow(len(entity.GetTableName()) + len(inserColumnName) + len(valuesql) + 19)
 	sqlBuilder.Grow(stringBuilderGrowLen)
 	sqlBuilder.WriteString("INSERT INTO ")
-	sqlBuilder.WriteString(tableName)
+	sqlBuilder.WriteString(wrapTableName(ctx, config, tableName))
 	// sqlstr = sqlstr + insertsql + " VALUES" + valuesql
 	if !config.InsertSQLNoColumn {
 		sqlBuilder.WriteString(*inserColumnName)
@@ -208,7 +185,7 @@ var wrapInsertEntityMapSliceSQL = func(ctx context.Context, config *DataSourceCo
 		if config.Dialect == "tdengine" && tableName != newTaableName { // 如果是tdengine,拼接类似 INSERT INTO table1 values('1','2'),('3','4')  table2 values('5','6'),目前要求字段和类型必须一致,如果不一致,改动略多
 			tableName = newTaableName
 			sqlBuilder.WriteByte(' ')
-			sqlBuilder.WriteString(tableName)
+			sqlBuilder.WriteString(wrapTableName(ctx, config, tableName))
 			sqlBuilder.WriteString(" VALUES")
 		} else { // 标准语法 类似 INSERT INTO table1(id,name) values('2','3'), values('4','5')
 			sqlBuilder.WriteByte(',')
@@ -218,7 +195,10 @@ var wrapInsertEntityMapSliceSQL = func(ctx context.Context, config *DataSourceCo
 		entityMap := entityMapSlice[i]
 		for j := 0; j < len(dbFieldMapKey); j++ {
 			key := dbFieldMapKey[j]
-			value := entityMap.GetDBFieldMap()[key]
+			value, err := wrapEntityMapValue(entityMap.GetDBFieldMap()[key])
+			if err != nil {
+				return &sqlstr, values, err
+			}
 			*values = append(*values, value)
 		}
 	}
@@ -229,15 +209,15 @@ var wrapInsertEntityMapSliceSQL = func(ctx context.Context, config *DataSourceCo
 
 // wrapDeleteSQL 包装删除Struct语句
 // wrapDeleteSQL Package delete Struct statement
-var wrapDeleteSQL = func(ctx context.Context, entity IEntityStruct) (string, error) {
+var wrapDeleteSQL = func(ctx context.Context, config *DataSourceConfig, entity IEntityStruct) (string, error) {
 	// SQL语句的构造器
 	// SQL statement constructor
 	var sqlBuilder strings.Builder
 	sqlBuilder.Grow(stringBuilderGrowLen)
 	sqlBuilder.WriteString("DELETE FROM ")
-	sqlBuilder.WriteString(entity.GetTableName())
+	sqlBuilder.WriteString(wrapQuoteIdentifier(config, entity.GetTableName()))
 	sqlBuilder.WriteString(" WHERE ")
-	sqlBuilder.WriteString(entity.GetPKColumnName())
+	sqlBuilder.WriteString(wrapQuoteIdentifier(config, entity.GetPKColumnName()))
 	sqlBuilder.WriteString("=?")
 	sqlstr := sqlBuilder.String()
 
@@ -249,7 +229,7 @@ var wrapDeleteSQL = func(ctx context.Context, entity IEntityStruct) (string, err
 // it cannot complete the type judgment and assignment of Id. It is necessary to ensure that the value of Map is complete
 var wrapInsertEntityMapSQL = func(ctx context.Context, config *DataSourceConfig, entity IEntityMap) (string, *[]interface{}, bool, error) {
 	sqlstr := ""
-	inserColumnName, valuesql, values, autoIncrement, err := wrapInsertValueEntityMapSQL(entity)
+	inserColumnName, valuesql, values, autoIncrement, err := wrapInsertValueEntityMapSQL(config, entity)
 	if err != nil {
 		return sqlstr, nil, autoIncrement, err
 	}
@@ -260,7 +240,7 @@ var wrapInsertEntityMapSQL = func(ctx context.Context, config *DataSourceConfig,
 	// sqlBuilder.Grow(len(inserColumnName) + len(entity.GetTableName()) + len(valuesql) + 19)
 	sqlBuilder.Grow(stringBuilderGrowLen)
 	sqlBuilder.WriteString("INSERT INTO ")
-	sqlBuilder.WriteString(entity.GetTableName())
+	sqlBuilder.WriteString(wrapTableName(ctx, config, entity.GetTableName()))
 	if !config.InsertSQLNoColumn {
 		sqlBuilder.WriteString(*inserColumnName)
 	}
@@ -274,7 +254,7 @@ var wrapInsertEntityMapSQL = func(ctx context.Context, config *DataSourceConfig,
 // wrapInsertValueEntityMapSQL 包装保存Map语句,Map因为没有字段属性,无法完成Id的类型判断和赋值,需要确保Map的值是完整的
 // wrapInsertValueEntityMapSQL Pack and save the Map statement. Because Map does not have field attributes,
 // it cannot complete the type judgment and assignment of Id. It is necessary to ensure that the value of Map is complete
-func wrapInsertValueEntityMapSQL(entity IEntityMap) (*string, *string, *[]interface{}, bool, error) {
+func wrapInsertValueEntityMapSQL(config *DataSourceConfig, entity IEntityMap) (*string, *string, *[]interface{}, bool, error) {
 	var inserColumnName, valuesql string
 	// 是否自增,默认false
 	autoIncrement := false
@@ -307,7 +287,7 @@ func wrapInsertValueEntityMapSQL(entity IEntityMap) (*string, *string, *[]interf
 	if entity.GetPKColumnName() != "" && !hasPK { // 如果有主键字段,却没值,认为是自增或者序列 | If the primary key is not set, it is considered to be auto-increment or sequence
 		autoIncrement = true
 		if entity.GetEntityMapPkSequence() != "" { // 如果是序列 | If it is a sequence.
-			sqlBuilder.WriteString(entity.GetPKColumnName())
+			sqlBuilder.WriteString(wrapQuoteIdentifier(config, entity.GetPKColumnName()))
 			valueSQLBuilder.WriteString(entity.GetEntityMapPkSequence())
 			if len(dbFieldMap) > 1 { // 如果不只有序列
 				sqlBuilder.WriteByte(',')
@@ -324,10 +304,15 @@ func wrapInsertValueEntityMapSQL(entity IEntityMap) (*string, *string, *[]interf
 			valueSQLBuilder.WriteByte(',')
 		}
 		k := dbFieldMapKey[dbFieldMapIndex]
-		v := dbFieldMap[k]
+		// map,slice和struct的值序列化为JSON
+		// Values of map, slice and struct are serialized to JSON
+		v, err := wrapEntityMapValue(dbFieldMap[k])
+		if err != nil {
+			return &inserColumnName, &valuesql, nil, autoIncrement, err
+		}
 		// 拼接字符串
 		// Concatenated string
-		sqlBuilder.WriteString(k)
+		sqlBuilder.WriteString(wrapQuoteIdentifier(config, k))
 		valueSQLBuilder.WriteByte('?')
 		values = append(values, v)
 	}
@@ -343,7 +328,7 @@ func wrapInsertValueEntityMapSQL(entity IEntityMap) (*string, *string, *[]interf
 // wrapUpdateEntityMapSQL 包装Map更新语句,Map因为没有字段属性,无法完成Id的类型判断和赋值,需要确保Map的值是完整的
 // wrapUpdateEntityMapSQL Wrap the Map update statement. Because Map does not have field attributes,
 // it cannot complete the type judgment and assignment of Id. It is necessary to ensure that the value of Map is complete
-var wrapUpdateEntityMapSQL = func(ctx context.Context, entity IEntityMap) (*string, *[]interface{}, error) {
+var wrapUpdateEntityMapSQL = func(ctx context.Context, config *DataSourceConfig, entity IEntityMap) (*string, *[]interface{}, error) {
 	dbFieldMap := entity.GetDBFieldMap()
 	sqlstr := ""
 	dbFieldLen := len(dbFieldMap)
@@ -355,7 +340,7 @@ var wrapUpdateEntityMapSQL = func(ctx context.Context, entity IEntityMap) (*stri
 	var sqlBuilder strings.Builder
 	sqlBuilder.Grow(stringBuilderGrowLen)
 	sqlBuilder.WriteString("UPDATE ")
-	sqlBuilder.WriteString(entity.GetTableName())
+	sqlBuilder.WriteString(wrapTableName(ctx, config, entity.GetTableName()))
 	sqlBuilder.WriteString(" SET ")
 
 	// 优化: 预分配容量,减少扩容开销
@@ -376,9 +361,15 @@ var wrapUpdateEntityMapSQL = func(ctx context.Context, entity IEntityMap) (*stri
 		if dbFieldMapIndex > 0 {
 			sqlBuilder.WriteByte(',')
 		}
+		// map,slice和struct的值序列化为JSON
+		// Values of map, slice and struct are serialized to JSON
+		v, err := wrapEntityMapValue(v)
+		if err != nil {
+			return &sqlstr, nil, err
+		}
 
 		// 拼接字符串 | Splicing string.
-		sqlBuilder.WriteString(k)
+		sqlBuilder.WriteString(wrapQuoteIdentifier(config, k))
 		sqlBuilder.WriteString("=?")
 		values = append(values, v)
 		dbFieldMapIndex++
@@ -388,7 +379,7 @@ var wrapUpdateEntityMapSQL = func(ctx context.Context, entity IEntityMap) (*stri
 	values = append(values, pkValue)
 
 	sqlBuilder.WriteString(" WHERE ")
-	sqlBuilder.WriteString(entity.GetPKColumnName())
+	sqlBuilder.WriteString(wrapQuoteIdentifier(config, entity.GetPKColumnName()))
 	sqlBuilder.WriteString("=?")
 	sqlstr = sqlBuilder.String()
 
@@ -401,7 +392,7 @@ func wrapQuerySQL(ctx context.Context, config *DataSourceConfig, finder *Finder,
 	if page == nil {
 		// 获取到没有page的sql的语句
 		// Get the SQL statement without page.
-		sqlstr, err := finder.GetSQL()
+		sqlstr, err := finder.getSQL(config)
 		return sqlstr, err
 	}
 	sqlstr, err := wrapPageSQL(ctx, config, finder, page)
@@ -560,6 +551,82 @@ func wrapSQLHint(ctx context.Context, sqlstr *string) error {
 	return nil
 }
 
+// wrapSQLComment 在sql语句末尾增加sqlcommenter格式的注释,键值来自BindContextSQLComment和DataSourceConfig.FuncSQLComment
+// wrapSQLComment appends a sqlcommenter style comment to the end of the sql, the key-values come from BindContextSQLComment and DataSourceConfig.FuncSQLComment
+func wrapSQLComment(ctx context.Context, config *DataSourceConfig, sqlstr *string) error {
+	if dialect := getDialect(config); dialect == nil || !dialect.SupportSQLComment() {
+		return nil
+	}
+	ctxComments, _ := ctx.Value(contextSQLCommentValueKey).(map[string]string)
+	if len(ctxComments) < 1 && config.FuncSQLComment == nil {
+		return nil
+	}
+	comments := make(map[string]string)
+	if config.FuncSQLComment != nil {
+		for k, v := range config.FuncSQLComment(ctx) {
+			comments[k] = v
+		}
+	}
+	for k, v := range ctxComments {
+		comments[k] = v
+	}
+	comment := buildSQLComment(comments)
+	if comment == "" {
+		return nil
+	}
+
+	// 去掉末尾的空白和分号,注释添加在分号之前
+	// Trim trailing spaces and semicolon, the comment is added before the semicolon
+	trimSQL := strings.TrimRight(*sqlstr, " \t\r\n")
+	semicolon := strings.HasSuffix(trimSQL, ";")
+	if semicolon {
+		trimSQL = strings.TrimRight(trimSQL[:len(trimSQL)-1], " \t\r\n")
+	}
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(len(trimSQL) + len(comment) + 2)
+	sqlBuilder.WriteString(trimSQL)
+	sqlBuilder.WriteByte(' ')
+	sqlBuilder.WriteString(comment)
+	if semicolon {
+		sqlBuilder.WriteByte(';')
+	}
+	*sqlstr = sqlBuilder.String()
+	return nil
+}
+
+// buildSQLComment 构建sqlcommenter格式的注释,key按字典序排列,key和value使用URL编码,value使用单引号包裹
+// 编码后不会出现 ' 和 */ ,注释内容不会破坏SQL语句
+// buildSQLComment builds a sqlcommenter style comment, keys are sorted, keys and values are URL encoded, values are wrapped in single quotes
+// After encoding there is no ' or */ , the comment can not break the SQL statement
+func buildSQLComment(comments map[string]string) string {
+	if len(comments) < 1 {
+		return ""
+	}
+	keys := make([]string, 0, len(comments))
+	for k := range comments {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) < 1 {
+		return ""
+	}
+	sort.Strings(keys)
+	var commentBuilder strings.Builder
+	commentBuilder.WriteString("/*")
+	for i, k := range keys {
+		if i > 0 {
+			commentBuilder.WriteByte(',')
+		}
+		commentBuilder.WriteString(url.PathEscape(k))
+		commentBuilder.WriteString("='")
+		commentBuilder.WriteString(url.PathEscape(comments[k]))
+		commentBuilder.WriteByte('\'')
+	}
+	commentBuilder.WriteString("*/")
+	return commentBuilder.String()
+}
+
 // reBuildSQL 包装基础的SQL语句,根据数据库类型,调整SQL变量符号,例如?,? $1,$2这样的
 // reBuildSQL Pack basic SQL statements, adjust the SQL variable symbols according to the database type, such as?,? $1,$2
 var reBuildSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *string, args *[]interface{}) (*string, *[]interface{}, error) {
@@ -573,6 +640,9 @@ var reBuildSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *str
 	newValues := make([]interface{}, 0, argsNum)
 	// 记录sql参数值的下标,例如 $1 @p1 ,从1开始
 	sqlParamIndex := 1
+	// 数据库方言,处理占位符
+	// Database dialect, handles placeholders
+	dialect := getDialect(config)
 
 	// 新的sql
 	// new sql
@@ -589,7 +659,7 @@ var reBuildSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *str
 		}
 		i = i + 1
 		if i >= argsNum { // 占位符数量比参数值多,不使用 strings.Count函数,避免多次操作字符串
-			return nil, nil, fmt.Errorf("sql语句中参数和值数量不一致,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", *sqlstr, sqlErrorValues2String(*args))
+			return nil, nil, fmt.Errorf("sql语句中参数和值数量不一致,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", *sqlstr, sqlErrorValues2String(ctx, *sqlstr, *args))
 		}
 		v := (*args)[i]
 		var valueOf reflect.Value
@@ -662,18 +732,9 @@ var reBuildSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *str
 
 		}
 
-		switch config.Dialect {
-		case "mysql", "sqlite", "dm", "gbase", "clickhouse", "db2":
-			wrapParamSQL("?", valueLen, &sqlParamIndex, &newSQLStr, &valueOf, &newValues, false, false)
-		case "postgresql", "kingbase": // postgresql,kingbase
-			wrapParamSQL("$", valueLen, &sqlParamIndex, &newSQLStr, &valueOf, &newValues, true, false)
-		case "mssql": // mssql
-			wrapParamSQL("@p", valueLen, &sqlParamIndex, &newSQLStr, &valueOf, &newValues, true, false)
-		case "oracle", "shentong": // oracle,神通
-			wrapParamSQL(":", valueLen, &sqlParamIndex, &newSQLStr, &valueOf, &newValues, true, false)
-		case "tdengine": // tdengine,重新处理 字符类型的参数 '?'
-			wrapParamSQL("?", valueLen, &sqlParamIndex, &newSQLStr, &valueOf, &newValues, false, true)
-		default: // 其他情况,还是使用 ? | In other cases, or use  ?
+		if dialect != nil {
+			wrapParamSQL(dialect, valueLen, &sqlParamIndex, &newSQLStr, &valueOf, &newValues)
+		} else { // 其他情况,还是使用 ? | In other cases, or use  ?
 			newSQLStr.WriteByte('?')
 		}
 
@@ -681,7 +742,7 @@ var reBuildSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *str
 
 	// ?号占位符的数量和参数不一致,不使用 strings.Count函数,避免多次操作字符串
 	if (i + 1) != argsNum {
-		return nil, nil, fmt.Errorf("sql语句中参数和值数量不一致,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", *sqlstr, sqlErrorValues2String(*args))
+		return nil, nil, fmt.Errorf("sql语句中参数和值数量不一致,-->zormErrorExecSQL:%s,-->zormErrorSQLValues:%s", *sqlstr, sqlErrorValues2String(ctx, *sqlstr, *args))
 	}
 	sqlstring := newSQLStr.String()
 	return &sqlstring, &newValues, nil
@@ -689,92 +750,132 @@ var reBuildSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *str
 
 // reUpdateFinderSQL 根据数据类型更新 手动编写的 UpdateFinder的语句,用于处理数据库兼容,例如 clickhouse的 UPDATE 和 DELETE
 var reBuildUpdateSQL = func(ctx context.Context, config *DataSourceConfig, sqlstr *string) error {
-	if config.Dialect != "clickhouse" { // 目前只处理clickhouse
+	dialect := getDialect(config)
+	if dialect == nil {
 		return nil
 	}
-	// 处理clickhouse的特殊更新语法
-	sqlByte := []byte(*sqlstr)
-	// 获取第一个单词
-	firstWord, start, end, err := firstOneWord(0, sqlByte)
-	if err != nil {
-		return err
+	return dialect.ReBuildUpdateSQL(sqlstr)
+}
+
+// wrapAutoIncrementInsertSQL 包装自增的自增主键的插入sql
+var wrapAutoIncrementInsertSQL = func(ctx context.Context, config *DataSourceConfig, pkColumnName string, sqlstr *string, values *[]interface{}) (*int64, *int64) {
+	dialect := getDialect(config)
+	if dialect == nil {
+		return nil, nil
 	}
-	if start == -1 || end == -1 { // 未取到字符串
-		return nil
+	return dialect.WrapAutoIncrementInsertSQL(wrapQuoteIdentifier(config, pkColumnName), sqlstr, values)
+}
+
+// wrapAutoIncrementInsertSliceSQL 包装批量插入的自增主键sql,返回每行主键的获取方式
+var wrapAutoIncrementInsertSliceSQL = func(ctx context.Context, config *DataSourceConfig, pkColumnName string, sqlstr *string) GeneratedKeys {
+	dialect := getDialect(config)
+	if dialect == nil {
+		return GeneratedKeysNone
 	}
-	// SQL语句的构造器
-	// SQL statement constructor
-	var sqlBuilder strings.Builder
-	sqlBuilder.Grow(stringBuilderGrowLen)
-	sqlBuilder.WriteString((*sqlstr)[:start])
-	sqlBuilder.WriteString("ALTER TABLE ")
-	firstWord = strings.ToUpper(firstWord)
-	tableName := ""
-	switch firstWord {
-	case "UPDATE": // 更新  update tableName set
-		tableName, _, end, err = firstOneWord(end, sqlByte)
-		if err != nil {
-			return err
+	generatedKeys := dialect.WrapAutoIncrementInsertSliceSQL(wrapQuoteIdentifier(config, pkColumnName), sqlstr)
+	// 没有确认自增主键是连续的,不赋值
+	// The auto-increment keys are not confirmed to be contiguous, they are not assigned
+	if generatedKeys == GeneratedKeysLastInsertID && !config.ContiguousAutoIncrement {
+		return GeneratedKeysNone
+	}
+	return generatedKeys
+}
+
+// batchRowCount 批量操作每条语句的最大行数,rowParams是每行的参数数量,返回0不限制
+var batchRowCount = func(config *DataSourceConfig, rowParams int) int {
+	maxParams := config.BatchMaxParams
+	if maxParams == 0 {
+		if dialect := getDialect(config); dialect != nil {
+			maxParams = dialect.MaxParams()
 		}
-		// 拿到 set
-		_, start, end, err = firstOneWord(end, sqlByte)
+	}
+	batchRows := config.BatchMaxRows
+	if batchRows < 0 {
+		batchRows = 0
+	}
+	if maxParams > 0 && rowParams > 0 {
+		paramRows := maxParams / rowParams
+		if paramRows < 1 {
+			paramRows = 1
+		}
+		if batchRows == 0 || paramRows < batchRows {
+			batchRows = paramRows
+		}
+	}
+	return batchRows
+}
 
-	case "DELETE": // 删除 delete from tableName
-		// 拿到from
-		_, _, end, err = firstOneWord(end, sqlByte)
-		if err != nil {
-			return err
+// wrapQuoteIdentifier 开启DataSourceConfig.QuoteIdentifier时,使用方言的IDialect.QuoteIdentifier包裹表名或者列名.
+// 已经包裹的不再处理,带 . 的名称分别包裹每一部分,例如 schema.table 转为 "schema"."table" ,config为nil时不处理
+var wrapQuoteIdentifier = func(config *DataSourceConfig, name string) string {
+	if config == nil || !config.QuoteIdentifier || name == "" {
+		return name
+	}
+	dialect := getDialect(config)
+	if dialect == nil {
+		return name
+	}
+	if strings.IndexByte(name, '.') < 0 {
+		return quoteIdentifierPart(dialect, name)
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifierPart(dialect, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// contextTableName 根据ctx中BindContextTableName的映射和BindContextSchema的schema获取实际的表名,没有绑定时返回tableName.
+// 映射的实际表名有schema时保留,不使用BindContextSchema的schema
+func contextTableName(ctx context.Context, tableName string) string {
+	if tableNames, ok := ctx.Value(contextTableNameValueKey).(map[string]string); ok {
+		if physicalTableName, has := tableNames[tableName]; has {
+			if strings.IndexByte(physicalTableName, '.') >= 0 {
+				return physicalTableName
+			}
+			tableName = physicalTableName
 		}
-		// 拿到 tableName
-		tableName, start, end, err = firstOneWord(end, sqlByte)
-	default: // 只处理UPDATE 和 DELETE 语法
-		return nil
 	}
-	if err != nil {
-		return err
+	schema, ok := ctx.Value(contextSchemaValueKey).(string)
+	if !ok || schema == "" {
+		return tableName
 	}
-	if start == -1 || end == -1 { // 获取的位置异常
-		return errors.New("->reBuildUpdateSQL中clickhouse语法异常,请检查sql语句是否标准,-->zormErrorExecSQL:" + *sqlstr)
+	// 替换已有的schema
+	// Replace the existing schema
+	if index := strings.LastIndexByte(tableName, '.'); index >= 0 {
+		tableName = tableName[index+1:]
 	}
-	sqlBuilder.WriteString(tableName)
-	sqlBuilder.WriteByte(' ')
-	sqlBuilder.WriteString(firstWord)
-	// sqlBuilder.WriteByte(' ')
-	sqlBuilder.WriteString((*sqlstr)[end:])
-	*sqlstr = sqlBuilder.String()
-	return nil
+	return schema + "." + tableName
 }
 
-// wrapAutoIncrementInsertSQL 包装自增的自增主键的插入sql
-var wrapAutoIncrementInsertSQL = func(ctx context.Context, config *DataSourceConfig, pkColumnName string, sqlstr *string, values *[]interface{}) (*int64, *int64) {
-	// oracle 12c+ 支持IDENTITY属性的自增列,因为分页也要求12c+的语法,所以数据库就IDENTITY创建自增吧
-	// 处理序列产生的自增主键,例如oracle,postgresql等
-	var lastInsertID, zormSQLOutReturningID *int64
-	var sqlBuilder strings.Builder
-	// sqlBuilder.Grow(len(*sqlstr) + len(pkColumnName) + 40)
-	sqlBuilder.Grow(stringBuilderGrowLen)
-	sqlBuilder.WriteString(*sqlstr)
-	switch config.Dialect {
-	case "postgresql", "kingbase":
-		var p int64 = 0
-		lastInsertID = &p
-		// sqlstr = sqlstr + " RETURNING " + pkColumnName
-		sqlBuilder.WriteString(" RETURNING ")
-		sqlBuilder.WriteString(pkColumnName)
-	case "oracle", "shentong":
-		var p int64 = 0
-		zormSQLOutReturningID = &p
-		// 不要使用命名参数,统一使用占位符
-		// sqlstr = sqlstr + " RETURNING " + pkColumnName + " INTO :zormSQLOutReturningID "
-		sqlBuilder.WriteString(" RETURNING ")
-		sqlBuilder.WriteString(pkColumnName)
-		sqlBuilder.WriteString(" INTO ? ")
-		v := sql.Out{Dest: zormSQLOutReturningID}
-		*values = append(*values, v)
+// wrapTableName 生成语句使用的表名,处理ctx中的表名映射和QuoteIdentifier
+var wrapTableName = func(ctx context.Context, config *DataSourceConfig, tableName string) string {
+	return wrapQuoteIdentifier(config, contextTableName(ctx, tableName))
+}
+
+// wrapCacheTableName entityStructCache缓存的insertSQL和deleteSQL使用的是GetTableName()的表名,ctx中有表名映射时替换为实际的表名,不修改缓存.
+// prefix是表名前的语句,例如 INSERT INTO ,语句不是prefix+表名开头时(例如重写了wrapDeleteSQL)不处理
+func wrapCacheTableName(ctx context.Context, config *DataSourceConfig, sqlstr string, prefix string, tableName string) string {
+	physicalTableName := contextTableName(ctx, tableName)
+	if physicalTableName == tableName {
+		return sqlstr
+	}
+	oldPrefix := prefix + wrapQuoteIdentifier(config, tableName)
+	if !strings.HasPrefix(sqlstr, oldPrefix) {
+		return sqlstr
 	}
+	return prefix + wrapQuoteIdentifier(config, physicalTableName) + sqlstr[len(oldPrefix):]
+}
 
-	*sqlstr = sqlBuilder.String()
-	return lastInsertID, zormSQLOutReturningID
+// quoteIdentifierPart 包裹名称的一部分,已经使用反引号,双引号或者中括号包裹的不再处理
+func quoteIdentifierPart(dialect IDialect, name string) string {
+	if len(name) > 1 {
+		first, last := name[0], name[len(name)-1]
+		if (first == '`' && last == '`') || (first == '"' && last == '"') || (first == '[' && last == ']') {
+			return name
+		}
+	}
+	return dialect.QuoteIdentifier(name)
 }
 
 // getConfigFromConnection 从dbConnection中获取数据库config,如果没有,从FuncReadWriteStrategy获取dbDao,获取dbdao.config
@@ -795,34 +896,27 @@ func getConfigFromConnection(ctx context.Context, dbConnection *dataBaseConnecti
 }
 
 // wrapParamSQL 包装SQL语句
-// symbols(占位符) valueLen(参数长度) sqlParamIndexPtr(参数的下标指针,数组会改变值) newSQLStr(SQL字符串Builder) valueOf(参数值的反射对象) hasParamIndex(是否拼接参数下标 $1 $2) isTDengine(TDengine数据库需要单独处理字符串类型)
-func wrapParamSQL(symbols string, valueLen int, sqlParamIndexPtr *int, newSQLStr *strings.Builder, valueOf *reflect.Value, newValues *[]interface{}, hasParamIndex bool, isTDengine bool) {
+// dialect(数据库方言,生成占位符) valueLen(参数长度) sqlParamIndexPtr(参数的下标指针,数组会改变值) newSQLStr(SQL字符串Builder) valueOf(参数值的反射对象)
+func wrapParamSQL(dialect IDialect, valueLen int, sqlParamIndexPtr *int, newSQLStr *strings.Builder, valueOf *reflect.Value, newValues *[]interface{}) {
 	sqlParamIndex := *sqlParamIndexPtr
 	if valueLen == 1 {
-		if isTDengine && valueOf.Kind() == reflect.String { // 处理tdengine的字符串类型
-			symbols = "'?'"
-		}
-		newSQLStr.WriteString(symbols)
-
-		if hasParamIndex {
-			newSQLStr.WriteString(strconv.Itoa(sqlParamIndex))
-		}
-
+		newSQLStr.WriteString(dialect.Placeholder(sqlParamIndex, *valueOf))
 	} else if valueLen > 1 { // 如果值是数组
+		placeholder := ""
+		sticky := false
+		stickyDialect, hasSticky := dialect.(IStickyPlaceholder)
 		for j := 0; j < valueLen; j++ {
 			valuej := (*valueOf).Index(j)
-			if isTDengine && valuej.Kind() == reflect.String { // 处理tdengine的字符串类型
-				symbols = "'?'"
-			}
-			if j == 0 { // 第一个
-				newSQLStr.WriteString(symbols)
-			} else {
+			if j > 0 {
 				newSQLStr.WriteByte(',')
-				newSQLStr.WriteString(symbols)
 			}
-			if hasParamIndex {
-				newSQLStr.WriteString(strconv.Itoa(sqlParamIndex + j))
+			// 方言的占位符是sticky时,数组后面的元素都使用这个占位符,例如tdengine字符类型的 '?'
+			// When the placeholder of the dialect is sticky, the following elements of the array all use it, e.g. the '?' of tdengine string kind
+			if !sticky {
+				placeholder = dialect.Placeholder(sqlParamIndex+j, valuej)
+				sticky = hasSticky && stickyDialect.StickyPlaceholder(placeholder)
 			}
+			newSQLStr.WriteString(placeholder)
 			sliceValue := valuej.Interface()
 			*newValues = append(*newValues, sliceValue)
 		}
@@ -132,7 +132,7 @@ func Test_wrapUpdateEntityMapSQL_stableColumnOrder(t *testing.T) {
 	expectedOrder := []string{"status", "name", "age", "email"}
 
 	for i := 0; i < 10; i++ {
-		sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, entity)
+		sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, nil, entity)
 		if err != nil {
 			t.Fatalf("wrapUpdateEntityMapSQL error: %v", err)
 		}
@@ -176,7 +176,7 @@ func Test_wrapUpdateEntityMapSQL_stableColumnOrder(t *testing.T) {
 	}
 
 	// Step 3: Verify generated SQL has correct format
-	sqlstr, _, _ := wrapUpdateEntityMapSQL(ctx, entity)
+	sqlstr, _, _ := wrapUpdateEntityMapSQL(ctx, nil, entity)
 	expectedSQL := "UPDATE t_user SET status=?,name=?,age=?,email=? WHERE id=?"
 	if *sqlstr != expectedSQL {
 		t.Errorf("SQL mismatch.\nExpected: %s\nGot:      %s", expectedSQL, *sqlstr)
@@ -196,7 +196,7 @@ func Test_wrapUpdateEntityMapSQL_stableColumnOrder_multiple(t *testing.T) {
 		entity.Set("banana", 4)
 		entity.Set("id", 999)
 
-		sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, entity)
+		sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, nil, entity)
 		if err != nil {
 			t.Fatalf("wrapUpdateEntityMapSQL error: %v", err)
 		}
@@ -550,7 +550,7 @@ func Test_wrapUpdateEntityMapSQL_emptyDBFieldMap(t *testing.T) {
 	entity := NewEntityMap("t_test")
 	entity.PkColumnName = "id"
 
-	_, _, err := wrapUpdateEntityMapSQL(ctx, entity)
+	_, _, err := wrapUpdateEntityMapSQL(ctx, nil, entity)
 	if err == nil {
 		t.Error("expected error for empty dbFieldMap, got nil")
 	}
@@ -565,7 +565,7 @@ func Test_wrapUpdateEntityMapSQL_PKSetLast(t *testing.T) {
 	entity.Set("amount", 99.9)
 	entity.Set("order_id", "ORD001")
 
-	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, entity)
+	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, nil, entity)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
@@ -589,7 +589,7 @@ func Test_wrapUpdateEntityMapSQL_PKOnly(t *testing.T) {
 	entity.PkColumnName = "id"
 	entity.Set("id", 1)
 
-	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, entity)
+	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, nil, entity)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
@@ -614,7 +614,7 @@ func Test_wrapUpdateEntityMapSQL_PKFirstInSet(t *testing.T) {
 	entity.Set("name", "alice")
 	entity.Set("age", 30)
 
-	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, entity)
+	sqlstr, values, err := wrapUpdateEntityMapSQL(ctx, nil, entity)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
@@ -988,3 +988,113 @@ func Test_FuncGenerateStringID_ConcurrentNoDuplicate(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func Test_wrapSQLComment(t *testing.T) {
+	ctx, _ := BindContextSQLComment(context.Background(), "route", "/orders")
+	ctx, _ = BindContextSQLComment(ctx, "app", "it's */ evil")
+	funcSQLComment := func(ctx context.Context) map[string]string {
+		return map[string]string{"app": "order", "traceparent": "00-abc-01"}
+	}
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		config *DataSourceConfig
+		sqlstr string
+		want   string
+	}{
+		{
+			name:   "no comment",
+			ctx:    context.Background(),
+			config: &DataSourceConfig{Dialect: "mysql"},
+			sqlstr: "SELECT 1",
+			want:   "SELECT 1",
+		},
+		{
+			name:   "ctx and func, ctx takes precedence",
+			ctx:    ctx,
+			config: &DataSourceConfig{Dialect: "mysql", FuncSQLComment: funcSQLComment},
+			sqlstr: "SELECT * FROM t WHERE id=? ; ",
+			want:   "SELECT * FROM t WHERE id=? /*app='it%27s%20%2A%2F%20evil',route='%2Forders',traceparent='00-abc-01'*/;",
+		},
+		{
+			name:   "func only",
+			ctx:    context.Background(),
+			config: &DataSourceConfig{Dialect: "postgresql", FuncSQLComment: funcSQLComment},
+			sqlstr: "UPDATE t SET a=$1",
+			want:   "UPDATE t SET a=$1 /*app='order',traceparent='00-abc-01'*/",
+		},
+		{
+			name:   "tdengine disabled",
+			ctx:    ctx,
+			config: &DataSourceConfig{Dialect: "tdengine", FuncSQLComment: funcSQLComment},
+			sqlstr: "SELECT 1",
+			want:   "SELECT 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlstr := tt.sqlstr
+			if err := wrapSQLComment(tt.ctx, tt.config, &sqlstr); err != nil {
+				t.Fatal(err)
+			}
+			if sqlstr != tt.want {
+				t.Errorf("wrapSQLComment() = %q, want %q", sqlstr, tt.want)
+			}
+		})
+	}
+}
+
+func Test_batchRowCount(t *testing.T) {
+	tests := []struct {
+		config    DataSourceConfig
+		rowParams int
+		want      int
+	}{
+		{config: DataSourceConfig{Dialect: "mssql"}, rowParams: 3, want: 700},
+		{config: DataSourceConfig{Dialect: "mssql", BatchMaxRows: 100}, rowParams: 3, want: 100},
+		{config: DataSourceConfig{Dialect: "mssql", BatchMaxParams: -1}, rowParams: 3, want: 0},
+		{config: DataSourceConfig{Dialect: "sqlite", DialectVersion: "3.31.1"}, rowParams: 10, want: 99},
+		{config: DataSourceConfig{Dialect: "mysql", BatchMaxParams: 10}, rowParams: 20, want: 1},
+		{config: DataSourceConfig{Dialect: "db2"}, rowParams: 3, want: 0},
+	}
+	for _, tt := range tests {
+		if got := batchRowCount(&tt.config, tt.rowParams); got != tt.want {
+			t.Errorf("batchRowCount(%+v, %d) = %d, want %d", tt.config, tt.rowParams, got, tt.want)
+		}
+	}
+}
+
+// Test_OverrideFunc_withoutConfig 没有config参数的旧函数仍然可以重写wrapDeleteSQL和wrapUpdateEntityMapSQL,类型不匹配时返回错误
+// Test_OverrideFunc_withoutConfig the old functions without the config parameter can still override wrapDeleteSQL and wrapUpdateEntityMapSQL, a type mismatch returns an error
+func Test_OverrideFunc_withoutConfig(t *testing.T) {
+	defaultDeleteSQL, defaultUpdateEntityMapSQL := wrapDeleteSQL, wrapUpdateEntityMapSQL
+	defer func() {
+		wrapDeleteSQL, wrapUpdateEntityMapSQL = defaultDeleteSQL, defaultUpdateEntityMapSQL
+	}()
+	ctx := context.Background()
+
+	ok, oldFunc, err := OverrideFunc("wrapDeleteSQL", func(ctx context.Context, entity IEntityStruct) (string, error) {
+		return "DELETE FROM t_old", nil
+	})
+	if !ok || err != nil || oldFunc == nil {
+		t.Fatalf("OverrideFunc(wrapDeleteSQL) = %v, %v", ok, err)
+	}
+	if sqlstr, _ := wrapDeleteSQL(ctx, &DataSourceConfig{Dialect: "mysql"}, nil); sqlstr != "DELETE FROM t_old" {
+		t.Errorf("wrapDeleteSQL() = %q", sqlstr)
+	}
+
+	oldSQL := "UPDATE t_old SET a=?"
+	ok, _, err = OverrideFunc("wrapUpdateEntityMapSQL", func(ctx context.Context, entity IEntityMap) (*string, *[]interface{}, error) {
+		return &oldSQL, nil, nil
+	})
+	if !ok || err != nil {
+		t.Fatalf("OverrideFunc(wrapUpdateEntityMapSQL) = %v, %v", ok, err)
+	}
+	if sqlstr, _, _ := wrapUpdateEntityMapSQL(ctx, nil, nil); sqlstr != &oldSQL {
+		t.Errorf("wrapUpdateEntityMapSQL() = %q", *sqlstr)
+	}
+
+	if ok, _, err = OverrideFunc("wrapDeleteSQL", func(entity IEntityStruct) string { return "" }); ok || err == nil {
+		t.Errorf("OverrideFunc(wrapDeleteSQL) with a wrong type = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,270 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EntityStructToMap 把struct实体类转换为EntityMap,key是数据库列名,按照字段的顺序Set,表名,主键和序列使用实体类的.
+// 指针字段取指向的值,nil是NULL;数组字段使用Array包装;RegisterFieldValueConver注册的字段使用ToDriverValue转换.返回的EntityMap可以直接用于InsertEntityMap和UpdateEntityMap
+// EntityStructToMap converts a struct entity to EntityMap, keys are the database column names, Set in the order of the fields, the table name, primary key and sequence of the entity are used.
+// Pointer fields take the pointed value, nil is NULL; array fields are wrapped with Array; fields registered by RegisterFieldValueConver are converted with ToDriverValue. The returned EntityMap can be used directly with InsertEntityMap and UpdateEntityMap
+func EntityStructToMap(ctx context.Context, entity IEntityStruct) (*EntityMap, error) {
+	if entity == nil {
+		return nil, errors.New("->EntityStructToMap-->entity不能为nil")
+	}
+	config, err := getEntityMapConvertConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entityCache, err := getEntityStructCache(ctx, entity, config)
+	if err != nil {
+		return nil, fmt.Errorf("->EntityStructToMap-->getEntityStructCache获取实体类缓存错误:%w", err)
+	}
+	entityMap := NewEntityMap(entity.GetTableName())
+	entityMap.PkColumnName = entity.GetPKColumnName()
+	entityMap.PkSequence = entity.GetPkSequence()
+	valueOf := reflect.ValueOf(entity).Elem()
+	for _, column := range entityCache.columns {
+		fieldValue := valueOf.FieldByIndex(column.fieldIndex)
+		var value interface{}
+		switch {
+		case column.isPtr && fieldValue.IsNil():
+		case registeredFieldValueConver(entityCache.structType, column) != nil:
+			converted, err := fieldColumnValue(ctx, entityCache, column, fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("->EntityStructToMap-->字段%s转换错误:%w", column.fieldName, err)
+			}
+			value = converted.(convertedValue).value
+		case column.isArray:
+			value = NewArray(fieldValue.Interface())
+		case column.isPtr:
+			value = fieldValue.Elem().Interface()
+		default:
+			value = fieldValue.Interface()
+		}
+		entityMap.Set(column.columnName, value)
+	}
+	return entityMap, nil
+}
+
+// EntityMapToStruct 把EntityMap的值赋值给struct实体类,key按照列名(忽略大小写)匹配字段,没有对应字段的key忽略,nil赋值为字段的零值.
+// 值的类型和字段不同时转换类型:IValueConver注册的字段使用FromDriverValue,JSON字段反序列化,数组字段解析数组的文本,sql.Scanner使用Scan,
+// time.Time使用DataSourceConfig.TimePolicy解析字符串,数字,字符串和bool之间相互转换
+// EntityMapToStruct assigns the values of EntityMap to a struct entity, keys match fields by column name (case insensitive), keys without a field are ignored, nil is assigned as the zero value of the field.
+// Types are converted when the value type differs from the field: fields registered with IValueConver use FromDriverValue, JSON fields are deserialized, array fields parse the array text, sql.Scanner uses Scan,
+// time.Time parses strings with DataSourceConfig.TimePolicy, numbers, strings and bool are converted to each other
+func EntityMapToStruct(ctx context.Context, entityMap IEntityMap, entity IEntityStruct) error {
+	if entityMap == nil || entity == nil {
+		return errors.New("->EntityMapToStruct-->entityMap和entity不能为nil")
+	}
+	config, err := getEntityMapConvertConfig(ctx)
+	if err != nil {
+		return err
+	}
+	entityCache, err := getEntityStructCache(ctx, entity, config)
+	if err != nil {
+		return fmt.Errorf("->EntityMapToStruct-->getEntityStructCache获取实体类缓存错误:%w", err)
+	}
+	valueOf := reflect.ValueOf(entity).Elem()
+	dbFieldMap := entityMap.GetDBFieldMap()
+	for _, key := range entityMap.GetDBFieldMapKey() {
+		column, ok := entityCache.columnMap[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+		if err = setEntityMapFieldValue(ctx, config, entityCache, column, valueOf.FieldByIndex(column.fieldIndex), dbFieldMap[key]); err != nil {
+			return fmt.Errorf("->EntityMapToStruct-->列%s赋值给字段%s错误:%w", key, column.fieldName, err)
+		}
+	}
+	return nil
+}
+
+// getEntityMapConvertConfig 获取ctx中数据库连接的配置,没有连接时使用默认的dbDao,用于列名的方言和TimePolicy
+// getEntityMapConvertConfig gets the config of the database connection in ctx, the default dbDao is used without a connection, used for the dialect of column names and TimePolicy
+func getEntityMapConvertConfig(ctx context.Context) (*DataSourceConfig, error) {
+	if ctx == nil {
+		return nil, errors.New("->getEntityMapConvertConfig-->context不能为nil")
+	}
+	dbConnection, err := getDBConnectionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getConfigFromConnection(ctx, dbConnection, 0)
+}
+
+// setEntityMapFieldValue 把EntityMap的值转换为字段的类型并赋值,指针字段创建指向的值
+// setEntityMapFieldValue converts the EntityMap value to the type of the field and assigns it, the pointed value is created for pointer fields
+func setEntityMapFieldValue(ctx context.Context, config *DataSourceConfig, entityCache *entityStructCache, column *fieldColumnCache, fieldValue reflect.Value, value interface{}) error {
+	if value == nil {
+		fieldValue.Set(reflect.Zero(fieldValue.Type()))
+		return nil
+	}
+	valueOf := reflect.ValueOf(value)
+	if valueOf.Type().AssignableTo(fieldValue.Type()) {
+		fieldValue.Set(valueOf)
+		return nil
+	}
+	if valueConver := fieldValueConver(entityCache.structType, column); valueConver != nil {
+		converted, err := valueConver.FromDriverValue(ctx, value)
+		if err != nil {
+			return fmt.Errorf("->setEntityMapFieldValue-->FromDriverValue错误:%w", err)
+		}
+		return setConverValue(fieldValue, converted)
+	}
+	// 指针字段转换指向的值,成功后再赋值
+	// Pointer fields convert the pointed value, assigned after success
+	target := fieldValue
+	if column.isPtr {
+		target = reflect.New(fieldValue.Type().Elem()).Elem()
+	}
+	if err := convertEntityMapValue(config, column, target, value); err != nil {
+		return err
+	}
+	if column.isPtr {
+		fieldValue.Set(target.Addr())
+	}
+	return nil
+}
+
+// convertEntityMapValue 按照字段的类型转换EntityMap的值,target是可以赋值的非指针字段
+// convertEntityMapValue converts the EntityMap value by the type of the field, target is an assignable non-pointer field
+func convertEntityMapValue(config *DataSourceConfig, column *fieldColumnCache, target reflect.Value, value interface{}) error {
+	switch {
+	case column.isJSON:
+		data, err := jsonBytes(value)
+		if err != nil { // 已经反序列化的值,例如QueryMap的JSON列 | Already deserialized values, e.g. JSON columns of QueryMap
+			if data, err = json.Marshal(value); err != nil {
+				return fmt.Errorf("->convertEntityMapValue-->json.Marshal错误:%w", err)
+			}
+		}
+		return (&jsonScanner{dest: target.Addr().Interface()}).Scan(data)
+	case column.isArray:
+		if valuer, ok := value.(driver.Valuer); ok { // EntityStructToMap的Array | Array of EntityStructToMap
+			driverValue, err := valuer.Value()
+			if err != nil {
+				return err
+			}
+			value = driverValue
+		}
+		return (&Array{V: target.Addr().Interface()}).Scan(value)
+	}
+	if scanner, ok := target.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	if target.Type() == timeType {
+		policy := config.TimePolicy
+		if policy == nil {
+			policy = &TimePolicy{}
+		}
+		t, err := policy.parseTime(config.Dialect, value)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(t))
+		return nil
+	}
+	converted, err := typeConvertValue(value, target.Type())
+	if err != nil {
+		return err
+	}
+	target.Set(converted)
+	return nil
+}
+
+// QueryRowEntityMap 根据Finder查询一行数据,封装为EntityMap,使用tableName和pkColumnName(为空时是id),可以修改后直接调用UpdateEntityMap.
+// key是数据库列名,按照查询列的顺序Set,值和QueryRowMap一致.没有数据时返回nil
+// QueryRowEntityMap queries one row by Finder and wraps it as EntityMap with tableName and pkColumnName (id when empty), it can be modified and passed directly to UpdateEntityMap.
+// Keys are the database column names, Set in the order of the queried columns, values are the same as QueryRowMap. nil is returned when there is no row
+func QueryRowEntityMap(ctx context.Context, finder *Finder, tableName string, pkColumnName string) (*EntityMap, error) {
+	ctx, err := bindEntityMapOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0)
+	resultMap, err := QueryRowMap(context.WithValue(ctx, contextQueryMapColumnsValueKey, &columns), finder)
+	if resultMap == nil {
+		return nil, err
+	}
+	return newQueryEntityMap(tableName, pkColumnName, columns, resultMap), err
+}
+
+// QueryEntityMap 根据Finder查询,封装为EntityMap数组,使用tableName和pkColumnName(为空时是id),可以修改后直接调用UpdateEntityMap.
+// key是数据库列名,按照查询列的顺序Set,值和QueryMap一致.如果想不分页,查询所有数据,page传入nil
+// QueryEntityMap queries by Finder and wraps the rows as an EntityMap array with tableName and pkColumnName (id when empty), they can be modified and passed directly to UpdateEntityMap.
+// Keys are the database column names, Set in the order of the queried columns, values are the same as QueryMap. Pass nil page to query all rows without paging
+func QueryEntityMap(ctx context.Context, finder *Finder, tableName string, pkColumnName string, page *Page) ([]*EntityMap, error) {
+	ctx, err := bindEntityMapOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resultMaps, columns, err := QueryMapColumns(ctx, finder, page)
+	if err != nil {
+		return nil, err
+	}
+	entityMaps := make([]*EntityMap, 0, len(resultMaps))
+	for _, resultMap := range resultMaps {
+		entityMaps = append(entityMaps, newQueryEntityMap(tableName, pkColumnName, columns, resultMap))
+	}
+	return entityMaps, nil
+}
+
+// bindEntityMapOptions 复制ctx中的QueryMap选项,key使用原样的列名
+// bindEntityMapOptions copies the QueryMap options of ctx, keys use the column names as is
+func bindEntityMapOptions(ctx context.Context) (context.Context, error) {
+	if ctx == nil {
+		return nil, errors.New("->bindEntityMapOptions-->context不能为nil")
+	}
+	options := QueryMapOptions{}
+	if contextOptions := getContextQueryMapOptions(ctx); contextOptions != nil {
+		options = *contextOptions
+	}
+	options.KeyCase = QueryMapKeyCaseAsIs
+	return BindContextQueryMapOptions(ctx, &options)
+}
+
+// newQueryEntityMap 把QueryMap的一行数据按照列的顺序封装为EntityMap.
+// 主键列不区分大小写,使用查询返回的列名,例如oracle的 ID ,可以直接调用UpdateEntityMap
+// newQueryEntityMap wraps one row of QueryMap as EntityMap in the order of the columns.
+// The primary key column is case insensitive and the column name returned by the query is used, e.g. ID of oracle, so UpdateEntityMap can be called directly
+func newQueryEntityMap(tableName string, pkColumnName string, columns []string, resultMap map[string]interface{}) *EntityMap {
+	entityMap := NewEntityMap(tableName)
+	if pkColumnName != "" {
+		entityMap.PkColumnName = pkColumnName
+	}
+	for _, column := range columns {
+		value, ok := resultMap[column]
+		if !ok {
+			continue
+		}
+		if pkColumnName != "" && column != pkColumnName && strings.EqualFold(column, pkColumnName) {
+			entityMap.PkColumnName = column
+		}
+		entityMap.Set(column, value)
+	}
+	return entityMap
+}
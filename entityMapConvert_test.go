@@ -0,0 +1,212 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testEntityMapEntity EntityMap和struct相互转换的实体类
+// testEntityMapEntity entity converted between EntityMap and struct
+type testEntityMapEntity struct {
+	EntityStruct
+	ID        string                 `column:"id"`
+	Name      *string                `column:"user_name"`
+	Age       int8                   `column:"age"`
+	Score     *float64               `column:"score"`
+	Tags      []string               `column:"tags" zorm:"array"`
+	Attrs     map[string]interface{} `column:"attrs" zorm:"json"`
+	CreatedAt time.Time              `column:"created_at"`
+}
+
+func (entity *testEntityMapEntity) GetTableName() string { return "t_form" }
+
+func Test_EntityStructToMap(t *testing.T) {
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "mysql"})
+	name := "a"
+	entity := &testEntityMapEntity{ID: "1", Name: &name, Age: 2, Tags: []string{"x"}, Attrs: map[string]interface{}{"level": 1}}
+	entityMap, err := EntityStructToMap(ctx, entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entityMap.GetTableName() != "t_form" || entityMap.GetPKColumnName() != "id" {
+		t.Errorf("EntityStructToMap table = %s, pk = %s", entityMap.GetTableName(), entityMap.GetPKColumnName())
+	}
+	wantKeys := []string{"id", "user_name", "age", "score", "tags", "attrs", "created_at"}
+	if !reflect.DeepEqual(entityMap.GetDBFieldMapKey(), wantKeys) {
+		t.Errorf("EntityStructToMap keys = %v, want %v", entityMap.GetDBFieldMapKey(), wantKeys)
+	}
+	values := entityMap.GetDBFieldMap()
+	if values["user_name"] != "a" || values["score"] != nil || values["age"] != int8(2) {
+		t.Errorf("EntityStructToMap values = %v", values)
+	}
+	if _, ok := values["tags"].(*Array); !ok {
+		t.Errorf("EntityStructToMap tags = %T, want *Array", values["tags"])
+	}
+
+	// 转换回struct | Converted back to struct
+	got := &testEntityMapEntity{}
+	if err = EntityMapToStruct(ctx, entityMap, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, &testEntityMapEntity{ID: "1", Name: &name, Age: 2, Tags: []string{"x"}, Attrs: map[string]interface{}{"level": 1}}) {
+		t.Errorf("EntityMapToStruct = %+v", got)
+	}
+
+	// 直接用于UpdateEntityMap | Used directly with UpdateEntityMap
+	if _, err = Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return UpdateEntityMap(ctx, entityMap)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"UPDATE t_form SET user_name=?,age=?,score=?,tags=?,attrs=?,created_at=? WHERE id=?"}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func Test_EntityMapToStruct(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeEntityMapTable, 1)
+	entityMap := NewEntityMap("t_form")
+	entityMap.Set("ID", int64(7))
+	entityMap.Set("age", []byte("12"))
+	entityMap.Set("score", "1.5")
+	entityMap.Set("tags", `{a,"b c"}`)
+	entityMap.Set("attrs", `{"level":1}`)
+	entityMap.Set("created_at", "2024-01-02 03:04:05")
+	entityMap.Set("user_name", nil)
+	entityMap.Set("unknown", 1)
+	name := "old"
+	entity := &testEntityMapEntity{Name: &name}
+	if err := EntityMapToStruct(ctx, entityMap, entity); err != nil {
+		t.Fatal(err)
+	}
+	score := 1.5
+	want := &testEntityMapEntity{ID: "7", Age: 12, Score: &score, Tags: []string{"a", "b c"}, Attrs: map[string]interface{}{"level": float64(1)}, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if !reflect.DeepEqual(entity, want) {
+		t.Errorf("EntityMapToStruct = %+v, want %+v", entity, want)
+	}
+
+	for _, value := range []interface{}{"abc", 300, 1.5} {
+		entityMap = NewEntityMap("t_form")
+		entityMap.Set("age", value)
+		if err := EntityMapToStruct(ctx, entityMap, entity); err == nil {
+			t.Errorf("EntityMapToStruct(age=%v) should fail", value)
+		}
+	}
+}
+
+// scanFakeEntityMapTable QueryEntityMap的结果集,偶数行的user_name和age是NULL
+// scanFakeEntityMapTable result set of QueryEntityMap, user_name and age of even rows are NULL
+var scanFakeEntityMapTable = &scanFakeTable{
+	name:        "entityMap",
+	columns:     []string{"id", "user_name", "age"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT"},
+	row:         scanFakeUserValues,
+}
+
+func Test_QueryEntityMap(t *testing.T) {
+	ctx, err := BindContextQueryMapOptions(newScanFakeContext(t, scanFakeEntityMapTable, 2), &QueryMapOptions{KeyCase: QueryMapKeyCaseCamel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entityMaps, err := QueryEntityMap(ctx, NewSelectFinder("t_form"), "t_form", "user_name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entityMaps) != 2 {
+		t.Fatalf("len(entityMaps) = %d", len(entityMaps))
+	}
+	for _, entityMap := range entityMaps {
+		if entityMap.GetTableName() != "t_form" || entityMap.GetPKColumnName() != "user_name" || !reflect.DeepEqual(entityMap.GetDBFieldMapKey(), scanFakeEntityMapTable.columns) {
+			t.Errorf("QueryEntityMap = %+v", entityMap)
+		}
+	}
+
+	ctx = newScanFakeContext(t, scanFakeEntityMapTable, 1)
+	entityMap, err := QueryRowEntityMap(ctx, NewSelectFinder("t_form", "age").Append("WHERE id=?", 1), "t_form", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entityMap.GetPKColumnName() != "id" || !reflect.DeepEqual(entityMap.GetDBFieldMapKey(), []string{"age"}) {
+		t.Errorf("QueryRowEntityMap = %+v", entityMap)
+	}
+	entity := &testEntityMapEntity{}
+	if err = EntityMapToStruct(ctx, entityMap, entity); err != nil || entity.Age != 1 {
+		t.Errorf("EntityMapToStruct = %+v, %v", entity, err)
+	}
+}
+
+// Test_newQueryEntityMap_pkCase 主键列使用查询返回的列名,例如oracle的大写列名,可以直接生成更新语句
+// Test_newQueryEntityMap_pkCase the primary key column uses the column name returned by the query, e.g. the upper case column names of oracle, so the update statement can be generated directly
+func Test_newQueryEntityMap_pkCase(t *testing.T) {
+	columns := []string{"ID", "NAME"}
+	entityMap := newQueryEntityMap("t_form", "id", columns, map[string]interface{}{"ID": int64(1), "NAME": "a"})
+	if entityMap.GetPKColumnName() != "ID" {
+		t.Errorf("GetPKColumnName() = %s, want ID", entityMap.GetPKColumnName())
+	}
+	sqlstr, values, err := wrapUpdateEntityMapSQL(context.Background(), nil, entityMap)
+	if err != nil || *sqlstr != "UPDATE t_form SET NAME=? WHERE ID=?" || !reflect.DeepEqual(*values, []interface{}{"a", int64(1)}) {
+		t.Errorf("wrapUpdateEntityMapSQL() = %v, %v, %v", sqlstr, values, err)
+	}
+}
+
+func Test_typeConvertValue(t *testing.T) {
+	type status int
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{"12", int64(12)},
+		{[]byte("12.0"), 12},
+		{int64(3), status(3)},
+		{uint8(3), int16(3)},
+		{"7", uint32(7)},
+		{int64(1), true},
+		{"false", false},
+		{int64(5), "5"},
+		{1.5, float32(1.5)},
+		{"2.5", 2.5},
+		{"ab", []byte("ab")},
+	}
+	for _, tt := range tests {
+		got, err := typeConvertValue(tt.value, reflect.TypeOf(tt.want))
+		if err != nil || !reflect.DeepEqual(got.Interface(), tt.want) {
+			t.Errorf("typeConvertValue(%v) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		value  interface{}
+		typeOf reflect.Type
+	}{
+		{"x", reflect.TypeOf(0)},
+		{1.5, reflect.TypeOf(0)},
+		{int64(-1), reflect.TypeOf(uint(0))},
+		{int64(128), reflect.TypeOf(int8(0))},
+		{"yes", reflect.TypeOf(true)},
+		{time.Time{}, reflect.TypeOf(0)},
+	} {
+		if _, err := typeConvertValue(tt.value, tt.typeOf); err == nil {
+			t.Errorf("typeConvertValue(%v, %s) should fail", tt.value, tt.typeOf)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// EntitySnapshot 匿名注入到struct实体类开启脏数据跟踪,Query和QueryRow查询的实体类记录列的原始值,UpdateChanged只更新修改过的列.
+// 实体类复制时共享快照,UpdateChanged成功后替换为新的快照,不影响复制的实体类
+// EntitySnapshot is injected anonymously into struct entities to enable dirty tracking, entities queried by Query and QueryRow remember the original column values, UpdateChanged only updates modified columns.
+// Copies of the entity share the snapshot, UpdateChanged replaces it with a new snapshot on success without affecting the copies
+type EntitySnapshot struct {
+	// snapshotValues 列的原始值,key是小写的列名,没有快照时是nil
+	// snapshotValues original values of the columns, keys are lower case column names, nil without a snapshot
+	snapshotValues map[string]interface{}
+}
+
+// entitySnapshot 返回快照,用于识别注入了EntitySnapshot的实体类
+// entitySnapshot returns the snapshot, used to identify entities injected with EntitySnapshot
+func (snapshot *EntitySnapshot) entitySnapshot() *EntitySnapshot {
+	return snapshot
+}
+
+// HasSnapshot 是否有快照,Query和QueryRow查询或者UpdateChanged成功之后有快照
+// HasSnapshot whether there is a snapshot, there is one after being queried by Query and QueryRow or after UpdateChanged succeeds
+func (snapshot *EntitySnapshot) HasSnapshot() bool {
+	return snapshot.snapshotValues != nil
+}
+
+// ClearSnapshot 清除快照,例如实体类复用于新的数据时
+// ClearSnapshot clears the snapshot, e.g. when the entity is reused for new data
+func (snapshot *EntitySnapshot) ClearSnapshot() {
+	snapshot.snapshotValues = nil
+}
+
+// iEntitySnapshot 注入了EntitySnapshot的实体类
+// iEntitySnapshot entities injected with EntitySnapshot
+type iEntitySnapshot interface {
+	entitySnapshot() *EntitySnapshot
+}
+
+// entitySnapshotType iEntitySnapshot的反射类型
+// entitySnapshotType reflection type of iEntitySnapshot
+var entitySnapshotType = reflect.TypeOf((*iEntitySnapshot)(nil)).Elem()
+
+// querySliceLen Query查询之前rowsSlicePtr的长度,Query只Append元素,快照只处理新增的元素.不是slice的指针时返回0
+// querySliceLen length of rowsSlicePtr before Query, Query only appends elements and snapshots only process the appended elements. 0 when it is not a pointer to a slice
+func querySliceLen(rowsSlicePtr interface{}) int {
+	valueOf := reflect.ValueOf(rowsSlicePtr)
+	if valueOf.Kind() != reflect.Ptr || valueOf.IsNil() || valueOf.Elem().Kind() != reflect.Slice {
+		return 0
+	}
+	return valueOf.Elem().Len()
+}
+
+// snapshotQueryRows 记录Query和QueryRow结果的快照,rowsPtr是*[]struct,*[]*struct或者*struct,slice只处理start之后Query新增的元素.没有注入EntitySnapshot时不处理
+// snapshotQueryRows records the snapshots of the results of Query and QueryRow, rowsPtr is *[]struct, *[]*struct or *struct, only the elements appended by Query after start are processed for slices. Nothing is done without EntitySnapshot injected
+func snapshotQueryRows(ctx context.Context, rowsPtr interface{}, start int) error {
+	valueOf := reflect.Indirect(reflect.ValueOf(rowsPtr))
+	typeOf := valueOf.Type()
+	if typeOf.Kind() == reflect.Slice {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf.Kind() != reflect.Struct || !reflect.PtrTo(typeOf).Implements(entitySnapshotType) {
+		return nil
+	}
+	dbConnection, err := getDBConnectionFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	config, err := getConfigFromConnection(ctx, dbConnection, 0)
+	if err != nil {
+		return err
+	}
+	entityCache, err := getStructTypeOfCache(ctx, &typeOf, config)
+	if err != nil {
+		return err
+	}
+	rows := []reflect.Value{valueOf}
+	if valueOf.Kind() == reflect.Slice {
+		rows = make([]reflect.Value, 0, valueOf.Len())
+		for i := start; i < valueOf.Len(); i++ {
+			rows = append(rows, reflect.Indirect(valueOf.Index(i)))
+		}
+	}
+	for _, row := range rows {
+		if !row.IsValid() {
+			continue
+		}
+		values, err := entitySnapshotValues(ctx, entityCache, row)
+		if err != nil {
+			return fmt.Errorf("->snapshotQueryRows-->记录快照错误:%w", err)
+		}
+		row.Addr().Interface().(iEntitySnapshot).entitySnapshot().snapshotValues = values
+	}
+	return nil
+}
+
+// entitySnapshotValues 实体类所有列保存的值,和Update使用的值一致,[]byte复制一份,避免原地修改后无法比较
+// entitySnapshotValues saved values of all columns of the entity, the same as the values used by Update, []byte is copied so in place modifications can still be compared
+func entitySnapshotValues(ctx context.Context, entityCache *entityStructCache, valueOf reflect.Value) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(entityCache.columns))
+	for _, column := range entityCache.columns {
+		value, err := fieldColumnValue(ctx, entityCache, column, valueOf.FieldByIndex(column.fieldIndex))
+		if err != nil {
+			return nil, err
+		}
+		if bytes, ok := value.([]byte); ok && bytes != nil {
+			value = append([]byte{}, bytes...)
+		}
+		values[column.columnNameLower] = value
+	}
+	return values, nil
+}
+
+// snapshotValueEqual 快照的值和当前值是否相等,time.Time使用Equal比较,忽略时区的差异
+// snapshotValueEqual whether the snapshot value equals the current value, time.Time is compared with Equal, ignoring differences of the time zone
+func snapshotValueEqual(snapshotValue interface{}, value interface{}) bool {
+	if t, ok := snapshotValue.(time.Time); ok {
+		current, isTime := value.(time.Time)
+		return isTime && t.Equal(current)
+	}
+	return reflect.DeepEqual(snapshotValue, value)
+}
+
+var errUpdateChangedSnapshot = errors.New("->UpdateChanged-->entity必须注入EntitySnapshot,并且是Query或者QueryRow查询的")
+
+// UpdateChanged 只更新和快照相比修改过的列,entity必须匿名注入EntitySnapshot,并且是Query或者QueryRow查询的.
+// 可以把列更新为零值,没有修改时不执行SQL,返回0.如果ctx绑定了BindContextOnlyUpdateCols,只更新其中修改过的列.更新成功后快照更新为当前的值
+// ctx不能为nil,参照使用zorm.Transaction方法传入ctx
+// UpdateChanged only updates the columns modified compared to the snapshot, entity must inject EntitySnapshot anonymously and be queried by Query or QueryRow.
+// Columns can be updated to zero values, no SQL is executed and 0 is returned when nothing changed. If ctx is bound with BindContextOnlyUpdateCols, only the modified columns among them are updated. The snapshot is updated to the current values after a successful update
+// ctx cannot be nil, refer to zorm.Transaction method to pass in ctx
+func UpdateChanged(ctx context.Context, entity IEntityStruct) (int, error) {
+	return updateChanged(ctx, entity)
+}
+
+var updateChanged = func(ctx context.Context, entity IEntityStruct) (int, error) {
+	snapshotEntity, ok := entity.(iEntitySnapshot)
+	if !ok || reflect.ValueOf(entity).IsNil() || snapshotEntity.entitySnapshot().snapshotValues == nil {
+		FuncLogError(ctx, errUpdateChangedSnapshot)
+		return 0, errUpdateChangedSnapshot
+	}
+	// 和Update使用相同的配置,保证实体类缓存一致
+	// The same config as Update, keeping the entity cache consistent
+	dbConnection, err := getDBConnectionFromContext(ctx)
+	if err != nil {
+		FuncLogError(ctx, err)
+		return 0, err
+	}
+	config, err := getConfigFromConnection(ctx, dbConnection, 1)
+	if err != nil {
+		FuncLogError(ctx, err)
+		return 0, err
+	}
+	entityCache, err := getEntityStructCache(ctx, entity, config)
+	if err != nil {
+		err = fmt.Errorf("->UpdateChanged-->getEntityStructCache获取实体类缓存错误:%w", err)
+		FuncLogError(ctx, err)
+		return 0, err
+	}
+	snapshot := snapshotEntity.entitySnapshot()
+	values, err := entitySnapshotValues(ctx, entityCache, reflect.ValueOf(entity).Elem())
+	if err != nil {
+		err = fmt.Errorf("->UpdateChanged-->获取字段的值错误:%w", err)
+		FuncLogError(ctx, err)
+		return 0, err
+	}
+	onlyUpdateColsMap, _ := contextUpdateColsMap(ctx, false)
+	changedCols := make([]string, 0)
+	for _, column := range entityCache.columns {
+		if column.isPK || (onlyUpdateColsMap != nil && !onlyUpdateColsMap[column.columnNameLower]) {
+			continue
+		}
+		if !snapshotValueEqual(snapshot.snapshotValues[column.columnNameLower], values[column.columnNameLower]) {
+			changedCols = append(changedCols, column.columnNameLower)
+		}
+	}
+	if len(changedCols) < 1 { // 没有修改的列 | No modified columns
+		return 0, nil
+	}
+	updateCtx, err := BindContextOnlyUpdateCols(ctx, changedCols)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := updateEntity(updateCtx, entity)
+	if err != nil {
+		return affected, err
+	}
+	// 使用新的map,不影响共享快照的复制的实体类
+	// Use a new map, not affecting copies of the entity sharing the snapshot
+	snapshot.snapshotValues = values
+	return affected, nil
+}
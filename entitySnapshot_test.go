@@ -0,0 +1,135 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testSnapshotEntity 开启脏数据跟踪的实体类
+// testSnapshotEntity entity with dirty tracking enabled
+type testSnapshotEntity struct {
+	EntityStruct
+	EntitySnapshot
+	ID        string    `column:"id"`
+	UserName  string    `column:"user_name"`
+	Age       *int      `column:"age"`
+	Extra     []byte    `column:"extra"`
+	CreatedAt time.Time `column:"created_at"`
+}
+
+func (entity *testSnapshotEntity) GetTableName() string { return "t_snapshot" }
+
+// scanFakeSnapshotTable 脏数据跟踪的结果集,偶数行的user_name和age是NULL
+// scanFakeSnapshotTable result set of dirty tracking, user_name and age of even rows are NULL
+var scanFakeSnapshotTable = &scanFakeTable{
+	name:        "snapshot",
+	columns:     []string{"id", "user_name", "age", "extra", "created_at"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT", "VARCHAR", "DATETIME"},
+	row: func(index int, values []driver.Value) {
+		scanFakeUserValues(index, values)
+		values[3] = []byte("extra")
+		values[4] = scanFakeCreatedAt
+	},
+}
+
+func Test_UpdateChanged(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeSnapshotTable, 1)
+	statements := recordScanFakeSQL(t)
+	entity := &testSnapshotEntity{}
+	if _, err := UpdateChanged(ctx, entity); !errors.Is(err, errUpdateChangedSnapshot) {
+		t.Errorf("UpdateChanged without snapshot = %v, want errUpdateChangedSnapshot", err)
+	}
+	if has, err := QueryRow(ctx, NewSelectFinder("t_snapshot"), entity); err != nil || !has || !entity.HasSnapshot() {
+		t.Fatalf("QueryRow = %v, %v, HasSnapshot = %v", has, err, entity.HasSnapshot())
+	}
+	updateChanged := func(ctx context.Context) int {
+		affected, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+			return UpdateChanged(ctx, entity)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return affected.(int)
+	}
+
+	// 没有修改,时区不同的相同时间不算修改 | Nothing changed, the same time in another time zone is not a change
+	entity.CreatedAt = entity.CreatedAt.In(time.FixedZone("UTC+8", 8*3600))
+	if affected := updateChanged(ctx); affected != 0 {
+		t.Errorf("UpdateChanged without changes = %d, want 0", affected)
+	}
+	// 更新为零值和原地修改[]byte | Updated to zero value and []byte modified in place
+	entity.UserName = ""
+	entity.Extra[0] = 'E'
+	updateChanged(ctx)
+	// 快照已经更新 | The snapshot has been updated
+	updateChanged(ctx)
+	// 只更新BindContextOnlyUpdateCols中修改过的列 | Only the modified columns in BindContextOnlyUpdateCols are updated
+	entity.Age = nil
+	entity.UserName = "b"
+	onlyCtx, err := BindContextOnlyUpdateCols(ctx, []string{"age", "extra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updateChanged(onlyCtx)
+
+	want := []string{
+		"SELECT * FROM t_snapshot",
+		"UPDATE t_snapshot SET user_name=?,extra=? WHERE id=?",
+		"UPDATE t_snapshot SET age=? WHERE id=?",
+	}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func Test_snapshotQueryRows(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeSnapshotTable, 2)
+	list := make([]*testSnapshotEntity, 0)
+	if err := Query(ctx, NewSelectFinder("t_snapshot"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i, entity := range list {
+		if !entity.HasSnapshot() || entity.snapshotValues["id"] != entity.ID {
+			t.Errorf("list[%d] snapshot = %v", i, entity.snapshotValues)
+		}
+	}
+	// 第2行的age是NULL | age of the 2nd row is NULL
+	if list[1].snapshotValues["age"] != nil {
+		t.Errorf("snapshot age = %v, want nil", list[1].snapshotValues["age"])
+	}
+	// 再次Query只记录新增元素的快照,已经修改的元素保留原来的快照
+	// Querying again only snapshots the appended elements, the modified elements keep the original snapshot
+	list[0].UserName = "changed"
+	if err := Query(ctx, NewSelectFinder("t_snapshot"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 4 || list[0].snapshotValues["user_name"] != "name" || !list[3].HasSnapshot() {
+		t.Errorf("snapshot of existing element = %v", list[0].snapshotValues)
+	}
+	list[0].ClearSnapshot()
+	if list[0].HasSnapshot() {
+		t.Error("ClearSnapshot did not clear the snapshot")
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// tagZormName zorm的tag标签名称,多个选项使用逗号分隔,例如 `column:"attrs" zorm:"json"`
+// tagZormName tag name of zorm, multiple options are separated by commas, e.g. `column:"attrs" zorm:"json"`
+const tagZormName = "zorm"
+
+// zormTagOptionJSON 字段的值序列化为JSON保存,查询时反序列化.适用于map,slice和struct字段,数据库列可以是JSON,JSONB或者文本类型
+// zormTagOptionJSON the field value is saved as JSON and deserialized when querying. For map, slice and struct fields, the column can be JSON, JSONB or text
+const zormTagOptionJSON = "json"
+
+// hasZormTagOption zorm tag是否包含option选项
+// hasZormTagOption whether the zorm tag contains the option
+func hasZormTagOption(tag reflect.StructTag, option string) bool {
+	zormTag := tag.Get(tagZormName)
+	for zormTag != "" {
+		var name string
+		if i := strings.IndexByte(zormTag, ','); i >= 0 {
+			name, zormTag = zormTag[:i], zormTag[i+1:]
+		} else {
+			name, zormTag = zormTag, ""
+		}
+		if strings.TrimSpace(name) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// zormTagOptionValue zorm tag中 option=value 选项的值,例如 `zorm:"prefix=customer_"` ,没有这个选项时返回""
+// zormTagOptionValue value of the option=value option of the zorm tag, e.g. `zorm:"prefix=customer_"` , "" when the option does not exist
+func zormTagOptionValue(tag reflect.StructTag, option string) string {
+	for _, name := range strings.Split(tag.Get(tagZormName), ",") {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, option+"=") {
+			return name[len(option)+1:]
+		}
+	}
+	return ""
+}
+
+// jsonFieldValue 把JSON字段的值序列化为JSON字符串,nil的指针,map和slice保存为数据库的NULL.
+// 使用字符串参数,原生的JSON类型(mysql的JSON,postgresql和kingbase的JSON和JSONB)和文本类型都可以接收
+// jsonFieldValue serializes the value of a JSON field to a JSON string, nil pointers, maps and slices are saved as database NULL.
+// A string parameter is used, both native JSON types (JSON of mysql, JSON and JSONB of postgresql and kingbase) and text types accept it
+func jsonFieldValue(fieldValue reflect.Value) (interface{}, error) {
+	switch fieldValue.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if fieldValue.IsNil() {
+			return nil, nil
+		}
+	}
+	data, err := json.Marshal(fieldValue.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("->jsonFieldValue-->json.Marshal错误:%w", err)
+	}
+	return string(data), nil
+}
+
+// timeType time.Time的反射类型
+// timeType reflection type of time.Time
+var timeType = reflect.TypeOf(time.Time{})
+
+// wrapEntityMapValue EntityMap的值是map,slice(不包括[]byte)或者struct(不包括time.Time),并且没有实现driver.Valuer时序列化为JSON字符串.
+// 这些类型的值数据库驱动无法直接保存,slice还会被reBuildSQL展开为IN的多个参数
+// wrapEntityMapValue serializes the EntityMap value to a JSON string when it is a map, slice (except []byte) or struct (except time.Time) and does not implement driver.Valuer.
+// The database driver cannot save these values directly, and slices would be expanded by reBuildSQL into multiple IN parameters
+func wrapEntityMapValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return value, nil
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return value, nil
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.Map:
+	case reflect.Slice:
+		if valueOf.Type().Elem().Kind() == reflect.Uint8 {
+			return value, nil
+		}
+	case reflect.Struct:
+		if valueOf.Type() == timeType {
+			return value, nil
+		}
+	default:
+		return value, nil
+	}
+	return jsonFieldValue(valueOf)
+}
+
+// jsonScanner 反序列化JSON列的值到dest指针,用于 zorm:"json" 的字段
+// jsonScanner deserializes the value of a JSON column into the dest pointer, used for zorm:"json" fields
+type jsonScanner struct {
+	dest interface{}
+}
+
+// Scan 实现sql.Scanner接口,NULL值不处理
+// Scan implements the sql.Scanner interface, NULL values are not processed
+func (scanner *jsonScanner) Scan(src interface{}) error {
+	data, err := jsonBytes(src)
+	if err != nil || data == nil {
+		return err
+	}
+	if err = json.Unmarshal(data, scanner.dest); err != nil {
+		return fmt.Errorf("->jsonScanner.Scan-->json.Unmarshal错误:%w", err)
+	}
+	return nil
+}
+
+// jsonBytes 数据库驱动返回的JSON值,原生JSON类型一般是[]byte,文本类型可能是string
+// jsonBytes JSON value returned by the database driver, native JSON types are usually []byte, text types may be string
+func jsonBytes(src interface{}) ([]byte, error) {
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("->jsonBytes-->不支持的JSON值类型:%T", src)
+	}
+}
+
+// isJSONDatabaseType 是否是方言原生的JSON类型,mysql的JSON,postgresql和kingbase的JSON和JSONB.QueryMap会反序列化这些列
+// isJSONDatabaseType whether it is a native JSON type of the dialect, JSON of mysql, JSON and JSONB of postgresql and kingbase. QueryMap deserializes these columns
+func isJSONDatabaseType(dialect string, databaseTypeName string) bool {
+	switch dialect {
+	case "mysql":
+		return databaseTypeName == "JSON"
+	case "postgresql", "kingbase":
+		return databaseTypeName == "JSON" || databaseTypeName == "JSONB"
+	}
+	return false
+}
+
+// jsonMapValue 反序列化QueryMap中原生JSON列的值,对象是map[string]interface{},数组是[]interface{}
+// jsonMapValue deserializes the value of a native JSON column in QueryMap, objects are map[string]interface{}, arrays are []interface{}
+func jsonMapValue(src interface{}) (interface{}, error) {
+	data, err := jsonBytes(src)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("->jsonMapValue-->json.Unmarshal错误:%w", err)
+	}
+	return value, nil
+}
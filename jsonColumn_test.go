@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testJSONAttrs JSON列对应的struct
+// testJSONAttrs struct of the JSON column
+type testJSONAttrs struct {
+	Level int      `json:"level"`
+	Tags  []string `json:"tags"`
+}
+
+// testJSONEntity 使用 zorm:"json" tag的实体类
+// testJSONEntity entity using the zorm:"json" tag
+type testJSONEntity struct {
+	EntityStruct
+	ID      string                 `column:"id"`
+	Attrs   map[string]interface{} `column:"attrs" zorm:"json"`
+	Tags    []string               `column:"tags" zorm:"readonly, json"`
+	Profile *testJSONAttrs         `column:"profile" zorm:"json"`
+}
+
+func (entity *testJSONEntity) GetTableName() string {
+	return "t_json"
+}
+
+func Test_hasZormTagOption(t *testing.T) {
+	tests := []struct {
+		tag  reflect.StructTag
+		want bool
+	}{
+		{`zorm:"json"`, true},
+		{`zorm:"readonly, json"`, true},
+		{`zorm:"jsonb"`, false},
+		{`json:"attrs"`, false},
+	}
+	for _, tt := range tests {
+		if got := hasZormTagOption(tt.tag, zormTagOptionJSON); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func Test_JSONColumn_write(t *testing.T) {
+	ctx := context.Background()
+	config := &DataSourceConfig{Dialect: "mysql"}
+	entity := &testJSONEntity{ID: "1", Attrs: map[string]interface{}{"a": 1}, Tags: []string{"x"}}
+	entityCache, err := getEntityStructCache(ctx, entity, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]interface{}, 0)
+	if err = insertEntityFieldValues(ctx, entity, entityCache, true, &values); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"1", `{"a":1}`, `["x"]`, nil}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("insert values = %v, want %v", values, want)
+	}
+
+	entity.Profile = &testJSONAttrs{Level: 2}
+	sqlstr, updateValues, err := updateEntityFieldValues(ctx, config, entity, entityCache, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []interface{}{`{"a":1}`, `["x"]`, `{"level":2,"tags":null}`, "1"}
+	if *sqlstr != "UPDATE t_json SET attrs=?,tags=?,profile=? WHERE id=?" || !reflect.DeepEqual(*updateValues, want) {
+		t.Errorf("update = %s %v", *sqlstr, *updateValues)
+	}
+
+	// EntityMap的map,slice和struct值序列化为JSON
+	// Map, slice and struct values of EntityMap are serialized to JSON
+	now := time.Now()
+	for _, tt := range []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{map[string]int{"a": 1}, `{"a":1}`},
+		{[]int{1, 2}, `[1,2]`},
+		{testJSONAttrs{Level: 1}, `{"level":1,"tags":null}`},
+		{[]byte("raw"), []byte("raw")},
+		{now, now},
+		{"text", "text"},
+		{nil, nil},
+	} {
+		got, err := wrapEntityMapValue(tt.value)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapEntityMapValue(%v) = %v, %v", tt.value, got, err)
+		}
+	}
+	entityMap := NewEntityMap("t_json")
+	entityMap.Set("id", "1")
+	entityMap.Set("tags", []string{"x"})
+	_, _, mapValues, _, err := wrapInsertValueEntityMapSQL(config, entityMap)
+	if err != nil || !reflect.DeepEqual(*mapValues, []interface{}{"1", `["x"]`}) {
+		t.Errorf("entityMap values = %v, %v", *mapValues, err)
+	}
+}
+
+// scanFakeJSONTable JSON列的结果集,attrs是mysql的JSON列
+// scanFakeJSONTable result set of the JSON column, attrs is a JSON column of mysql
+var scanFakeJSONTable = &scanFakeTable{
+	name:        "json",
+	columns:     []string{"attrs"},
+	columnTypes: []string{"JSON"},
+	row: func(index int, values []driver.Value) {
+		values[0] = []byte(`{"level":1,"tags":["a"]}`)
+	},
+}
+
+func Test_JSONColumn_read(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeJSONTable, 2)
+	rowCtx := newScanFakeContext(t, scanFakeJSONTable, 1)
+	finder := NewSelectFinder("test_table")
+	type jsonRow struct {
+		Attrs testJSONAttrs `column:"attrs" zorm:"json"`
+	}
+	list := make([]jsonRow, 0)
+	if err := Query(ctx, finder, &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !reflect.DeepEqual(list[1].Attrs, testJSONAttrs{Level: 1, Tags: []string{"a"}}) {
+		t.Errorf("Query = %+v", list)
+	}
+
+	type jsonPtrRow struct {
+		Attrs *map[string]interface{} `column:"attrs" zorm:"json"`
+	}
+	row := jsonPtrRow{}
+	if _, err := QueryRow(rowCtx, finder, &row); err != nil {
+		t.Fatal(err)
+	}
+	if row.Attrs == nil || (*row.Attrs)["level"] != float64(1) {
+		t.Errorf("QueryRow = %+v", row)
+	}
+
+	// QueryMap默认返回驱动的值
+	// QueryMap returns the value of the driver by default
+	maps, err := QueryMap(rowCtx, finder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 1 || !reflect.DeepEqual(maps[0]["attrs"], []byte(`{"level":1,"tags":["a"]}`)) {
+		t.Errorf("QueryMap attrs = %#v", maps[0]["attrs"])
+	}
+
+	// DecodeJSON时QueryMap反序列化mysql的JSON列
+	// QueryMap deserializes the JSON column of mysql with DecodeJSON
+	ctx, err = BindContextQueryMapOptions(ctx, &QueryMapOptions{DecodeJSON: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	maps, err = QueryMap(ctx, finder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"level": float64(1), "tags": []interface{}{"a"}}
+	if len(maps) != 2 || !reflect.DeepEqual(maps[0]["attrs"], want) {
+		t.Errorf("QueryMap attrs = %#v", maps[0]["attrs"])
+	}
+}
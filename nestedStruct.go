@@ -0,0 +1,140 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// zormTagOptionPrefix 嵌套struct字段的列名前缀,例如 `zorm:"prefix=customer_"` ,查询的 customer_name 列赋值给嵌套struct的 name 列.
+// 没有前缀时可以使用 字段名.列名 的别名,例如 SELECT c.name AS "customer.name"
+// zormTagOptionPrefix column name prefix of a nested struct field, e.g. `zorm:"prefix=customer_"` , the queried customer_name column is assigned to the name column of the nested struct.
+// Without a prefix the alias fieldName.columnName can be used, e.g. SELECT c.name AS "customer.name"
+const zormTagOptionPrefix = "prefix"
+
+// nestedStructMaxDepth 嵌套struct的最大层数,避免 Parent *Node 这样的循环引用
+// nestedStructMaxDepth maximum depth of nested structs, avoids circular references like Parent *Node
+const nestedStructMaxDepth = 5
+
+// scannerType sql.Scanner的反射类型
+// scannerType reflection type of sql.Scanner
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// nestedFieldStep 查询列对应的嵌套struct字段的路径
+// nestedFieldStep path of the nested struct field of a queried column
+type nestedFieldStep struct {
+	// fieldIndex 嵌套struct字段在上一级struct中的索引
+	// fieldIndex index of the nested struct field in the parent struct
+	fieldIndex []int
+	// ptrType 字段是指针时指向的struct类型,不是指针时是nil
+	// ptrType the struct type pointed to when the field is a pointer, nil otherwise
+	ptrType reflect.Type
+}
+
+// nestedStructType 字段是否可以作为嵌套struct接收查询的列,返回struct类型.
+// time.Time,实现了sql.Scanner的类型,JSON字段和数据库列字段不是嵌套struct
+// nestedStructType whether the field can receive queried columns as a nested struct, returns the struct type.
+// time.Time, types implementing sql.Scanner, JSON fields and column fields are not nested structs
+func nestedStructType(field *fieldColumnCache) (reflect.Type, bool) {
+	if field.structField == nil || field.columnName != "" || field.isJSON {
+		return nil, false
+	}
+	typeOf := field.structField.Type
+	if typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf.Kind() != reflect.Struct || typeOf == timeType || reflect.PtrTo(typeOf).Implements(scannerType) {
+		return nil, false
+	}
+	return typeOf, true
+}
+
+// lookupColumnFieldCache 根据小写的列名查找字段缓存,依次使用列名,字段名和去掉下划线的字段名
+// lookupColumnFieldCache looks up the field cache by the lower case column name, using the column name, field name and field name without underscores in turn
+func lookupColumnFieldCache(entityCache *entityStructCache, columnName string) *fieldColumnCache {
+	if field, ok := entityCache.columnMap[columnName]; ok {
+		return field
+	}
+	if field, ok := entityCache.fieldMap[columnName]; ok { // 尝试用struct属性名查找
+		return field
+	}
+	if strings.Contains(columnName, "_") { // 尝试驼峰命名转换(去除下划线)
+		if field, ok := entityCache.fieldMap[strings.ReplaceAll(columnName, "_", "")]; ok {
+			return field
+		}
+	}
+	return nil
+}
+
+// findNestedFieldCache 查找列对应的嵌套struct字段,列名是 字段名.列名 或者 前缀+列名,支持多层嵌套.返回字段缓存,嵌套的路径和字段所在struct的缓存
+// findNestedFieldCache finds the nested struct field of the column, the column name is fieldName.columnName or prefix+columnName, multiple levels are supported. Returns the field cache, the nested path and the cache of the struct containing the field
+func findNestedFieldCache(ctx context.Context, config *DataSourceConfig, entityCache *entityStructCache, columnName string, depth int) (*fieldColumnCache, []nestedFieldStep, *entityStructCache, error) {
+	if depth >= nestedStructMaxDepth {
+		return nil, nil, nil, nil
+	}
+	for _, field := range entityCache.fields {
+		nestedType, ok := nestedStructType(field)
+		if !ok {
+			continue
+		}
+		var nestedColumnName string
+		fieldNameLower := strings.ToLower(field.fieldName)
+		if dot := strings.IndexByte(columnName, '.'); dot > 0 && strings.ReplaceAll(columnName[:dot], "_", "") == fieldNameLower {
+			nestedColumnName = columnName[dot+1:]
+		} else if prefix := strings.ToLower(zormTagOptionValue(field.structField.Tag, zormTagOptionPrefix)); prefix != "" && strings.HasPrefix(columnName, prefix) {
+			nestedColumnName = columnName[len(prefix):]
+		} else {
+			continue
+		}
+		nestedCache, err := getStructTypeOfCache(ctx, &nestedType, config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		step := nestedFieldStep{fieldIndex: field.fieldIndex}
+		if field.isPtr {
+			step.ptrType = nestedType
+		}
+		if nestedField := lookupColumnFieldCache(nestedCache, nestedColumnName); nestedField != nil {
+			return nestedField, []nestedFieldStep{step}, nestedCache, nil
+		}
+		nestedField, path, fieldCache, err := findNestedFieldCache(ctx, config, nestedCache, nestedColumnName, depth+1)
+		if err != nil || nestedField != nil {
+			return nestedField, append([]nestedFieldStep{step}, path...), fieldCache, err
+		}
+	}
+	return nil, nil, nil, nil
+}
+
+// selectFieldValue 查询列对应的字段反射值,嵌套struct的指针是nil时创建.NULL的列不会调用,只有出现非NULL值时才创建嵌套struct
+// selectFieldValue reflection value of the field of the queried column, nil pointers of nested structs are created. It is not called for NULL columns, nested structs are only created when a non NULL value appears
+func selectFieldValue(valueOfElem reflect.Value, fieldCache *fieldColumnCache) reflect.Value {
+	for _, step := range fieldCache.nestedPath {
+		valueOfElem = valueOfElem.FieldByIndex(step.fieldIndex)
+		if step.ptrType != nil {
+			if valueOfElem.IsNil() {
+				valueOfElem.Set(reflect.New(step.ptrType))
+			}
+			valueOfElem = valueOfElem.Elem()
+		}
+	}
+	return valueOfElem.FieldByIndex(fieldCache.fieldIndex)
+}
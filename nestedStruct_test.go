@@ -0,0 +1,111 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testCustomer 嵌套的struct
+// testCustomer nested struct
+type testCustomer struct {
+	Name string `column:"name"`
+	Age  int    `column:"age"`
+}
+
+// testNode 循环引用的struct
+// testNode struct with a circular reference
+type testNode struct {
+	Name      string    `column:"name"`
+	CreatedAt time.Time `column:"created_at"`
+	Parent    *testNode
+}
+
+func Test_findNestedFieldCache(t *testing.T) {
+	ctx := context.Background()
+	config := &DataSourceConfig{Dialect: "mysql"}
+	typeOf := reflect.TypeOf(testNode{})
+	entityCache, err := getStructTypeOfCache(ctx, &typeOf, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, path, _, err := findNestedFieldCache(ctx, config, entityCache, "parent.parent.name", 0)
+	if err != nil || field == nil || field.fieldName != "Name" || len(path) != 2 || path[1].ptrType != typeOf {
+		t.Errorf("findNestedFieldCache = %v, %v, %v", field, path, err)
+	}
+	for _, columnName := range []string{"parent.none", "other.name", "created_at.name", "parent.parent.parent.parent.parent.parent.name"} {
+		if field, _, _, _ := findNestedFieldCache(ctx, config, entityCache, columnName, 0); field != nil {
+			t.Errorf("%s: unexpected field %s", columnName, field.fieldName)
+		}
+	}
+
+	// 嵌套的指针只在赋值时创建
+	// Nested pointers are only created when assigned
+	node := testNode{}
+	field.nestedPath = path
+	selectFieldValue(reflect.ValueOf(&node).Elem(), field).SetString("root")
+	if node.Parent == nil || node.Parent.Parent == nil || node.Parent.Parent.Name != "root" || node.Parent.Parent.Parent != nil {
+		t.Errorf("selectFieldValue = %+v", node)
+	}
+}
+
+// scanFakeNestedTable 嵌套struct的结果集,偶数行的customer.name和customer_age是NULL
+// scanFakeNestedTable result set of nested structs, customer.name and customer_age of even rows are NULL
+var scanFakeNestedTable = &scanFakeTable{
+	name:        "nested",
+	columns:     []string{"id", "customer.name", "customer_age"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT"},
+	row: func(index int, values []driver.Value) {
+		values[0] = []byte(strconv.Itoa(index))
+		values[1], values[2] = nil, nil
+		if index%2 == 1 {
+			values[1] = []byte("customer")
+			values[2] = int64(30 + index)
+		}
+	},
+}
+
+func Test_NestedStruct_read(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeNestedTable, 2)
+	type orderRow struct {
+		ID       string        `column:"id"`
+		Customer *testCustomer // customer.name
+		Buyer    testCustomer  `zorm:"prefix=customer_"`
+	}
+	list := make([]orderRow, 0)
+	if err := Query(ctx, NewSelectFinder("test_table"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Query = %+v", list)
+	}
+	if list[0].Customer == nil || list[0].Customer.Name != "customer" || list[0].Buyer.Age != 31 || list[0].Buyer.Name != "" {
+		t.Errorf("row 0 = %+v %+v", list[0], list[0].Customer)
+	}
+	// NULL的列不创建嵌套的指针
+	// Nested pointers are not created for NULL columns
+	if list[1].Customer != nil || list[1].Buyer.Age != 0 || list[1].ID != "2" {
+		t.Errorf("row 1 = %+v", list[1])
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package otelzorm zorm的OpenTelemetry链路追踪,实现了zorm.ISQLHook接口,为Transaction,SQL语句和分布式事务创建span
+// 独立的module,不会给zorm引入OpenTelemetry依赖
+//
+//	dbDaoConfig := zorm.DataSourceConfig{
+//		// ...
+//		SQLHooks: []zorm.ISQLHook{otelzorm.NewHook()},
+//	}
+//
+// Package otelzorm OpenTelemetry tracing for zorm, implements the zorm.ISQLHook interface and creates spans for Transaction, SQL statements and distributed transactions
+// It is a separate module and does not bring OpenTelemetry dependencies into zorm
+package otelzorm
+
+import (
+	"context"
+	"strings"
+
+	"gitee.com/chunanyong/zorm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName tracer的名称
+// instrumentationName name of the tracer
+const instrumentationName = "gitee.com/chunanyong/zorm/otelzorm"
+
+// 语义约定的属性名,直接使用字符串,避免依赖semconv的版本
+// Attribute names of the semantic conventions, strings are used to avoid depending on a semconv version
+const (
+	dbSystemKey       = attribute.Key("db.system")
+	dbStatementKey    = attribute.Key("db.statement")
+	dbOperationKey    = attribute.Key("db.operation")
+	dbRowsAffectedKey = attribute.Key("db.rows_affected")
+	zormDialectKey    = attribute.Key("zorm.dialect")
+	zormOperationKey  = attribute.Key("zorm.operation")
+	zormInTxKey       = attribute.Key("zorm.in_transaction")
+)
+
+// dbSystemMap zorm的Dialect和db.system的对应关系,没有标准值的使用other_sql
+// dbSystemMap maps zorm Dialect to db.system, other_sql is used when there is no standard value
+var dbSystemMap = map[string]string{
+	"mysql":      "mysql",
+	"postgresql": "postgresql",
+	"oracle":     "oracle",
+	"mssql":      "mssql",
+	"sqlite":     "sqlite",
+	"db2":        "db2",
+	"clickhouse": "clickhouse",
+	"kingbase":   "postgresql",
+}
+
+// Option 配置hook的选项
+// Option configures the hook
+type Option func(h *hook)
+
+// WithTracerProvider 指定TracerProvider,默认使用otel.GetTracerProvider()
+// WithTracerProvider specifies the TracerProvider, otel.GetTracerProvider() is used by default
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(h *hook) {
+		h.tracerProvider = tracerProvider
+	}
+}
+
+// WithDBStatement 是否记录db.statement属性,默认true
+// WithDBStatement whether to record the db.statement attribute, true by default
+func WithDBStatement(enabled bool) Option {
+	return func(h *hook) {
+		h.dbStatement = enabled
+	}
+}
+
+// WithAttributes 所有span附加的属性,例如 db.name
+// WithAttributes attributes added to every span, e.g. db.name
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(h *hook) {
+		h.attrs = append(h.attrs, attrs...)
+	}
+}
+
+// hook zorm.ISQLHook 的实现
+// hook implementation of zorm.ISQLHook
+type hook struct {
+	tracerProvider trace.TracerProvider
+	tracer         trace.Tracer
+	dbStatement    bool
+	attrs          []attribute.KeyValue
+}
+
+// NewHook 创建链路追踪的钩子,配置到 zorm.DataSourceConfig.SQLHooks
+// NewHook creates the tracing hook, configure it in zorm.DataSourceConfig.SQLHooks
+func NewHook(opts ...Option) zorm.ISQLHook {
+	h := &hook{dbStatement: true}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.tracerProvider == nil {
+		h.tracerProvider = otel.GetTracerProvider()
+	}
+	h.tracer = h.tracerProvider.Tracer(instrumentationName)
+	return h
+}
+
+// Before 创建span,父span从ctx中获取
+// Before creates the span, the parent span is taken from ctx
+func (h *hook) Before(ctx context.Context, event *zorm.SQLHookEvent) context.Context {
+	attrs := make([]attribute.KeyValue, 0, len(h.attrs)+6)
+	attrs = append(attrs, h.attrs...)
+	attrs = append(attrs, zormOperationKey.String(event.Operation), zormInTxKey.Bool(event.InTransaction))
+	if event.Config != nil {
+		dbSystem, ok := dbSystemMap[event.Config.Dialect]
+		if !ok {
+			dbSystem = "other_sql"
+		}
+		attrs = append(attrs, dbSystemKey.String(dbSystem), zormDialectKey.String(event.Config.Dialect))
+	}
+
+	spanKind := trace.SpanKindInternal
+	spanName := spanNameOf(event.Operation)
+	if event.SQL != "" {
+		spanKind = trace.SpanKindClient
+		if operation := firstWord(event.SQL); operation != "" {
+			spanName = operation
+			attrs = append(attrs, dbOperationKey.String(operation))
+		}
+		if h.dbStatement {
+			attrs = append(attrs, dbStatementKey.String(event.SQL))
+		}
+	}
+
+	ctx, _ = h.tracer.Start(ctx, spanName, trace.WithSpanKind(spanKind), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+// After 记录影响行数和错误,结束span
+// After records rows affected and the error, then ends the span
+func (h *hook) After(ctx context.Context, event *zorm.SQLHookEvent) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		span.End()
+		return
+	}
+	if event.RowsAffected >= 0 {
+		span.SetAttributes(dbRowsAffectedKey.Int64(event.RowsAffected))
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	span.End()
+}
+
+// spanNameOf 没有SQL语句时的span名称
+// spanNameOf span name when there is no SQL statement
+func spanNameOf(operation string) string {
+	switch operation {
+	case zorm.SQLHookOperationTransaction:
+		return "zorm.Transaction"
+	case zorm.SQLHookOperationBeginGTX:
+		return "zorm.BeginGTX"
+	case zorm.SQLHookOperationCommitGTX:
+		return "zorm.CommitGTX"
+	case zorm.SQLHookOperationRollbackGTX:
+		return "zorm.RollbackGTX"
+	}
+	return "zorm." + operation
+}
+
+// firstWord 获取SQL的第一个单词并转为大写,例如 SELECT
+// firstWord gets the first word of the SQL in upper case, e.g. SELECT
+func firstWord(sqlstr string) string {
+	sqlstr = strings.TrimLeft(sqlstr, " \t\r\n(")
+	end := strings.IndexAny(sqlstr, " \t\r\n(")
+	if end < 0 {
+		end = len(sqlstr)
+	}
+	return strings.ToUpper(sqlstr[:end])
+}
@@ -0,0 +1,249 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package otelzorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gitee.com/chunanyong/zorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeDriver 测试用的数据库驱动,exec影响1行,query返回一行一列,包含 fail 的语句返回错误
+// fakeDriver test driver, exec affects 1 row, query returns one row with one column, statements containing fail return an error
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct{ query string }
+
+type fakeTx struct{}
+
+type fakeRows struct{ done bool }
+
+var errFake = errors.New("fake error")
+
+func (fakeDriver) Open(name string) (driver.Conn, error)   { return fakeConn{}, nil }
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+func (fakeTx) Commit() error                               { return nil }
+func (fakeTx) Rollback() error                             { return nil }
+func (s *fakeStmt) Close() error                           { return nil }
+func (s *fakeStmt) NumInput() int                          { return -1 }
+func (fakeRows) Columns() []string                         { return []string{"id"} }
+func (fakeRows) Close() error                              { return nil }
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFake
+	}
+	return &fakeRows{}, nil
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFake
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+// fakeGlobalTransaction 测试用的分布式事务
+// fakeGlobalTransaction test distributed transaction
+type fakeGlobalTransaction struct{}
+
+func (fakeGlobalTransaction) BeginGTX(ctx context.Context, globalRootContext context.Context) error {
+	return nil
+}
+
+func (fakeGlobalTransaction) CommitGTX(ctx context.Context, globalRootContext context.Context) error {
+	return nil
+}
+
+func (fakeGlobalTransaction) RollbackGTX(ctx context.Context, globalRootContext context.Context) error {
+	return nil
+}
+
+func (fakeGlobalTransaction) GetGTXID(ctx context.Context, globalRootContext context.Context) (string, error) {
+	return "gtx-1", nil
+}
+
+func init() {
+	sql.Register("otelzorm_fake", fakeDriver{})
+}
+
+func newTestDBDao(t *testing.T) (*zorm.DBDao, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	dbDao, err := zorm.NewDBDao(&zorm.DataSourceConfig{
+		DSN:           "fake",
+		DriverName:    "otelzorm_fake",
+		Dialect:       "postgresql",
+		SlowSQLMillis: -1,
+		SQLHooks:      []zorm.ISQLHook{NewHook(WithTracerProvider(tracerProvider))},
+		FuncGlobalTransaction: func(ctx context.Context) (zorm.IGlobalTransaction, context.Context, context.Context, error) {
+			return fakeGlobalTransaction{}, ctx, ctx, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = dbDao.CloseDB() })
+	return dbDao, exporter
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestHook_TransactionAndStatements(t *testing.T) {
+	dbDao, exporter := newTestDBDao(t)
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zorm.Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		finder := zorm.NewUpdateFinder("t_user").Append("name=? WHERE id=?", "abc", 1)
+		return zorm.UpdateFinder(ctx, finder)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	// span 按结束顺序导出,语句先结束 | spans are exported in end order, the statement ends first
+	stmtSpan, txSpan := spans[0], spans[1]
+	if txSpan.Name != "zorm.Transaction" {
+		t.Errorf("transaction span name = %s", txSpan.Name)
+	}
+	if stmtSpan.Name != "UPDATE" {
+		t.Errorf("statement span name = %s", stmtSpan.Name)
+	}
+	if stmtSpan.Parent.SpanID() != txSpan.SpanContext.SpanID() {
+		t.Errorf("statement span is not a child of the transaction span")
+	}
+	if v, _ := attributeValue(stmtSpan, dbSystemKey); v.AsString() != "postgresql" {
+		t.Errorf("db.system = %s", v.AsString())
+	}
+	if v, _ := attributeValue(stmtSpan, dbStatementKey); v.AsString() != "UPDATE t_user SET  name=$1 WHERE id=$2" {
+		t.Errorf("db.statement = %q", v.AsString())
+	}
+	if v, _ := attributeValue(stmtSpan, dbRowsAffectedKey); v.AsInt64() != 1 {
+		t.Errorf("db.rows_affected = %d", v.AsInt64())
+	}
+	if v, _ := attributeValue(stmtSpan, zormInTxKey); !v.AsBool() {
+		t.Errorf("zorm.in_transaction should be true")
+	}
+}
+
+func TestHook_ParentFromContext(t *testing.T) {
+	dbDao, exporter := newTestDBDao(t)
+	parentProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := parentProvider.Tracer("test").Start(context.Background(), "parent")
+	ctx, err := dbDao.BindContextDBConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := 0
+	if _, err = zorm.QueryRow(ctx, zorm.NewSelectFinder("t_user", "id"), &id); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	if spans[0].Name != "SELECT" || spans[0].Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("query span %s is not a child of the parent span", spans[0].Name)
+	}
+}
+
+func TestHook_Error(t *testing.T) {
+	dbDao, exporter := newTestDBDao(t)
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zorm.Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return zorm.UpdateFinder(ctx, zorm.NewDeleteFinder("fail_table"))
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status.Code != codes.Error {
+			t.Errorf("span %s status = %v, want Error", span.Name, span.Status.Code)
+		}
+	}
+}
+
+func TestHook_GlobalTransaction(t *testing.T) {
+	dbDao, exporter := newTestDBDao(t)
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = zorm.BindContextEnableGlobalTransaction(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zorm.Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	if strings.Join(names, ",") != "zorm.BeginGTX,zorm.CommitGTX,zorm.Transaction" {
+		t.Errorf("spans = %v", names)
+	}
+}
@@ -0,0 +1,362 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 关联关系的zorm tag选项,例如:
+// Items    []OrderItem `zorm:"hasMany,foreignKey=order_id"` order_item.order_id 关联 order 的主键
+// Customer *Customer   `zorm:"belongsTo,foreignKey=customer_id"` order.customer_id 关联 customer 的主键
+// Profile  *Profile    `zorm:"hasOne,foreignKey=user_id,references=id"` profile.user_id 关联 user.id
+// 关联的struct必须实现IEntityStruct,references默认是主键列
+// zorm tag options of relations, e.g.:
+// Items    []OrderItem `zorm:"hasMany,foreignKey=order_id"` order_item.order_id references the primary key of order
+// Customer *Customer   `zorm:"belongsTo,foreignKey=customer_id"` order.customer_id references the primary key of customer
+// Profile  *Profile    `zorm:"hasOne,foreignKey=user_id,references=id"` profile.user_id references user.id
+// The related struct must implement IEntityStruct, references is the primary key column by default
+const (
+	// zormTagOptionHasOne 一对一,外键在关联表,字段是*struct或者struct
+	// zormTagOptionHasOne one-to-one, the foreign key is in the related table, the field is *struct or struct
+	zormTagOptionHasOne = "hasOne"
+	// zormTagOptionHasMany 一对多,外键在关联表,字段是[]struct或者[]*struct
+	// zormTagOptionHasMany one-to-many, the foreign key is in the related table, the field is []struct or []*struct
+	zormTagOptionHasMany = "hasMany"
+	// zormTagOptionBelongsTo 多对一,外键在当前表,字段是*struct或者struct
+	// zormTagOptionBelongsTo many-to-one, the foreign key is in the current table, the field is *struct or struct
+	zormTagOptionBelongsTo = "belongsTo"
+	// zormTagOptionForeignKey 外键的列名
+	// zormTagOptionForeignKey column name of the foreign key
+	zormTagOptionForeignKey = "foreignKey"
+	// zormTagOptionReferences 外键关联的列名,默认是主键列
+	// zormTagOptionReferences column name referenced by the foreign key, the primary key column by default
+	zormTagOptionReferences = "references"
+)
+
+// relationInfo 关联关系的信息
+// relationInfo information of a relation
+type relationInfo struct {
+	// kind hasOne,hasMany或者belongsTo
+	// kind hasOne, hasMany or belongsTo
+	kind string
+	// field 关联的字段
+	// field the relation field
+	field reflect.StructField
+	// entityType 关联的struct类型
+	// entityType the related struct type
+	entityType reflect.Type
+	// tableName 关联的表名
+	// tableName the related table name
+	tableName string
+	// parentColumn 当前表用于关联的列
+	// parentColumn column of the current table used by the relation
+	parentColumn string
+	// childColumn 关联表用于关联的列
+	// childColumn column of the related table used by the relation
+	childColumn string
+}
+
+// contextPreloadValueKey 把预加载的关联关系放到context里使用的key
+// contextPreloadValueKey The key used to put the preloaded relations into the context
+const contextPreloadValueKey = wrapContextStringKey("contextPreloadValueKey")
+
+// BindContextPreload context中绑定Query和QueryRow预加载的关联关系,relations是关联的字段名,多层使用 . 分隔,例如 "Items","Items.Product","Customer".
+// 查询主表之后,每个关联关系执行一次 WHERE 关联列 IN (?) 的查询,按照BatchMaxRows和BatchMaxParams拆分,然后赋值给主表的字段,避免N+1查询.
+// 会保留parent中已经绑定的关联关系
+// BindContextPreload binds the relations preloaded by Query and QueryRow to the context, relations are the field names of the relations, levels are separated by . , e.g. "Items","Items.Product","Customer".
+// After the main rows are queried, each relation runs one WHERE relationColumn IN (?) query, split by BatchMaxRows and BatchMaxParams, then assigns the results to the fields of the main rows, avoiding N+1 queries.
+// The relations already bound in parent are kept
+func BindContextPreload(parent context.Context, relations ...string) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextPreload-->context的parent不能为nil")
+	}
+	if len(relations) < 1 {
+		return nil, errors.New("->BindContextPreload-->relations不能为空")
+	}
+	preloads := make([]string, 0)
+	if parentPreloads, ok := parent.Value(contextPreloadValueKey).([]string); ok {
+		preloads = append(preloads, parentPreloads...)
+	}
+	preloads = append(preloads, relations...)
+	ctx := context.WithValue(parent, contextPreloadValueKey, preloads)
+	return ctx, nil
+}
+
+// entityStructType IEntityStruct的反射类型
+// entityStructType reflection type of IEntityStruct
+var entityStructType = reflect.TypeOf((*IEntityStruct)(nil)).Elem()
+
+// preloadQueryRows 预加载Query和QueryRow结果的关联关系,rowsPtr是*[]struct,*[]*struct或者*struct,slice只处理start之后Query新增的元素.
+// ctx没有绑定预加载或者没有实现IEntityStruct时不处理,例如time.Time和decimal
+// preloadQueryRows preloads the relations of the results of Query and QueryRow, rowsPtr is *[]struct, *[]*struct or *struct, only the elements appended by Query after start are processed for slices.
+// Nothing is done when ctx has no preloads bound or IEntityStruct is not implemented, e.g. time.Time and decimal
+func preloadQueryRows(ctx context.Context, rowsPtr interface{}, start int) error {
+	preloads, ok := ctx.Value(contextPreloadValueKey).([]string)
+	if !ok || len(preloads) < 1 {
+		return nil
+	}
+	valueOf := reflect.Indirect(reflect.ValueOf(rowsPtr))
+	var parents []reflect.Value
+	if valueOf.Kind() == reflect.Slice {
+		parents = make([]reflect.Value, 0, valueOf.Len())
+		for i := start; i < valueOf.Len(); i++ {
+			if parent := reflect.Indirect(valueOf.Index(i)); parent.IsValid() {
+				parents = append(parents, parent)
+			}
+		}
+	} else {
+		parents = []reflect.Value{valueOf}
+	}
+	if len(parents) < 1 || parents[0].Kind() != reflect.Struct || !reflect.PtrTo(parents[0].Type()).Implements(entityStructType) {
+		return nil
+	}
+	dbConnection, err := getDBConnectionFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	config, err := getConfigFromConnection(ctx, dbConnection, 0)
+	if err != nil {
+		return err
+	}
+	// 关联表的查询不再预加载,多层关联由preloadRelations递归处理
+	// Queries of related tables do not preload again, multiple levels are handled recursively by preloadRelations
+	ctx = context.WithValue(ctx, contextPreloadValueKey, []string(nil))
+	err = preloadRelations(ctx, config, parents, parents[0].Type(), preloads)
+	if err != nil {
+		err = fmt.Errorf("->preloadQueryRows-->预加载关联关系错误:%w", err)
+		FuncLogError(ctx, err)
+	}
+	return err
+}
+
+// preloadRelations 加载parents的关联关系,paths的第一层是当前struct的字段名,其余的层级递归加载
+// preloadRelations loads the relations of parents, the first level of paths is the field name of the current struct, the remaining levels are loaded recursively
+func preloadRelations(ctx context.Context, config *DataSourceConfig, parents []reflect.Value, parentType reflect.Type, paths []string) error {
+	// 按照第一层字段名分组,保持顺序
+	// Group by the first level field name, keeping the order
+	names := make([]string, 0, len(paths))
+	subPaths := make(map[string][]string)
+	for _, path := range paths {
+		name, subPath := path, ""
+		if dot := strings.IndexByte(path, '.'); dot >= 0 {
+			name, subPath = path[:dot], path[dot+1:]
+		}
+		key := strings.ToLower(name)
+		if _, ok := subPaths[key]; !ok {
+			names = append(names, name)
+			subPaths[key] = make([]string, 0)
+		}
+		if subPath != "" {
+			subPaths[key] = append(subPaths[key], subPath)
+		}
+	}
+	for _, name := range names {
+		relation, err := parseRelation(parentType, name)
+		if err != nil {
+			return err
+		}
+		children, err := loadRelation(ctx, config, parents, parentType, relation)
+		if err != nil {
+			return err
+		}
+		if nested := subPaths[strings.ToLower(name)]; len(nested) > 0 && len(children) > 0 {
+			if err = preloadRelations(ctx, config, children, relation.entityType, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseRelation 解析struct字段的关联关系
+// parseRelation parses the relation of the struct field
+func parseRelation(parentType reflect.Type, name string) (*relationInfo, error) {
+	field, ok := parentType.FieldByNameFunc(func(fieldName string) bool {
+		return strings.EqualFold(fieldName, name)
+	})
+	if !ok {
+		return nil, fmt.Errorf("->parseRelation-->%s没有字段%s", parentType.String(), name)
+	}
+	relation := &relationInfo{field: field}
+	for _, kind := range []string{zormTagOptionHasOne, zormTagOptionHasMany, zormTagOptionBelongsTo} {
+		if hasZormTagOption(field.Tag, kind) {
+			relation.kind = kind
+		}
+	}
+	if relation.kind == "" {
+		return nil, fmt.Errorf("->parseRelation-->字段%s没有声明hasOne,hasMany或者belongsTo", field.Name)
+	}
+	entityType := field.Type
+	if relation.kind == zormTagOptionHasMany {
+		if entityType.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("->parseRelation-->hasMany字段%s必须是slice", field.Name)
+		}
+		entityType = entityType.Elem()
+	}
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	entity, ok := reflect.New(entityType).Interface().(IEntityStruct)
+	if entityType.Kind() != reflect.Struct || !ok {
+		return nil, fmt.Errorf("->parseRelation-->字段%s关联的类型必须实现IEntityStruct", field.Name)
+	}
+	relation.entityType = entityType
+	relation.tableName = entity.GetTableName()
+
+	foreignKey := zormTagOptionValue(field.Tag, zormTagOptionForeignKey)
+	if foreignKey == "" {
+		return nil, fmt.Errorf("->parseRelation-->字段%s没有声明foreignKey", field.Name)
+	}
+	references := zormTagOptionValue(field.Tag, zormTagOptionReferences)
+	if relation.kind == zormTagOptionBelongsTo { // 外键在当前表,关联表默认使用主键 | The foreign key is in the current table, the related table uses the primary key by default
+		if references == "" {
+			references = entity.GetPKColumnName()
+		}
+		relation.parentColumn, relation.childColumn = foreignKey, references
+	} else { // 外键在关联表,当前表默认使用主键 | The foreign key is in the related table, the current table uses the primary key by default
+		if references == "" {
+			parentEntity, isEntity := reflect.New(parentType).Interface().(IEntityStruct)
+			if !isEntity {
+				return nil, fmt.Errorf("->parseRelation-->%s没有实现IEntityStruct,字段%s必须声明references", parentType.String(), field.Name)
+			}
+			references = parentEntity.GetPKColumnName()
+		}
+		relation.parentColumn, relation.childColumn = references, foreignKey
+	}
+	if relation.parentColumn == "" || relation.childColumn == "" {
+		return nil, fmt.Errorf("->parseRelation-->字段%s关联的列不能为空", field.Name)
+	}
+	return relation, nil
+}
+
+// relationKey 关联列的值转换为map的key,兼容不同的整数类型和指针,nil返回false
+// relationKey converts the value of the relation column to a map key, compatible with different integer types and pointers, false for nil
+func relationKey(value reflect.Value) (interface{}, string, bool) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, "", false
+		}
+		value = value.Elem()
+	}
+	v := value.Interface()
+	return v, fmt.Sprint(v), true
+}
+
+// loadRelation 查询关联表并赋值给parents的关联字段,返回关联的struct,用于下一层的预加载
+// loadRelation queries the related table and assigns the results to the relation field of parents, returns the related structs for the preload of the next level
+func loadRelation(ctx context.Context, config *DataSourceConfig, parents []reflect.Value, parentType reflect.Type, relation *relationInfo) ([]reflect.Value, error) {
+	parentCache, err := getStructTypeOfCache(ctx, &parentType, config)
+	if err != nil {
+		return nil, err
+	}
+	childCache, err := getStructTypeOfCache(ctx, &relation.entityType, config)
+	if err != nil {
+		return nil, err
+	}
+	parentField := lookupColumnFieldCache(parentCache, strings.ToLower(relation.parentColumn))
+	childField := lookupColumnFieldCache(childCache, strings.ToLower(relation.childColumn))
+	if parentField == nil || childField == nil {
+		return nil, fmt.Errorf("->loadRelation-->字段%s关联的列%s或者%s不存在", relation.field.Name, relation.parentColumn, relation.childColumn)
+	}
+
+	// 去重的关联值
+	// Distinct relation values
+	keys := make([]interface{}, 0, len(parents))
+	keySet := make(map[string]bool, len(parents))
+	for _, parent := range parents {
+		key, keyString, ok := relationKey(parent.FieldByIndex(parentField.fieldIndex))
+		if ok && !keySet[keyString] {
+			keySet[keyString] = true
+			keys = append(keys, key)
+		}
+	}
+
+	// 每批使用一条 IN 语句查询,按照BatchMaxRows和BatchMaxParams拆分
+	// Each batch is queried with one IN statement, split by BatchMaxRows and BatchMaxParams
+	childSlice := reflect.New(reflect.SliceOf(relation.entityType))
+	batchRows := batchRowCount(config, 1)
+	if batchRows < 1 {
+		batchRows = len(keys)
+	}
+	for start := 0; start < len(keys); start += batchRows {
+		end := start + batchRows
+		if end > len(keys) {
+			end = len(keys)
+		}
+		finder := NewSelectFinder(wrapTableName(ctx, config, relation.tableName)).Append("WHERE "+wrapQuoteIdentifier(config, relation.childColumn)+" IN (?)", keys[start:end])
+		chunk := reflect.New(reflect.SliceOf(relation.entityType))
+		if err = query(ctx, finder, chunk.Interface(), nil); err != nil {
+			return nil, err
+		}
+		if err = snapshotQueryRows(ctx, chunk.Interface(), 0); err != nil {
+			return nil, err
+		}
+		childSlice.Elem().Set(reflect.AppendSlice(childSlice.Elem(), chunk.Elem()))
+	}
+
+	// 按照关联值分组
+	// Group by the relation value
+	childValues := childSlice.Elem()
+	groups := make(map[string][]reflect.Value)
+	for i := 0; i < childValues.Len(); i++ {
+		child := childValues.Index(i)
+		if _, keyString, ok := relationKey(child.FieldByIndex(childField.fieldIndex)); ok {
+			groups[keyString] = append(groups[keyString], child)
+		}
+	}
+
+	// 赋值给parents的关联字段
+	// Assign to the relation field of parents
+	children := make([]reflect.Value, 0, childValues.Len())
+	for _, parent := range parents {
+		var matched []reflect.Value
+		if _, keyString, ok := relationKey(parent.FieldByIndex(parentField.fieldIndex)); ok {
+			matched = groups[keyString]
+		}
+		fieldValue := parent.FieldByIndex(relation.field.Index)
+		if relation.kind == zormTagOptionHasMany {
+			sliceValue := reflect.MakeSlice(fieldValue.Type(), len(matched), len(matched))
+			for i, child := range matched {
+				if fieldValue.Type().Elem().Kind() == reflect.Ptr {
+					sliceValue.Index(i).Set(child.Addr())
+				} else {
+					sliceValue.Index(i).Set(child)
+				}
+				children = append(children, reflect.Indirect(sliceValue.Index(i)))
+			}
+			fieldValue.Set(sliceValue)
+			continue
+		}
+		if len(matched) < 1 {
+			continue
+		}
+		if fieldValue.Kind() == reflect.Ptr {
+			fieldValue.Set(matched[0].Addr())
+		} else {
+			fieldValue.Set(matched[0])
+		}
+		children = append(children, reflect.Indirect(fieldValue))
+	}
+	return children, nil
+}
@@ -0,0 +1,209 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testPreloadOrder 预加载的主表
+// testPreloadOrder main table of the preload
+type testPreloadOrder struct {
+	EntityStruct
+	ID    string             `column:"id"`
+	Age   *int               `column:"age"`
+	Items []*testPreloadItem `zorm:"hasMany,foreignKey=id"`
+	Owner *testPreloadOwner  `zorm:"belongsTo,foreignKey=age"`
+}
+
+func (entity *testPreloadOrder) GetTableName() string { return "t_order" }
+
+// testPreloadItem hasMany关联的表
+// testPreloadItem table of the hasMany relation
+type testPreloadItem struct {
+	EntityStruct
+	ID    string           `column:"id"`
+	Email string           `column:"email"`
+	Age   int              `column:"age"`
+	Owner testPreloadOwner `zorm:"belongsTo,foreignKey=age"`
+}
+
+func (entity *testPreloadItem) GetTableName() string { return "t_order_item" }
+
+// testPreloadOwner belongsTo关联的表,主键是age
+// testPreloadOwner table of the belongsTo relation, the primary key is age
+type testPreloadOwner struct {
+	EntityStruct
+	Age      int    `column:"age"`
+	UserName string `column:"user_name"`
+}
+
+func (entity *testPreloadOwner) GetTableName() string { return "t_owner" }
+func (entity *testPreloadOwner) GetPKColumnName() string {
+	return "age"
+}
+
+// scanFakePreloadTable 预加载的结果集,所有表都返回相同的行,偶数行的user_name和age是NULL
+// scanFakePreloadTable result set of the preload, all tables return the same rows, user_name and age of even rows are NULL
+var scanFakePreloadTable = &scanFakeTable{
+	name:        "preload",
+	columns:     []string{"id", "user_name", "age", "email"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT", "VARCHAR"},
+	row: func(index int, values []driver.Value) {
+		scanFakeUserValues(index, values)
+		values[3] = "a@b.c"
+	},
+}
+
+// newPreloadFakeContext 返回scanFakePreloadTable数据库连接的ctx和执行语句的函数,查询返回rowCount行
+// newPreloadFakeContext returns the ctx of a scanFakePreloadTable connection and a function of the executed statements, queries return rowCount rows
+func newPreloadFakeContext(t *testing.T, rowCount int, batchMaxRows int) (context.Context, func() []string) {
+	ctx := newScanFakeConfigContext(t, scanFakePreloadTable, &DataSourceConfig{DSN: strconv.Itoa(rowCount), Dialect: "mysql", BatchMaxRows: batchMaxRows})
+	return ctx, recordScanFakeSQL(t)
+}
+
+func Test_Preload(t *testing.T) {
+	ctx, statements := newPreloadFakeContext(t, 3, 0)
+	ctx, err := BindContextPreload(ctx, "Items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = BindContextPreload(ctx, "Owner", "Items.Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := make([]testPreloadOrder, 0)
+	if err = Query(ctx, NewSelectFinder("t_order"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT * FROM t_order",
+		"SELECT * FROM t_order_item WHERE id IN (?,?,?)",
+		"SELECT * FROM t_owner WHERE age IN (?,?,?)",
+		"SELECT * FROM t_owner WHERE age IN (?,?)",
+	}
+	if got := statements(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("statements = %q, want %q", got, want)
+	}
+	if len(list) != 3 {
+		t.Fatalf("len(list) = %d", len(list))
+	}
+	for i, order := range list {
+		if len(order.Items) != 1 || order.Items[0].ID != order.ID || order.Items[0].Email != "a@b.c" {
+			t.Errorf("list[%d].Items = %+v", i, order.Items)
+		}
+	}
+	// 第2行的age是NULL,没有关联的数据 | age of the 2nd row is NULL, there is no related row
+	if list[0].Owner == nil || list[0].Owner.Age != 1 || list[0].Owner.UserName != "name" || list[1].Owner != nil || list[2].Owner == nil || list[2].Owner.Age != 3 {
+		t.Errorf("Owner = %+v, %+v, %+v", list[0].Owner, list[1].Owner, list[2].Owner)
+	}
+	if owner := list[2].Items[0].Owner; owner.Age != 3 || owner.UserName != "name" {
+		t.Errorf("Items.Owner = %+v", owner)
+	}
+	if owner := list[1].Items[0].Owner; owner.Age != 0 {
+		t.Errorf("Items.Owner = %+v, want empty", owner)
+	}
+}
+
+func Test_Preload_QueryRow(t *testing.T) {
+	ctx, statements := newPreloadFakeContext(t, 1, 0)
+	ctx, err := BindContextPreload(ctx, "Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := testPreloadOrder{}
+	has, err := QueryRow(ctx, NewSelectFinder("t_order"), &order)
+	if err != nil || !has {
+		t.Fatalf("QueryRow = %v, %v", has, err)
+	}
+	if order.Owner == nil || order.Owner.Age != 1 || order.Items != nil || len(statements()) != 2 {
+		t.Errorf("order = %+v, statements = %q", order, statements())
+	}
+}
+
+func Test_Preload_chunk(t *testing.T) {
+	ctx, statements := newPreloadFakeContext(t, 3, 2)
+	ctx, err := BindContextPreload(ctx, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := make([]*testPreloadOrder, 0)
+	if err = Query(ctx, NewSelectFinder("t_order"), &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT * FROM t_order",
+		"SELECT * FROM t_order_item WHERE id IN (?,?)",
+		"SELECT * FROM t_order_item WHERE id IN (?)",
+	}
+	if got := statements(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("statements = %q, want %q", got, want)
+	}
+	// 驱动每次返回3行,每个id在两批中各出现一次 | The driver returns 3 rows each time, each id appears once in each batch
+	for i, order := range list {
+		if len(order.Items) != 2 || order.Owner != nil {
+			t.Errorf("list[%d] = %+v", i, order)
+		}
+	}
+}
+
+func Test_parseRelation(t *testing.T) {
+	orderType := reflect.TypeOf(testPreloadOrder{})
+	relation, err := parseRelation(orderType, "owner")
+	if err != nil || relation.kind != zormTagOptionBelongsTo || relation.parentColumn != "age" || relation.childColumn != "age" || relation.tableName != "t_owner" {
+		t.Errorf("parseRelation = %+v, %v", relation, err)
+	}
+	relation, err = parseRelation(orderType, "Items")
+	if err != nil || relation.kind != zormTagOptionHasMany || relation.parentColumn != "id" || relation.childColumn != "id" {
+		t.Errorf("parseRelation = %+v, %v", relation, err)
+	}
+	for _, name := range []string{"None", "ID"} {
+		if _, err = parseRelation(orderType, name); err == nil {
+			t.Errorf("parseRelation(%s) should fail", name)
+		}
+	}
+	if _, err = BindContextPreload(context.Background()); err == nil {
+		t.Error("BindContextPreload without relations should fail")
+	}
+}
+
+func Test_Preload_notEntity(t *testing.T) {
+	ctx, statements := newPreloadFakeContext(t, 1, 0)
+	ctx, err := BindContextPreload(ctx, "Items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有实现IEntityStruct的类型不预加载 | Types not implementing IEntityStruct are not preloaded
+	age := 0
+	if has, err := QueryRow(ctx, NewSelectFinder("t_order", "age"), &age); err != nil || !has || age != 1 {
+		t.Errorf("QueryRow = %v, %v, %v", has, err, age)
+	}
+	names := make([]string, 0)
+	if err = Query(ctx, NewSelectFinder("t_order", "user_name"), &names, nil); err != nil || len(names) != 1 {
+		t.Errorf("Query = %v, %v", names, err)
+	}
+	if got := statements(); len(got) != 2 {
+		t.Errorf("statements = %q", got)
+	}
+}
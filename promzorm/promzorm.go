@@ -0,0 +1,223 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package promzorm zorm的Prometheus监控指标,每个DBDao创建一个Collector,指标使用dialect和datasource标签区分
+// 独立的module,不会给zorm引入Prometheus依赖
+//
+//	collector, _ := promzorm.NewCollector(&dbDaoConfig)
+//	dbDaoConfig.SQLHooks = append(dbDaoConfig.SQLHooks, collector)
+//	dbDao, _ := zorm.NewDBDao(&dbDaoConfig)
+//	collector.SetDBDao(dbDao)
+//	prometheus.MustRegister(collector)
+//
+// Package promzorm Prometheus metrics for zorm, one Collector per DBDao, metrics are distinguished by the dialect and datasource labels
+// It is a separate module and does not bring Prometheus dependencies into zorm
+package promzorm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"gitee.com/chunanyong/zorm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace 指标的命名空间
+// namespace of the metrics
+const namespace = "zorm"
+
+// Option 配置Collector的选项
+// Option configures the Collector
+type Option func(c *Collector)
+
+// WithBuckets 耗时直方图的桶,单位秒,默认 prometheus.DefBuckets
+// WithBuckets buckets of the latency histogram in seconds, prometheus.DefBuckets by default
+func WithBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.buckets = buckets
+	}
+}
+
+// WithSlowSQLMillis 慢SQL的阈值,单位毫秒,默认使用DataSourceConfig.SlowSQLMillis,小于等于0不统计慢SQL
+// WithSlowSQLMillis threshold of slow SQL in milliseconds, DataSourceConfig.SlowSQLMillis by default, slow SQL is not counted when <= 0
+func WithSlowSQLMillis(slowSQLMillis int) Option {
+	return func(c *Collector) {
+		c.slowSQLMillis = slowSQLMillis
+	}
+}
+
+// Collector 一个DBDao的监控指标,实现了prometheus.Collector和zorm.ISQLHook
+// Collector metrics of one DBDao, implements prometheus.Collector and zorm.ISQLHook
+type Collector struct {
+	// dbDao 连接池指标的DBDao,保存*zorm.DBDao
+	// dbDao DBDao of the connection pool metrics, holds a *zorm.DBDao
+	dbDao         atomic.Value
+	buckets       []float64
+	slowSQLMillis int
+
+	operations   *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	transactions *prometheus.CounterVec
+	slowQueries  *prometheus.CounterVec
+
+	maxOpenDesc      *prometheus.Desc
+	openDesc         *prometheus.Desc
+	inUseDesc        *prometheus.Desc
+	idleDesc         *prometheus.Desc
+	waitCountDesc    *prometheus.Desc
+	waitDurationDesc *prometheus.Desc
+}
+
+// NewCollector 根据数据源配置创建Collector,不修改config.需要在NewDBDao之前把Collector添加到config.SQLHooks,
+// NewDBDao之后调用SetDBDao采集连接池指标,返回的Collector需要注册到prometheus.Registerer.config.Name作为datasource标签,不能为空
+// NewCollector creates the Collector from the datasource config, config is not modified. The Collector must be appended to config.SQLHooks before NewDBDao,
+// call SetDBDao after NewDBDao to collect the connection pool metrics, the returned Collector needs to be registered to a prometheus.Registerer.
+// config.Name is used as the datasource label and must not be empty
+func NewCollector(config *zorm.DataSourceConfig, opts ...Option) (*Collector, error) {
+	if config == nil {
+		return nil, errors.New("->promzorm.NewCollector-->DataSourceConfig不能为nil")
+	}
+	if config.Name == "" {
+		return nil, errors.New("->promzorm.NewCollector-->DataSourceConfig.Name不能为空,用于区分datasource标签")
+	}
+	c := &Collector{
+		buckets:       prometheus.DefBuckets,
+		slowSQLMillis: config.SlowSQLMillis,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	constLabels := prometheus.Labels{"dialect": config.Dialect, "datasource": config.Name}
+
+	c.operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Name:        "operations_total",
+		Help:        "Number of SQL statements and distributed transaction operations executed by zorm.",
+		ConstLabels: constLabels,
+	}, []string{"operation", "status"})
+	c.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   namespace,
+		Name:        "operation_duration_seconds",
+		Help:        "Latency of SQL statements and transactions executed by zorm.",
+		ConstLabels: constLabels,
+		Buckets:     c.buckets,
+	}, []string{"operation"})
+	c.transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Name:        "transactions_total",
+		Help:        "Number of zorm.Transaction calls by outcome.",
+		ConstLabels: constLabels,
+	}, []string{"outcome"})
+	c.slowQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Name:        "slow_queries_total",
+		Help:        "Number of SQL statements slower than the slow SQL threshold.",
+		ConstLabels: constLabels,
+	}, []string{"operation"})
+
+	newPoolDesc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, constLabels)
+	}
+	c.maxOpenDesc = newPoolDesc("max_open_connections", "Maximum number of open connections to the database.")
+	c.openDesc = newPoolDesc("open_connections", "The number of established connections both in use and idle.")
+	c.inUseDesc = newPoolDesc("in_use_connections", "The number of connections currently in use.")
+	c.idleDesc = newPoolDesc("idle_connections", "The number of idle connections.")
+	c.waitCountDesc = newPoolDesc("wait_count_total", "The total number of connections waited for.")
+	c.waitDurationDesc = newPoolDesc("wait_duration_seconds_total", "The total time blocked waiting for a new connection.")
+
+	return c, nil
+}
+
+// SetDBDao 设置采集连接池指标的DBDao,没有设置时不采集连接池指标
+// SetDBDao sets the DBDao whose connection pool metrics are collected, the connection pool metrics are not collected without it
+func (c *Collector) SetDBDao(dbDao *zorm.DBDao) {
+	c.dbDao.Store(dbDao)
+}
+
+// Describe 实现prometheus.Collector
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.operations.Describe(ch)
+	c.duration.Describe(ch)
+	c.transactions.Describe(ch)
+	c.slowQueries.Describe(ch)
+	ch <- c.maxOpenDesc
+	ch <- c.openDesc
+	ch <- c.inUseDesc
+	ch <- c.idleDesc
+	ch <- c.waitCountDesc
+	ch <- c.waitDurationDesc
+}
+
+// Collect 实现prometheus.Collector,连接池指标在采集时读取
+// Collect implements prometheus.Collector, connection pool metrics are read at collection time
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.operations.Collect(ch)
+	c.duration.Collect(ch)
+	c.transactions.Collect(ch)
+	c.slowQueries.Collect(ch)
+
+	dbDao, ok := c.dbDao.Load().(*zorm.DBDao)
+	if !ok || dbDao == nil {
+		return
+	}
+	stats, err := dbDao.Stats()
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.maxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.openDesc, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUseDesc, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idleDesc, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCountDesc, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
+
+// Before 实现zorm.ISQLHook,不做处理
+// Before implements zorm.ISQLHook, does nothing
+func (c *Collector) Before(ctx context.Context, event *zorm.SQLHookEvent) context.Context {
+	return ctx
+}
+
+// After 实现zorm.ISQLHook,记录次数,耗时,事务结果和慢SQL
+// After implements zorm.ISQLHook, records count, latency, transaction outcome and slow SQL
+func (c *Collector) After(ctx context.Context, event *zorm.SQLHookEvent) {
+	c.duration.WithLabelValues(event.Operation).Observe(event.Duration.Seconds())
+
+	if event.Operation == zorm.SQLHookOperationTransaction {
+		outcome := "commit"
+		if event.Err != nil {
+			outcome = "rollback"
+		}
+		c.transactions.WithLabelValues(outcome).Inc()
+		return
+	}
+
+	status := "ok"
+	if event.Err != nil {
+		status = "error"
+	}
+	c.operations.WithLabelValues(event.Operation, status).Inc()
+
+	if c.slowSQLMillis > 0 && event.SQL != "" && event.Duration >= time.Duration(c.slowSQLMillis)*time.Millisecond {
+		c.slowQueries.WithLabelValues(event.Operation).Inc()
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package promzorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gitee.com/chunanyong/zorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeDriver 测试用的数据库驱动,exec影响1行,query返回一行一列,包含 fail 的语句返回错误,包含 slow 的语句休眠5毫秒
+// fakeDriver test driver, exec affects 1 row, query returns one row with one column, statements containing fail return an error, statements containing slow sleep 5ms
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct{ query string }
+
+type fakeTx struct{}
+
+type fakeRows struct{ done bool }
+
+var errFake = errors.New("fake error")
+
+func (fakeDriver) Open(name string) (driver.Conn, error)   { return fakeConn{}, nil }
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+func (fakeTx) Commit() error                               { return nil }
+func (fakeTx) Rollback() error                             { return nil }
+func (s *fakeStmt) Close() error                           { return nil }
+func (s *fakeStmt) NumInput() int                          { return -1 }
+func (fakeRows) Columns() []string                         { return []string{"id"} }
+func (fakeRows) Close() error                              { return nil }
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFake
+	}
+	return &fakeRows{}, nil
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFake
+	}
+	if strings.Contains(s.query, "slow") {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	sql.Register("promzorm_fake", fakeDriver{})
+}
+
+func newTestCollector(t *testing.T, name string) (*zorm.DBDao, *Collector) {
+	config := &zorm.DataSourceConfig{
+		Name:          name,
+		DSN:           "fake",
+		DriverName:    "promzorm_fake",
+		Dialect:       "mysql",
+		SlowSQLMillis: 1,
+	}
+	collector, err := NewCollector(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.SQLHooks = append(config.SQLHooks, collector)
+	dbDao, err := zorm.NewDBDao(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = dbDao.CloseDB() })
+	collector.SetDBDao(dbDao)
+	return dbDao, collector
+}
+
+func TestCollector_SeparateSeriesPerDBDao(t *testing.T) {
+	dbDao1, collector1 := newTestCollector(t, "order")
+	dbDao2, collector2 := newTestCollector(t, "user")
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector1, collector2)
+
+	update := func(dbDao *zorm.DBDao, sqlstr string) error {
+		ctx, err := dbDao.BindContextDBConnection(context.Background())
+		if err != nil {
+			return err
+		}
+		_, err = zorm.Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+			return zorm.UpdateFinder(ctx, zorm.NewFinder().Append(sqlstr))
+		})
+		return err
+	}
+	if err := update(dbDao1, "UPDATE t_order SET slow=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := update(dbDao1, "UPDATE t_order SET a=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := update(dbDao2, "UPDATE fail SET a=1"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if v := testutil.ToFloat64(collector1.operations.WithLabelValues("exec", "ok")); v != 2 {
+		t.Errorf("order exec ok = %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(collector2.operations.WithLabelValues("exec", "error")); v != 1 {
+		t.Errorf("user exec error = %v, want 1", v)
+	}
+	if v := testutil.ToFloat64(collector1.transactions.WithLabelValues("commit")); v != 2 {
+		t.Errorf("order commit = %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(collector2.transactions.WithLabelValues("rollback")); v != 1 {
+		t.Errorf("user rollback = %v, want 1", v)
+	}
+	if v := testutil.ToFloat64(collector1.slowQueries.WithLabelValues("exec")); v < 1 {
+		t.Errorf("order slow queries = %v, want >= 1", v)
+	}
+
+	expected := `
+# HELP zorm_db_max_open_connections Maximum number of open connections to the database.
+# TYPE zorm_db_max_open_connections gauge
+zorm_db_max_open_connections{datasource="order",dialect="mysql"} 50
+zorm_db_max_open_connections{datasource="user",dialect="mysql"} 50
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "zorm_db_max_open_connections"); err != nil {
+		t.Error(err)
+	}
+	count, err := testutil.GatherAndCount(registry, "zorm_operations_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("zorm_operations_total series = %d, want 2", count)
+	}
+}
+
+func TestNewCollector_RequireName(t *testing.T) {
+	_, err := NewCollector(&zorm.DataSourceConfig{DSN: "fake", DriverName: "promzorm_fake", Dialect: "mysql"})
+	if err == nil {
+		t.Fatal("NewCollector without Name should return an error")
+	}
+}
@@ -0,0 +1,324 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryMapKeyCase QueryMap返回的map的key的格式
+// QueryMapKeyCase format of the keys of the maps returned by QueryMap
+type QueryMapKeyCase int
+
+const (
+	// QueryMapKeyCaseAsIs 使用数据库返回的列名,默认值
+	// QueryMapKeyCaseAsIs uses the column names returned by the database, the default value
+	QueryMapKeyCaseAsIs QueryMapKeyCase = iota
+	// QueryMapKeyCaseLower 列名转换为小写,例如oracle的 USER_NAME 转换为 user_name
+	// QueryMapKeyCaseLower column names are converted to lower case, e.g. USER_NAME of oracle to user_name
+	QueryMapKeyCaseLower
+	// QueryMapKeyCaseCamel 列名转换为驼峰,例如 user_name 和 USER_NAME 转换为 userName
+	// QueryMapKeyCaseCamel column names are converted to camelCase, e.g. user_name and USER_NAME to userName
+	QueryMapKeyCaseCamel
+)
+
+// QueryMapOptions QueryMap和QueryRowMap的选项,使用BindContextQueryMapOptions绑定到ctx
+// QueryMapOptions options of QueryMap and QueryRowMap, bound to ctx with BindContextQueryMapOptions
+type QueryMapOptions struct {
+	// NormalizeValue 根据列的DatabaseTypeName统一值的类型,不受驱动影响.整数是int64,UNSIGNED BIGINT是uint64,浮点数是float64,DECIMAL和NUMERIC使用FuncDecimalValue,
+	// 日期和时间戳是time.Time,布尔是bool,其他的[]byte转换为string,二进制列(BLOB,BYTEA等)保持[]byte.ICustomDriverValueConver和IValueConver转换的值不处理
+	// NormalizeValue normalizes the value types by the DatabaseTypeName of the column, regardless of the driver. Integers are int64, UNSIGNED BIGINT is uint64, floats are float64, DECIMAL and NUMERIC use FuncDecimalValue,
+	// dates and timestamps are time.Time, booleans are bool, other []byte values are converted to string, binary columns (BLOB, BYTEA etc.) keep []byte. Values converted by ICustomDriverValueConver and IValueConver are not processed
+	NormalizeValue bool
+
+	// KeyCase map的key的格式,默认QueryMapKeyCaseAsIs
+	// KeyCase format of the map keys, QueryMapKeyCaseAsIs by default
+	KeyCase QueryMapKeyCase
+
+	// DecodeJSON 反序列化mysql,postgresql和kingbase原生的JSON和JSONB列,对象是map[string]interface{},数组是[]interface{},默认false和之前一样返回驱动的值
+	// DecodeJSON deserializes the native JSON and JSONB columns of mysql, postgresql and kingbase, objects are map[string]interface{}, arrays are []interface{}, false by default returns the value of the driver as before
+	DecodeJSON bool
+}
+
+// contextQueryMapOptionsValueKey 把QueryMap的选项放到context里使用的key
+// contextQueryMapOptionsValueKey The key used to put the options of QueryMap into the context
+const contextQueryMapOptionsValueKey = wrapContextStringKey("contextQueryMapOptionsValueKey")
+
+// BindContextQueryMapOptions context中绑定QueryMap和QueryRowMap的选项,统一值的类型,key的格式和列的顺序,用于不同数据库返回稳定的JSON
+// BindContextQueryMapOptions binds the options of QueryMap and QueryRowMap to the context, normalizing value types, key format and column order, so that different databases return stable JSON
+func BindContextQueryMapOptions(parent context.Context, options *QueryMapOptions) (context.Context, error) {
+	if parent == nil {
+		return nil, errors.New("->BindContextQueryMapOptions-->context的parent不能为nil")
+	}
+	if options == nil {
+		return nil, errors.New("->BindContextQueryMapOptions-->options不能为nil")
+	}
+	ctx := context.WithValue(parent, contextQueryMapOptionsValueKey, options)
+	return ctx, nil
+}
+
+// getContextQueryMapOptions 获取ctx中绑定的QueryMap选项,没有绑定时返回nil
+// getContextQueryMapOptions gets the QueryMap options bound to ctx, nil when not bound
+func getContextQueryMapOptions(ctx context.Context) *QueryMapOptions {
+	options, _ := ctx.Value(contextQueryMapOptionsValueKey).(*QueryMapOptions)
+	return options
+}
+
+// contextQueryMapColumnsValueKey 接收查询列顺序的key,每次调用QueryMapColumns绑定新的slice,并发的查询互不影响
+// contextQueryMapColumnsValueKey The key receiving the order of the queried columns, QueryMapColumns binds a new slice for each call, concurrent queries do not affect each other
+const contextQueryMapColumnsValueKey = wrapContextStringKey("contextQueryMapColumnsValueKey")
+
+// QueryMapColumns 和QueryMap一样查询,同时返回按照查询列顺序的key.map没有顺序,可以用于保持列的顺序,例如报表的表头
+// QueryMapColumns queries like QueryMap and also returns the keys in the order of the queried columns. Maps have no order, it can be used to keep the column order, e.g. the header of a report
+func QueryMapColumns(ctx context.Context, finder *Finder, page *Page) ([]map[string]interface{}, []string, error) {
+	if ctx == nil {
+		return nil, nil, errors.New("->QueryMapColumns-->context不能为nil")
+	}
+	columns := make([]string, 0)
+	resultMaps, err := QueryMap(context.WithValue(ctx, contextQueryMapColumnsValueKey, &columns), finder, page)
+	return resultMaps, columns, err
+}
+
+// queryMapKey 根据KeyCase转换列名
+// queryMapKey converts the column name by KeyCase
+func queryMapKey(keyCase QueryMapKeyCase, columnName string) string {
+	switch keyCase {
+	case QueryMapKeyCaseLower:
+		return strings.ToLower(columnName)
+	case QueryMapKeyCaseCamel:
+		// 全大写的列名(例如oracle)先转换为小写
+		// Column names in upper case (e.g. oracle) are converted to lower case first
+		if strings.ToUpper(columnName) == columnName {
+			columnName = strings.ToLower(columnName)
+		}
+		var builder strings.Builder
+		upper := false
+		for i := 0; i < len(columnName); i++ {
+			c := columnName[i]
+			if c == '_' {
+				upper = builder.Len() > 0
+				continue
+			}
+			if upper && c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			} else if builder.Len() == 0 && c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			upper = false
+			builder.WriteByte(c)
+		}
+		return builder.String()
+	}
+	return columnName
+}
+
+// 统一值类型时的数据库类型分类
+// Database type categories used when normalizing values
+const (
+	normalizeKindNone = iota
+	normalizeKindInt
+	normalizeKindUint
+	normalizeKindFloat
+	normalizeKindDecimal
+	normalizeKindNumber
+	normalizeKindTime
+	normalizeKindBool
+	normalizeKindBinary
+)
+
+// normalizeDatabaseTypes 数据库类型对应的分类,没有的类型只把[]byte转换为string
+// normalizeDatabaseTypes category of the database types, types not listed only convert []byte to string
+var normalizeDatabaseTypes = map[string]int{
+	"INT": normalizeKindInt, "INT2": normalizeKindInt, "INT4": normalizeKindInt, "INT8": normalizeKindInt, "INTEGER": normalizeKindInt,
+	"TINYINT": normalizeKindInt, "SMALLINT": normalizeKindInt, "MEDIUMINT": normalizeKindInt, "BIGINT": normalizeKindInt,
+	"SERIAL": normalizeKindInt, "SERIAL2": normalizeKindInt, "SERIAL4": normalizeKindInt, "SERIAL8": normalizeKindInt,
+	"SMALLSERIAL": normalizeKindInt, "BIGSERIAL": normalizeKindInt, "AUTONUMBER": normalizeKindInt,
+	"UNSIGNED INT": normalizeKindInt, "UNSIGNED TINYINT": normalizeKindInt, "UNSIGNED SMALLINT": normalizeKindInt, "UNSIGNED MEDIUMINT": normalizeKindInt, "UNSIGNED BIGINT": normalizeKindUint,
+	"FLOAT": normalizeKindFloat, "FLOAT4": normalizeKindFloat, "FLOAT8": normalizeKindFloat, "REAL": normalizeKindFloat, "DOUBLE": normalizeKindFloat, "SINGLE": normalizeKindFloat,
+	"DECIMAL": normalizeKindDecimal, "NUMERIC": normalizeKindDecimal, "DEC": normalizeKindDecimal, "MONEY": normalizeKindDecimal,
+	"NUMBER": normalizeKindNumber,
+	"DATE":   normalizeKindTime, "DATETIME": normalizeKindTime, "DATETIME2": normalizeKindTime, "SMALLDATETIME": normalizeKindTime,
+	"DATETIMEOFFSET": normalizeKindTime, "TIMESTAMP": normalizeKindTime, "TIMESTAMPTZ": normalizeKindTime,
+	"BOOL": normalizeKindBool, "BOOLEAN": normalizeKindBool,
+	"BLOB": normalizeKindBinary, "TINYBLOB": normalizeKindBinary, "MEDIUMBLOB": normalizeKindBinary, "LONGBLOB": normalizeKindBinary,
+	"BINARY": normalizeKindBinary, "VARBINARY": normalizeKindBinary, "BYTEA": normalizeKindBinary, "IMAGE": normalizeKindBinary,
+	"RAW": normalizeKindBinary, "LONG RAW": normalizeKindBinary, "BIT": normalizeKindBinary, "GEOMETRY": normalizeKindBinary,
+}
+
+// normalizeMapValue 根据列的DatabaseTypeName统一QueryMap值的类型
+// normalizeMapValue normalizes the type of the QueryMap value by the DatabaseTypeName of the column
+func normalizeMapValue(ctx context.Context, config *DataSourceConfig, columnType *sql.ColumnType, databaseTypeName string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	kind := normalizeDatabaseTypes[databaseTypeName]
+	if kind == normalizeKindNumber { // oracle等的NUMBER,根据精度判断 | NUMBER of oracle etc., decided by the precision
+		precision, scale, isDecimal := columnType.DecimalSize()
+		if isDecimal || precision > 18 || precision-scale > 18 {
+			kind = normalizeKindDecimal
+		} else if scale > 0 {
+			kind = normalizeKindFloat
+		} else {
+			kind = normalizeKindInt
+		}
+	}
+	switch kind {
+	case normalizeKindInt:
+		return normalizeInt64(value)
+	case normalizeKindUint:
+		return normalizeUint64(value)
+	case normalizeKindFloat:
+		return normalizeFloat64(value)
+	case normalizeKindDecimal:
+		decimalValue := FuncDecimalValue(ctx, config)
+		scanner, ok := decimalValue.(sql.Scanner)
+		if !ok || reflect.TypeOf(value) == reflect.TypeOf(decimalValue).Elem() { // 已经是decimal类型 | Already the decimal type
+			return value, nil
+		}
+		if b, isBytes := value.([]byte); isBytes {
+			value = string(b)
+		}
+		if err := scanner.Scan(value); err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(decimalValue).Elem().Interface(), nil
+	case normalizeKindTime:
+		if _, ok := value.(time.Time); ok {
+			return value, nil
+		}
+		policy := config.TimePolicy
+		if policy == nil {
+			policy = &TimePolicy{}
+		}
+		return policy.parseTime(config.Dialect, value)
+	case normalizeKindBool:
+		return normalizeBool(value)
+	case normalizeKindBinary:
+		return value, nil
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
+
+// normalizeInt64 转换为int64
+// normalizeInt64 converts to int64
+func normalizeInt64(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return valueOf.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if valueOf.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("->normalizeInt64-->%d超出int64的范围", valueOf.Uint())
+		}
+		return int64(valueOf.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return int64(valueOf.Float()), nil
+	}
+	return nil, fmt.Errorf("->normalizeInt64-->不支持的类型:%T", value)
+}
+
+// normalizeUint64 UNSIGNED BIGINT 转换为uint64,超过int64的值不会变成负数
+// normalizeUint64 converts UNSIGNED BIGINT to uint64, values beyond int64 do not become negative
+func normalizeUint64(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return strconv.ParseUint(string(v), 10, 64)
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if valueOf.Int() < 0 {
+			return nil, fmt.Errorf("->normalizeUint64-->%d不能是负数", valueOf.Int())
+		}
+		return uint64(valueOf.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return valueOf.Uint(), nil
+	}
+	return nil, fmt.Errorf("->normalizeUint64-->不支持的类型:%T", value)
+}
+
+// normalizeFloat64 转换为float64
+// normalizeFloat64 converts to float64
+func normalizeFloat64(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(valueOf.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(valueOf.Uint()), nil
+	case reflect.Float32:
+		// 使用字符串转换,避免float32的精度误差,例如0.1
+		// Convert by string to avoid the precision error of float32, e.g. 0.1
+		return strconv.ParseFloat(strconv.FormatFloat(valueOf.Float(), 'g', -1, 32), 64)
+	case reflect.Float64:
+		return valueOf.Float(), nil
+	}
+	return nil, fmt.Errorf("->normalizeFloat64-->不支持的类型:%T", value)
+}
+
+// normalizeBool 转换为bool
+// normalizeBool converts to bool
+func normalizeBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case []byte:
+		return strconv.ParseBool(string(v))
+	case string:
+		return strconv.ParseBool(v)
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return valueOf.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return valueOf.Uint() != 0, nil
+	}
+	return nil, fmt.Errorf("->normalizeBool-->不支持的类型:%T", value)
+}
@@ -0,0 +1,160 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"gitee.com/chunanyong/zorm/decimal"
+)
+
+func Test_queryMapKey(t *testing.T) {
+	tests := []struct {
+		keyCase QueryMapKeyCase
+		column  string
+		want    string
+	}{
+		{QueryMapKeyCaseAsIs, "User_Name", "User_Name"},
+		{QueryMapKeyCaseLower, "USER_NAME", "user_name"},
+		{QueryMapKeyCaseCamel, "user_name", "userName"},
+		{QueryMapKeyCaseCamel, "USER_NAME", "userName"},
+		{QueryMapKeyCaseCamel, "_id", "id"},
+		{QueryMapKeyCaseCamel, "UserName", "userName"},
+		{QueryMapKeyCaseCamel, "user__name_", "userName"},
+	}
+	for _, tt := range tests {
+		if got := queryMapKey(tt.keyCase, tt.column); got != tt.want {
+			t.Errorf("queryMapKey(%d, %s) = %s, want %s", tt.keyCase, tt.column, got, tt.want)
+		}
+	}
+}
+
+func Test_normalizeMapValue(t *testing.T) {
+	ctx := context.Background()
+	config := &DataSourceConfig{Dialect: "mysql"}
+	tests := []struct {
+		databaseTypeName string
+		value            interface{}
+		want             interface{}
+	}{
+		{"BIGINT", []byte("42"), int64(42)},
+		{"INT", 7, int64(7)},
+		{"UNSIGNED INT", uint32(8), int64(8)},
+		{"UNSIGNED BIGINT", []byte("18446744073709551615"), uint64(18446744073709551615)},
+		{"UNSIGNED BIGINT", uint64(9223372036854775808), uint64(9223372036854775808)},
+		{"FLOAT", float32(0.1), 0.1},
+		{"DOUBLE", []byte("1.5"), 1.5},
+		{"DECIMAL", []byte("1.50"), decimal.RequireFromString("1.50")},
+		{"DECIMAL", decimal.RequireFromString("2"), decimal.RequireFromString("2")},
+		{"DATETIME", []byte("2024-01-02 03:04:05"), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"BOOLEAN", []byte("1"), true},
+		{"BOOL", int64(0), false},
+		{"BLOB", []byte{0, 1}, []byte{0, 1}},
+		{"VARCHAR", []byte("a"), "a"},
+		{"TIME", []byte("838:59:59"), "838:59:59"},
+		{"INT", nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := normalizeMapValue(ctx, config, nil, tt.databaseTypeName, tt.value)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeMapValue(%s, %v) = %#v, %v, want %#v", tt.databaseTypeName, tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := normalizeMapValue(ctx, config, nil, "INT", []byte("x")); err == nil {
+		t.Error("expected error for an invalid integer")
+	}
+	if _, err := normalizeMapValue(ctx, config, nil, "BIGINT", uint64(9223372036854775808)); err == nil {
+		t.Error("expected error for an integer beyond int64")
+	}
+}
+
+// scanFakeQueryMapTable QueryMapOptions的结果集,偶数行的user_name和age是NULL
+// scanFakeQueryMapTable result set of QueryMapOptions, user_name and age of even rows are NULL
+var scanFakeQueryMapTable = &scanFakeTable{
+	name:        "queryMap",
+	columns:     []string{"id", "user_name", "age", "is_active", "tags", "created_at"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT", "BOOLEAN", "_TEXT", "DATETIME"},
+	row: func(index int, values []driver.Value) {
+		scanFakeUserValues(index, values)
+		values[3] = true
+		values[4] = []byte(`{a,"b c",NULL}`)
+		values[5] = scanFakeCreatedAt
+	},
+}
+
+func Test_QueryMapOptions(t *testing.T) {
+	if _, err := BindContextQueryMapOptions(context.Background(), nil); err == nil {
+		t.Error("expected error for nil options")
+	}
+	ctx, err := BindContextQueryMapOptions(newScanFakeContext(t, scanFakeQueryMapTable, 2), &QueryMapOptions{NormalizeValue: true, KeyCase: QueryMapKeyCaseCamel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	maps, columns, err := QueryMapColumns(ctx, NewSelectFinder("test_table"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantColumns := []string{"id", "userName", "age", "isActive", "tags", "createdAt"}
+	if !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("columns = %v", columns)
+	}
+	row := maps[0]
+	if len(maps) != 2 || row["userName"] != "name" || row["age"] != int64(1) || row["isActive"] != true || row["tags"] != `{a,"b c",NULL}` || row["createdAt"] != scanFakeCreatedAt {
+		t.Errorf("QueryMap = %#v", row)
+	}
+	if maps[1]["userName"] != nil || maps[1]["age"] != nil {
+		t.Errorf("QueryMap NULL = %#v", maps[1])
+	}
+
+	// QueryRowMap使用相同的选项
+	// QueryRowMap uses the same options
+	ctx, _ = BindContextQueryMapOptions(newScanFakeContext(t, scanFakeQueryMapTable, 1), &QueryMapOptions{KeyCase: QueryMapKeyCaseLower})
+	rowMap, err := QueryRowMap(ctx, NewFinder().Append("SELECT tags FROM test_table"))
+	if err != nil || !reflect.DeepEqual(rowMap, map[string]interface{}{"tags": []byte(`{a,"b c",NULL}`)}) {
+		t.Errorf("QueryRowMap = %#v, %v", rowMap, err)
+	}
+}
+
+func Test_QueryMapColumns_concurrent(t *testing.T) {
+	ctx, err := BindContextQueryMapOptions(newScanFakeContext(t, scanFakeQueryMapTable, 1), &QueryMapOptions{KeyCase: QueryMapKeyCaseCamel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 共享ctx的并发查询,各自返回自己的列 | Concurrent queries sharing ctx return their own columns
+	var wg sync.WaitGroup
+	for _, column := range []string{"id", "age", "tags"} {
+		wg.Add(1)
+		go func(column string) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				_, columns, err := QueryMapColumns(ctx, NewSelectFinder("test_table", column), nil)
+				if err != nil || !reflect.DeepEqual(columns, []string{column}) {
+					t.Errorf("QueryMapColumns(%s) = %v, %v", column, columns, err)
+					return
+				}
+			}
+		}(column)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,7 @@
+module gitee.com/chunanyong/zorm/slogzorm
+
+go 1.23
+
+require gitee.com/chunanyong/zorm v1.8.7
+
+replace gitee.com/chunanyong/zorm => ../
@@ -0,0 +1,160 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package slogzorm zorm的log/slog结构化日志,实现了zorm.ISQLHook接口,记录SQL,参数,耗时,数据源和操作类型
+// 参数按照zorm的脱敏规则处理,独立的module,zorm本身仍然兼容低版本的Go
+//
+//	logger := slog.Default()
+//	dbDaoConfig := zorm.DataSourceConfig{
+//		// ...
+//		SlowSQLMillis: -1, // 不再使用FuncPrintSQL | FuncPrintSQL is no longer used
+//		SQLHooks:      []zorm.ISQLHook{slogzorm.NewHook(logger, slogzorm.WithSlowThreshold(time.Second))},
+//	}
+//	zorm.FuncLogError = slogzorm.FuncLogError(logger)
+//	zorm.FuncLogPanic = slogzorm.FuncLogError(logger)
+//
+// Package slogzorm log/slog structured logging for zorm, implements the zorm.ISQLHook interface and records SQL, args, duration, datasource and operation
+// Args are redacted by the zorm redaction rules, it is a separate module so that zorm itself stays compatible with older Go versions
+package slogzorm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gitee.com/chunanyong/zorm"
+)
+
+// Option 配置hook的选项
+// Option configures the hook
+type Option func(h *hook)
+
+// WithLevel 正常执行时的日志级别,默认slog.LevelDebug.慢SQL使用slog.LevelWarn,错误使用slog.LevelError
+// WithLevel log level of normal executions, slog.LevelDebug by default. Slow SQL uses slog.LevelWarn, errors use slog.LevelError
+func WithLevel(level slog.Level) Option {
+	return func(h *hook) {
+		h.level = level
+	}
+}
+
+// WithSlowThreshold 慢SQL的阈值,默认使用DataSourceConfig.SlowSQLMillis,小于等于0不区分慢SQL
+// WithSlowThreshold threshold of slow SQL, DataSourceConfig.SlowSQLMillis by default, slow SQL is not distinguished when <= 0
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(h *hook) {
+		h.slowThreshold = &threshold
+	}
+}
+
+// hook zorm.ISQLHook 的实现
+// hook implementation of zorm.ISQLHook
+type hook struct {
+	logger        *slog.Logger
+	level         slog.Level
+	slowThreshold *time.Duration
+}
+
+// NewHook 创建结构化日志的钩子,配置到 zorm.DataSourceConfig.SQLHooks,logger为nil时使用slog.Default()
+// NewHook creates the structured logging hook, configure it in zorm.DataSourceConfig.SQLHooks, slog.Default() is used when logger is nil
+func NewHook(logger *slog.Logger, opts ...Option) zorm.ISQLHook {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	h := &hook{logger: logger, level: slog.LevelDebug}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Before 实现zorm.ISQLHook,不做处理
+// Before implements zorm.ISQLHook, does nothing
+func (h *hook) Before(ctx context.Context, event *zorm.SQLHookEvent) context.Context {
+	return ctx
+}
+
+// After 执行完成后记录日志
+// After logs after the execution
+func (h *hook) After(ctx context.Context, event *zorm.SQLHookEvent) {
+	level := h.level
+	if event.Err != nil {
+		level = slog.LevelError
+	} else if threshold := h.threshold(event.Config); threshold > 0 && event.Duration >= threshold {
+		level = slog.LevelWarn
+	}
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 9)
+	attrs = append(attrs, slog.String("operation", event.Operation))
+	if event.Config != nil {
+		datasource := event.Config.Name
+		if datasource == "" {
+			datasource = event.Config.Dialect
+		}
+		attrs = append(attrs, slog.String("datasource", datasource), slog.String("dialect", event.Config.Dialect))
+	}
+	if event.SQL != "" {
+		attrs = append(attrs, slog.String("sql", event.SQL), slog.Any("args", zorm.RedactSQLValues(ctx, event.SQL, event.Args)))
+	}
+	attrs = append(attrs, slog.Duration("duration", event.Duration), slog.Bool("in_transaction", event.InTransaction))
+	if event.RowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64("rows_affected", event.RowsAffected))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.Any("error", event.Err))
+	}
+	h.logger.LogAttrs(ctx, level, "zorm", attrs...)
+}
+
+// threshold 慢SQL的阈值
+// threshold threshold of slow SQL
+func (h *hook) threshold(config *zorm.DataSourceConfig) time.Duration {
+	if h.slowThreshold != nil {
+		return *h.slowThreshold
+	}
+	if config == nil {
+		return 0
+	}
+	return time.Duration(config.SlowSQLMillis) * time.Millisecond
+}
+
+// FuncLogError 返回使用slog记录错误的函数,用于 zorm.FuncLogError 和 zorm.FuncLogPanic
+// FuncLogError returns a function logging errors with slog, for zorm.FuncLogError and zorm.FuncLogPanic
+func FuncLogError(logger *slog.Logger) func(ctx context.Context, err error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context, err error) {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		logger.LogAttrs(ctx, slog.LevelError, "zorm error", slog.Any("error", err))
+	}
+}
+
+// FuncPrintSQL 返回使用slog打印SQL的函数,用于 zorm.FuncPrintSQL,参数已经按照脱敏规则处理.没有数据源和操作类型,推荐使用NewHook
+// FuncPrintSQL returns a function printing SQL with slog, for zorm.FuncPrintSQL, args are already redacted. It has no datasource and operation, NewHook is recommended
+func FuncPrintSQL(logger *slog.Logger) func(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {
+		logger.LogAttrs(ctx, slog.LevelInfo, "zorm", slog.String("sql", sqlstr), slog.Any("args", args), slog.Int64("execSQLMillis", execSQLMillis))
+	}
+}
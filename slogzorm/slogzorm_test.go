@@ -0,0 +1,150 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package slogzorm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"gitee.com/chunanyong/zorm"
+)
+
+// fakeDriver 测试用的数据库驱动,exec影响1行,query返回一行一列,包含 fail 的语句返回错误
+// fakeDriver test driver, exec affects 1 row, query returns one row with one column, statements containing fail return an error
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct{ query string }
+
+type fakeTx struct{}
+
+type fakeRows struct{ done bool }
+
+var errFake = errors.New("fake error")
+
+func (fakeDriver) Open(name string) (driver.Conn, error)   { return fakeConn{}, nil }
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+func (fakeTx) Commit() error                               { return nil }
+func (fakeTx) Rollback() error                             { return nil }
+func (s *fakeStmt) Close() error                           { return nil }
+func (s *fakeStmt) NumInput() int                          { return -1 }
+func (fakeRows) Columns() []string                         { return []string{"id"} }
+func (fakeRows) Close() error                              { return nil }
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFake
+	}
+	return &fakeRows{}, nil
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFake
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+// fakeGlobalTransaction 测试用的分布式事务
+// fakeGlobalTransaction test distributed transaction
+type fakeGlobalTransaction struct{}
+
+func init() {
+	sql.Register("slogzorm_fake", fakeDriver{})
+}
+
+func TestHook_StructuredFieldsAndRedaction(t *testing.T) {
+	zorm.RegisterRedactColumn("pwd")
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	dbDao, err := zorm.NewDBDao(&zorm.DataSourceConfig{
+		Name:          "main",
+		DSN:           "fake",
+		DriverName:    "slogzorm_fake",
+		Dialect:       "mysql",
+		SlowSQLMillis: -1,
+		SQLHooks:      []zorm.ISQLHook{NewHook(logger)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbDao.CloseDB()
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zorm.Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		if _, err := zorm.UpdateFinder(ctx, zorm.NewUpdateFinder("t_user").Append("pwd=? WHERE id=?", "secret", 1)); err != nil {
+			return nil, err
+		}
+		return zorm.UpdateFinder(ctx, zorm.NewUpdateFinder("fail_table").Append("pwd=?", "secret2"))
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "secret2") {
+		t.Errorf("error is not redacted: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("log lines = %d, want 3:\n%s", len(lines), buf.String())
+	}
+	records := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := records[0]
+	if first["level"] != "DEBUG" || first["operation"] != "exec" || first["datasource"] != "main" || first["dialect"] != "mysql" {
+		t.Errorf("unexpected record: %v", first)
+	}
+	if first["sql"] != "UPDATE t_user SET  pwd=? WHERE id=?" || first["rows_affected"] != float64(1) || first["in_transaction"] != true {
+		t.Errorf("unexpected record: %v", first)
+	}
+	if args, _ := first["args"].([]interface{}); len(args) != 2 || args[0] != zorm.RedactMask || args[1] != float64(1) {
+		t.Errorf("args = %v", first["args"])
+	}
+	if records[1]["level"] != "ERROR" || records[1]["error"] == nil || strings.Contains(lines[1], "secret2") {
+		t.Errorf("unexpected record: %s", lines[1])
+	}
+	if records[2]["operation"] != zorm.SQLHookOperationTransaction || records[2]["level"] != "ERROR" {
+		t.Errorf("unexpected record: %s", lines[2])
+	}
+}
@@ -0,0 +1,342 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlowSQLExplain 慢SQL的信息和执行计划,传递给DataSourceConfig.FuncSlowSQLExplain
+// SlowSQLExplain information and execution plan of a slow SQL, passed to DataSourceConfig.FuncSlowSQLExplain
+type SlowSQLExplain struct {
+	// Config 数据源配置
+	// Config data source config
+	Config *DataSourceConfig
+	// SQL 执行的SQL语句
+	// SQL the executed SQL statement
+	SQL string
+	// Args SQL的参数,已经按照脱敏规则处理
+	// Args args of the SQL, already redacted
+	Args []interface{}
+	// ExecSQLMillis SQL执行的毫秒数
+	// ExecSQLMillis milliseconds of the SQL execution
+	ExecSQLMillis int64
+	// Fingerprint SQL指纹,去掉了字面量,参数和注释,用于限流和聚合
+	// Fingerprint SQL fingerprint without literals, parameters and comments, used for rate limiting and aggregation
+	Fingerprint string
+	// ExplainSQL 执行计划的SQL语句,数据库不支持或者语句不需要执行计划时为""
+	// ExplainSQL SQL statement of the execution plan, "" if the database is not supported or the statement needs no plan
+	ExplainSQL string
+	// Plan 执行计划,每行一条记录,多列使用\t分隔
+	// Plan execution plan, one record per line, multiple columns are separated by \t
+	Plan string
+	// Err 获取执行计划的错误
+	// Err error of getting the execution plan
+	Err error
+}
+
+// slowSQLExplainKey 慢SQL限流的key,每个数据源的每个SQL指纹
+// slowSQLExplainKey key of the slow SQL rate limit, each SQL fingerprint of each data source
+type slowSQLExplainKey struct {
+	config      *DataSourceConfig
+	fingerprint string
+}
+
+// slowSQLExplainTimeMap 记录每个SQL指纹最后一次获取执行计划的时间
+// slowSQLExplainTimeMap records the last time the execution plan was got for each SQL fingerprint
+var (
+	slowSQLExplainTimeMap = sync.Map{}
+	// slowSQLExplainKeyCount slowSQLExplainTimeMap中SQL指纹的数量
+	// slowSQLExplainKeyCount number of SQL fingerprints in slowSQLExplainTimeMap
+	slowSQLExplainKeyCount int32
+)
+
+// slowSQLExplainMaxKeys slowSQLExplainTimeMap最多记录的SQL指纹数量,超过时清理超过限流间隔的指纹,仍然超过时不获取新指纹的执行计划
+// slowSQLExplainMaxKeys maximum number of SQL fingerprints in slowSQLExplainTimeMap, fingerprints older than the rate limit interval are evicted when exceeded, new fingerprints get no plan if it is still exceeded
+var slowSQLExplainMaxKeys int32 = 10000
+
+// slowSQLExplainTimeout 获取执行计划的超时时间
+// slowSQLExplainTimeout timeout of getting the execution plan
+var slowSQLExplainTimeout = 10 * time.Second
+
+// slowSQLExplain 慢SQL获取执行计划,按照SQL指纹限流,使用新的连接和相同的参数异步执行,然后调用FuncSlowSQLExplain
+// slowSQLExplain gets the execution plan of a slow SQL, rate limited by SQL fingerprint, executed asynchronously on a new connection with the same args, then calls FuncSlowSQLExplain
+func (dbConnection *dataBaseConnection) slowSQLExplain(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {
+	config := dbConnection.config
+	if config.FuncSlowSQLExplain == nil {
+		return
+	}
+	fingerprint := SQLFingerprint(sqlstr)
+	interval := slowSQLExplainInterval(config)
+	now := time.Now()
+	key := slowSQLExplainKey{config: config, fingerprint: fingerprint}
+	if interval > 0 {
+		last, loaded := slowSQLExplainTimeMap.LoadOrStore(key, now)
+		if !loaded && atomic.AddInt32(&slowSQLExplainKeyCount, 1) > slowSQLExplainMaxKeys && !evictSlowSQLExplainTime(key, now) {
+			return
+		}
+		if loaded {
+			if now.Sub(last.(time.Time)) < time.Duration(interval)*time.Second {
+				return
+			}
+			// 并发时只有一个协程获取执行计划
+			// Only one goroutine gets the execution plan concurrently
+			if !compareAndSwapTime(key, last.(time.Time), now) {
+				return
+			}
+		}
+	}
+
+	// 不使用请求的ctx,避免请求结束后ctx被取消,也不持有数据库连接和事务
+	// The ctx of the request is not used, to avoid it being cancelled after the request ends and holding the connection and transaction
+	ctx = slowSQLExplainContext{parent: ctx}
+	slowSQL := &SlowSQLExplain{
+		Config:        config,
+		SQL:           sqlstr,
+		Args:          RedactSQLValues(ctx, sqlstr, args),
+		ExecSQLMillis: execSQLMillis,
+		Fingerprint:   fingerprint,
+	}
+	prefix := ""
+	if dialect := getDialect(config); dialect != nil {
+		prefix = dialect.ExplainSQLPrefix()
+	}
+	if prefix == "" || !explainSupported(sqlstr) {
+		go config.FuncSlowSQLExplain(ctx, slowSQL)
+		return
+	}
+	slowSQL.ExplainSQL = prefix + sqlstr
+	db := dbConnection.db
+	go func() {
+		explainCtx, cancel := context.WithTimeout(ctx, slowSQLExplainTimeout)
+		defer cancel()
+		slowSQL.Plan, slowSQL.Err = queryExplainPlan(explainCtx, db, slowSQL.ExplainSQL, args)
+		config.FuncSlowSQLExplain(ctx, slowSQL)
+	}()
+}
+
+// slowSQLExplainInterval 同一个SQL指纹获取执行计划的最小间隔秒数,默认60,小于0不限流
+// slowSQLExplainInterval minimum interval in seconds of getting the plan for the same SQL fingerprint, 60 by default, no rate limit when < 0
+func slowSQLExplainInterval(config *DataSourceConfig) int {
+	if config.SlowSQLExplainIntervalSecond == 0 {
+		return 60
+	}
+	return config.SlowSQLExplainIntervalSecond
+}
+
+// slowSQLExplainContext 传给FuncSlowSQLExplain的ctx,没有请求ctx的取消和超时,不包含zorm的数据库连接和事务选项,其他值从请求ctx获取,例如链路追踪的span
+// slowSQLExplainContext ctx passed to FuncSlowSQLExplain, without the cancellation and deadline of the request ctx and without the zorm connection and transaction options, other values come from the request ctx, e.g. the tracing span
+type slowSQLExplainContext struct {
+	parent context.Context
+}
+
+func (slowSQLExplainContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (slowSQLExplainContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (slowSQLExplainContext) Err() error {
+	return nil
+}
+
+func (c slowSQLExplainContext) Value(key interface{}) interface{} {
+	if key == contextDBConnectionValueKey || key == contextTxOptionsKey {
+		return nil
+	}
+	return c.parent.Value(key)
+}
+
+// slowSQLExplainMutex 用于比较并更新限流时间
+// slowSQLExplainMutex used to compare and update the rate limit time
+var slowSQLExplainMutex sync.Mutex
+
+// evictSlowSQLExplainTime 清理超过限流间隔的SQL指纹,仍然超过slowSQLExplainMaxKeys时删除新增的key并返回false
+// evictSlowSQLExplainTime evicts the SQL fingerprints older than the rate limit interval, removes the new key and returns false if slowSQLExplainMaxKeys is still exceeded
+func evictSlowSQLExplainTime(newKey slowSQLExplainKey, now time.Time) bool {
+	slowSQLExplainMutex.Lock()
+	defer slowSQLExplainMutex.Unlock()
+	slowSQLExplainTimeMap.Range(func(key, value interface{}) bool {
+		explainKey := key.(slowSQLExplainKey)
+		if explainKey != newKey && now.Sub(value.(time.Time)) >= time.Duration(slowSQLExplainInterval(explainKey.config))*time.Second {
+			slowSQLExplainTimeMap.Delete(key)
+			atomic.AddInt32(&slowSQLExplainKeyCount, -1)
+		}
+		return true
+	})
+	if atomic.LoadInt32(&slowSQLExplainKeyCount) <= slowSQLExplainMaxKeys {
+		return true
+	}
+	slowSQLExplainTimeMap.Delete(newKey)
+	atomic.AddInt32(&slowSQLExplainKeyCount, -1)
+	return false
+}
+
+// compareAndSwapTime 如果key的值仍然是oldTime,更新为newTime
+// compareAndSwapTime updates the value of key to newTime if it is still oldTime
+func compareAndSwapTime(key slowSQLExplainKey, oldTime time.Time, newTime time.Time) bool {
+	slowSQLExplainMutex.Lock()
+	defer slowSQLExplainMutex.Unlock()
+	current, ok := slowSQLExplainTimeMap.Load(key)
+	if !ok || !current.(time.Time).Equal(oldTime) {
+		return false
+	}
+	slowSQLExplainTimeMap.Store(key, newTime)
+	return true
+}
+
+// explainSupported 只有 SELECT,UPDATE,DELETE,WITH 语句获取执行计划
+// explainSupported only SELECT, UPDATE, DELETE, WITH statements get the execution plan
+func explainSupported(sqlstr string) bool {
+	word, _, _, err := firstOneWord(0, []byte(sqlstr))
+	if err != nil {
+		return false
+	}
+	switch strings.ToUpper(word) {
+	case "SELECT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
+
+// queryExplainPlan 使用db新的连接执行 explainSQL,每行一条记录,多列使用\t分隔
+// queryExplainPlan executes explainSQL on a new connection of db, one record per line, multiple columns are separated by \t
+func queryExplainPlan(ctx context.Context, db *sql.DB, explainSQL string, args []interface{}) (string, error) {
+	rows, err := db.QueryContext(ctx, explainSQL, args...)
+	if err != nil {
+		return "", fmt.Errorf("->queryExplainPlan-->db.QueryContext执行错误:%w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("->queryExplainPlan-->rows.Columns错误:%w", err)
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var planBuilder strings.Builder
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return "", fmt.Errorf("->queryExplainPlan-->rows.Scan错误:%w", err)
+		}
+		if planBuilder.Len() > 0 {
+			planBuilder.WriteByte('\n')
+		}
+		for i := range values {
+			if i > 0 {
+				planBuilder.WriteByte('\t')
+			}
+			planBuilder.WriteString(values[i].String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return "", fmt.Errorf("->queryExplainPlan-->rows.Err错误:%w", err)
+	}
+	return planBuilder.String(), nil
+}
+
+// SQLFingerprint SQL指纹,去掉注释,字符串和数字字面量,参数统一为?,IN列表合并为(?),只在单词之间保留空格,转为小写.返回指纹文本的fnv64a哈希,16位十六进制
+// SQLFingerprint SQL fingerprint, comments removed, string and number literals and parameters replaced by ?, IN lists merged to (?), whitespace only kept between words, lower case. Returns the fnv64a hash of the fingerprint text as 16 hex digits
+func SQLFingerprint(sqlstr string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalizeSQL(sqlstr)))
+	fingerprint := strconv.FormatUint(h.Sum64(), 16)
+	return strings.Repeat("0", 16-len(fingerprint)) + fingerprint
+}
+
+// normalizeSQL SQL指纹的文本
+// normalizeSQL text of the SQL fingerprint
+func normalizeSQL(sqlstr string) string {
+	sc := &sqlScanner{sqlStr: sqlstr, sqlLen: len(sqlstr)}
+	var builder strings.Builder
+	builder.Grow(len(sqlstr))
+	// space 前面是否有空白,lastWord 前一个是否是单词或者?,只有两个单词之间保留一个空格
+	// space whether there is whitespace before, lastWord whether the previous token is a word or ?, only one space is kept between two words
+	space := false
+	lastWord := false
+	write := func(s string, word bool) {
+		if space && lastWord && word {
+			builder.WriteByte(' ')
+		}
+		space = false
+		lastWord = word
+		builder.WriteString(s)
+	}
+	for sc.index < sc.sqlLen {
+		c := sqlstr[sc.index]
+		switch {
+		case c == '\'':
+			sc.skipString()
+			write("?", true)
+		case sc.skipComment():
+			space = true
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			space = true
+			sc.index++
+		case c >= '0' && c <= '9', (c == '$' || c == ':' || c == '@') && sc.index+1 < sc.sqlLen && (isIdentChar(sqlstr[sc.index+1])):
+			// 数字和占位符 $1 :1 @p1 ,:: 类型转换不处理
+			// Numbers and placeholders $1 :1 @p1 , :: type casts are not handled
+			if c == ':' && sc.index > 0 && sqlstr[sc.index-1] == ':' {
+				write(":", false)
+				sc.index++
+				continue
+			}
+			sc.index++
+			for sc.index < sc.sqlLen && (isIdentChar(sqlstr[sc.index]) || sqlstr[sc.index] == '.') {
+				sc.index++
+			}
+			write("?", true)
+		case isIdentChar(c):
+			start := sc.index
+			for sc.index < sc.sqlLen && isIdentChar(sqlstr[sc.index]) {
+				sc.index++
+			}
+			write(strings.ToLower(sqlstr[start:sc.index]), true)
+		case c == '?':
+			write("?", true)
+			sc.index++
+		default:
+			write(string(c), false)
+			sc.index++
+		}
+	}
+	normalized := builder.String()
+	// 合并IN列表
+	// Merge IN lists
+	for {
+		merged := strings.Replace(normalized, "?,?", "?", -1)
+		if merged == normalized {
+			break
+		}
+		normalized = merged
+	}
+	return normalized
+}
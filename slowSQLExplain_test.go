@@ -0,0 +1,252 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// explainFakeDriver 测试用的数据库驱动,包含 slow 的语句休眠5毫秒,EXPLAIN 语句返回两列的执行计划
+// explainFakeDriver test driver, statements containing slow sleep 5ms, EXPLAIN statements return a two-column plan
+type explainFakeDriver struct{}
+
+type explainFakeConn struct{}
+
+type explainFakeStmt struct{ query string }
+
+type explainFakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (explainFakeDriver) Open(name string) (driver.Conn, error) { return explainFakeConn{}, nil }
+func (explainFakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &explainFakeStmt{query: query}, nil
+}
+func (explainFakeConn) Close() error              { return nil }
+func (explainFakeConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+func (s *explainFakeStmt) Close() error           { return nil }
+func (s *explainFakeStmt) NumInput() int          { return -1 }
+func (r *explainFakeRows) Columns() []string      { return r.columns }
+func (r *explainFakeRows) Close() error           { return nil }
+
+func (s *explainFakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (s *explainFakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.HasPrefix(s.query, "EXPLAIN QUERY PLAN ") {
+		return &explainFakeRows{columns: []string{"id", "detail"}, values: [][]driver.Value{{int64(2), "SCAN t_user"}, {int64(3), "USE TEMP B-TREE"}}}, nil
+	}
+	if strings.Contains(s.query, "slow") {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return &explainFakeRows{columns: []string{"id"}, values: [][]driver.Value{{int64(1)}}}, nil
+}
+
+func (r *explainFakeRows) Next(dest []driver.Value) error {
+	if len(r.values) < 1 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("zorm_explain_fake", explainFakeDriver{})
+}
+
+func Test_slowSQLExplain(t *testing.T) {
+	slowSQLs := make(chan *SlowSQLExplain, 10)
+	dbDao, err := NewDBDao(&DataSourceConfig{
+		DSN:           "fake",
+		DriverName:    "zorm_explain_fake",
+		Dialect:       "sqlite",
+		SlowSQLMillis: 1,
+		FuncSlowSQLExplain: func(ctx context.Context, slowSQL *SlowSQLExplain) {
+			slowSQLs <- slowSQL
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbDao.CloseDB()
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	printSQL := FuncPrintSQL
+	FuncPrintSQL = func(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {}
+	defer func() { FuncPrintSQL = printSQL }()
+
+	id := 0
+	// 相同指纹的第二条语句被限流 | The second statement with the same fingerprint is rate limited
+	for _, v := range []int{1, 2} {
+		if _, err = QueryRow(ctx, NewSelectFinder("t_user", "id").Append("WHERE slow=?", v), &id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 快速的语句不处理 | Fast statements are not handled
+	if _, err = QueryRow(ctx, NewSelectFinder("t_user", "id").Append("WHERE fast=?", 1), &id); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case slowSQL := <-slowSQLs:
+		if slowSQL.ExplainSQL != "EXPLAIN QUERY PLAN SELECT id FROM t_user WHERE slow=?" {
+			t.Errorf("ExplainSQL = %q", slowSQL.ExplainSQL)
+		}
+		if slowSQL.Err != nil || slowSQL.Plan != "2\tSCAN t_user\n3\tUSE TEMP B-TREE" {
+			t.Errorf("Plan = %q, Err = %v", slowSQL.Plan, slowSQL.Err)
+		}
+		if slowSQL.ExecSQLMillis < 1 || slowSQL.Fingerprint != SQLFingerprint("select id from t_user where slow = 2") {
+			t.Errorf("unexpected slow SQL %+v", slowSQL)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("FuncSlowSQLExplain is not called")
+	}
+	select {
+	case slowSQL := <-slowSQLs:
+		t.Errorf("unexpected slow SQL %s", slowSQL.SQL)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func Test_slowSQLExplain_evictAndContext(t *testing.T) {
+	type userKey struct{}
+	slowSQLs := make(chan *SlowSQLExplain, 10)
+	contexts := make(chan context.Context, 10)
+	config := &DataSourceConfig{
+		DSN:           "fake",
+		DriverName:    "zorm_explain_fake",
+		Dialect:       "sqlite",
+		SlowSQLMillis: 1,
+		FuncSlowSQLExplain: func(ctx context.Context, slowSQL *SlowSQLExplain) {
+			contexts <- ctx
+			slowSQLs <- slowSQL
+		},
+	}
+	dbDao, err := NewDBDao(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbDao.CloseDB()
+	requestCtx, cancel := context.WithCancel(context.WithValue(context.Background(), userKey{}, "trace"))
+	ctx, err := dbDao.BindContextDBConnection(requestCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printSQL := FuncPrintSQL
+	FuncPrintSQL = func(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {}
+	maxKeys := slowSQLExplainMaxKeys
+	slowSQLExplainMaxKeys = 1
+	defer func() {
+		FuncPrintSQL = printSQL
+		slowSQLExplainMaxKeys = maxKeys
+	}()
+	slowSQLExplainMutex.Lock()
+	slowSQLExplainTimeMap.Range(func(key, value interface{}) bool {
+		slowSQLExplainTimeMap.Delete(key)
+		return true
+	})
+	atomic.StoreInt32(&slowSQLExplainKeyCount, 0)
+	slowSQLExplainMutex.Unlock()
+
+	id := 0
+	queryRow := func(condition string) {
+		if _, err := QueryRow(ctx, NewSelectFinder("t_user", "id").Append(condition, 1), &id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第一个指纹获取执行计划 | The first fingerprint gets the plan
+	queryRow("WHERE slow=?")
+	select {
+	case explainCtx := <-contexts:
+		<-slowSQLs
+		cancel()
+		if explainCtx.Err() != nil || explainCtx.Done() != nil {
+			t.Errorf("ctx should not be cancelled with the request, Err = %v", explainCtx.Err())
+		}
+		if explainCtx.Value(contextDBConnectionValueKey) != nil {
+			t.Error("ctx should not hold the db connection")
+		}
+		if explainCtx.Value(userKey{}) != "trace" {
+			t.Errorf("ctx value = %v", explainCtx.Value(userKey{}))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("FuncSlowSQLExplain is not called")
+	}
+	if ctx, err = dbDao.BindContextDBConnection(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过上限并且没有过期的指纹时,新的指纹不获取执行计划 | New fingerprints get no plan when the limit is exceeded without expired fingerprints
+	queryRow("WHERE slow_name=?")
+	select {
+	case slowSQL := <-slowSQLs:
+		t.Errorf("unexpected slow SQL %s", slowSQL.SQL)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if count := atomic.LoadInt32(&slowSQLExplainKeyCount); count != 1 {
+		t.Errorf("slowSQLExplainKeyCount = %d", count)
+	}
+
+	// 过期的指纹被清理 | Expired fingerprints are evicted
+	slowSQLExplainTimeMap.Range(func(key, value interface{}) bool {
+		slowSQLExplainTimeMap.Store(key, time.Now().Add(-time.Hour))
+		return true
+	})
+	queryRow("WHERE slow_name=?")
+	select {
+	case slowSQL := <-slowSQLs:
+		<-contexts
+		if slowSQL.SQL != "SELECT id FROM t_user WHERE slow_name=?" {
+			t.Errorf("SQL = %q", slowSQL.SQL)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("FuncSlowSQLExplain is not called")
+	}
+	if count := atomic.LoadInt32(&slowSQLExplainKeyCount); count != 1 {
+		t.Errorf("slowSQLExplainKeyCount = %d", count)
+	}
+}
+
+func Test_normalizeSQL(t *testing.T) {
+	tests := []struct {
+		sqlstr string
+		want   string
+	}{
+		{sqlstr: "SELECT  id FROM t WHERE name='a''b' AND age>18 /* c */ AND id IN (?,?,?)", want: "select id from t where name=? and age>? and id in(?)"},
+		{sqlstr: "select id from t where name=$1 and v=$2::int and id in ($3, $4) /*app='x'*/", want: "select id from t where name=? and v=?::int and id in(?)"},
+		{sqlstr: "UPDATE t SET a=:1 WHERE b=@p2 -- x", want: "update t set a=? where b=?"},
+	}
+	for _, tt := range tests {
+		if got := normalizeSQL(tt.sqlstr); got != tt.want {
+			t.Errorf("normalizeSQL(%q) = %q, want %q", tt.sqlstr, got, tt.want)
+		}
+	}
+}
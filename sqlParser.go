@@ -18,6 +18,11 @@
 
 package zorm
 
+import (
+	"fmt"
+	"strings"
+)
+
 // sqlSpan 表示 SQL 中某个片段的位置范围 (左闭右开)
 // sqlSpan represents the position range of a SQL fragment (left-closed, right-open)
 type sqlSpan struct {
@@ -28,10 +33,8 @@ type sqlSpan struct {
 // sqlPart 表示 SQL 语句的各个子句片段
 // sqlPart represents the fragments of each clause in a SQL statement
 type sqlPart struct {
-	// @TODO: With 子句暂不处理 / WITH clause is not handled for now
-	// With sqlSpan   // WITH 子句 / WITH clause
-
-	Select    sqlSpan // SELECT 子句 / SELECT clause
+	With      sqlSpan // WITH 子句, 包含 RECURSIVE 和所有的 CTE 定义, 没有 WITH 时为空 / WITH clause, including RECURSIVE and all CTE definitions, empty without WITH
+	Select    sqlSpan // SELECT 子句, 有 WITH 时从主语句开始 / SELECT clause, starts at the main statement with WITH
 	From      sqlSpan // FROM 子句 / FROM clause
 	Where     sqlSpan // WHERE 子句 / WHERE clause
 	GroupBy   sqlSpan // GROUP BY 子句 / GROUP BY clause
@@ -49,6 +52,8 @@ type sqlScanner struct {
 	index  int    // 当前扫描位置 / Current scan position
 	sqlLen int    // SQL 字符串总长度 / Total length of SQL string
 	depth  int    // 括号嵌套深度, 用于处理子查询 / Parentheses nesting depth for handling subqueries
+	// 标准SQL的字符串, \ 不是转义字符, 例如 postgresql 的 'C:\' / Standard SQL strings, \ is not an escape character, e.g. 'C:\' of postgresql
+	noBackslashEscape bool
 }
 
 // ================= 基础能力 / Basic Capabilities =================
@@ -62,18 +67,18 @@ func isIdentChar(c byte) bool {
 		c == '_'
 }
 
-// skipString 跳过字符串字面量 (支持单引号 ' 和双引号 ")
-// 处理转义: \' 和 "" (SQL 标准双单引号转义)
-// skipString skips string literals (supports single quote ' and double quote ")
-// Handles escapes: \' and "" (SQL standard double single-quote escape)
-func (sc *sqlScanner) skipString() {
+// skipString 跳过字符串字面量 (支持单引号 ' 和双引号 "), 返回字符串是否闭合
+// 处理转义: \' (noBackslashEscape为false时) 和 "" (SQL 标准双单引号转义)
+// skipString skips string literals (supports single quote ' and double quote "), returns whether the string is closed
+// Handles escapes: \' (when noBackslashEscape is false) and "" (SQL standard double single-quote escape)
+func (sc *sqlScanner) skipString() bool {
 	quote := sc.sqlStr[sc.index] // 记录字符串的引号类型 / Record the quote type of the string
 	sc.index++                   // 跳过开引号 / Skip opening quote
 
 	for sc.index < sc.sqlLen {
 		// \ 转义: 处理 \' 这种情况
 		// Backslash escape: handles cases like \'
-		if sc.sqlStr[sc.index] == '\\' && sc.index+1 < sc.sqlLen {
+		if !sc.noBackslashEscape && sc.sqlStr[sc.index] == '\\' && sc.index+1 < sc.sqlLen {
 			sc.index += 2 // 跳过转义字符和下一个字符 / Skip escape character and next character
 			continue
 		}
@@ -85,13 +90,14 @@ func (sc *sqlScanner) skipString() {
 				sc.index += 2 // 跳过两个连续的引号 / Skip two consecutive quotes
 				continue
 			}
-			sc.index++ // 跳过闭引号 / Skip closing quote
-			return     // 字符串结束 / End of string
+			sc.index++  // 跳过闭引号 / Skip closing quote
+			return true // 字符串结束 / End of string
 		}
 
 		sc.index++ // 继续扫描下一个字符 / Continue to scan next character
 	}
 	// 字符串未闭合也会正常退出, 不会报错 / Exits normally even if string is unclosed, no error
+	return false
 }
 
 // skipComment 跳过注释, 返回是否成功跳过
@@ -219,7 +225,26 @@ func parseSQL(sqlStr string) sqlPart {
 	sc := &sqlScanner{sqlStr: sqlStr, sqlLen: len(sqlStr)}
 	var parts sqlPart
 	current := &parts.Select // 当前正在解析的子句, 默认为 SELECT / Current clause being parsed, defaults to SELECT
-	current.Start = 0        // SELECT 始终从位置 0 开始 / SELECT always starts at position 0
+	current.Start = 0        // 没有 WITH 时 SELECT 从位置 0 开始 / Without WITH, SELECT starts at position 0
+
+	// 0. WITH 子句: 跳过开头的空白和注释, 语句以 WITH 开头时, 到最外层的主语句之前都是 WITH 子句
+	// WITH clause: skip leading whitespace and comments, when the statement starts with WITH, everything before the outermost main statement is the WITH clause
+	for sc.index < sc.sqlLen {
+		c := sqlStr[sc.index]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			sc.index++
+			continue
+		}
+		if (c == '-' || c == '/') && sc.skipComment() {
+			continue
+		}
+		break
+	}
+	if matchKeyword(sqlStr, sc.index, "with") {
+		parts.With.Start = sc.index
+		current = &parts.With
+		sc.index += 4
+	}
 
 	for sc.index < sc.sqlLen {
 		c := sqlStr[sc.index]
@@ -255,7 +280,20 @@ func parseSQL(sqlStr string) sqlPart {
 			continue
 		}
 
-		// 4. 只在最外层 (非子查询内) 解析关键字
+		// 4. WITH 子句中, CTE 的定义都在括号内, 最外层的 SELECT/INSERT/UPDATE/DELETE 是主语句的开始
+		// In the WITH clause, CTE definitions are inside parentheses, the outermost SELECT/INSERT/UPDATE/DELETE starts the main statement
+		if current == &parts.With {
+			if sc.depth == 0 && (matchKeyword(sc.sqlStr, sc.index, "select") || matchKeyword(sc.sqlStr, sc.index, "insert") ||
+				matchKeyword(sc.sqlStr, sc.index, "update") || matchKeyword(sc.sqlStr, sc.index, "delete")) {
+				current.End = sc.index
+				parts.Select.Start = sc.index
+				current = &parts.Select
+			}
+			sc.index++
+			continue
+		}
+
+		// 5. 只在最外层 (非子查询内) 解析关键字
 		// Only parse keywords at the outermost level (not inside subqueries)
 		if sc.depth == 0 {
 			switch c {
@@ -342,3 +380,249 @@ func parseSQL(sqlStr string) sqlPart {
 
 	return parts
 }
+
+// ================= 注入检查 / Injection Check =================
+
+// InjectionRule SQL注入检查的规则,可以使用 | 组合多个规则
+// InjectionRule rules of the SQL injection check, several rules can be combined with |
+type InjectionRule int
+
+const (
+	// InjectionRuleStackedStatement 禁止 ; 分隔的多条语句,语句末尾的 ; 不检查
+	// InjectionRuleStackedStatement forbids several statements separated by ; , the trailing ; is not checked
+	InjectionRuleStackedStatement InjectionRule = 1 << iota
+	// InjectionRuleComment 禁止 -- 和 /* */ 注释,用于截断后面的条件. /*+ */ 格式的hint不检查
+	// InjectionRuleComment forbids -- and /* */ comments, which truncate the following conditions. Hints like /*+ */ are not checked
+	InjectionRuleComment
+	// InjectionRuleTautology 禁止 OR 后面是常量的恒真条件,例如 OR 1=1 , OR 'a'='a' , OR TRUE
+	// InjectionRuleTautology forbids constant conditions after OR, e.g. OR 1=1 , OR 'a'='a' , OR TRUE
+	InjectionRuleTautology
+	// InjectionRuleUnclosedString 禁止没有闭合的字符串,通常是拼接的参数中包含引号
+	// InjectionRuleUnclosedString forbids unclosed strings, usually the concatenated parameter contains a quote
+	InjectionRuleUnclosedString
+	// InjectionRuleStringLiteral 禁止所有的单引号字符串,只能使用 ? 占位符,和之前版本的单引号检查一致
+	// InjectionRuleStringLiteral forbids all single-quoted strings, only ? placeholders are allowed, the same as the single quote check of previous versions
+	InjectionRuleStringLiteral
+	// InjectionRuleQuoteBreakout 禁止拼接的参数闭合引号的特征: 字符串后面紧跟 UNION,或者字符串后面紧跟 OR 列和字符串的比较,或者 WHERE/AND/ON/HAVING 后面是字符串常量条件,
+	// 例如 name='x' UNION SELECT pwd FROM users WHERE 'a'='a' , name='x' OR name LIKE '%'
+	// 静态SQL中 status='A' UNION SELECT ... 和 status='A' OR status='B' 也会被禁止,可以使用 status IN ('A','B') 或者设置 finder.InjectionCheck = false
+	// InjectionRuleQuoteBreakout forbids the signs of a concatenated parameter closing the quote: a string followed by UNION, a string followed by OR comparing a column with a string,
+	// or a constant string condition after WHERE/AND/ON/HAVING, e.g. name='x' UNION SELECT pwd FROM users WHERE 'a'='a' , name='x' OR name LIKE '%'
+	// status='A' UNION SELECT ... and status='A' OR status='B' in static SQL are also forbidden, use status IN ('A','B') or set finder.InjectionCheck = false
+	InjectionRuleQuoteBreakout
+)
+
+// FinderInjectionRules Finder.InjectionCheck为true时使用的规则,默认不禁止静态SQL中的字符串,例如 status='A'
+// 词法检查不能发现所有的注入,字符串参数请使用 ? 占位符,需要更严格的检查可以增加 InjectionRuleStringLiteral
+// FinderInjectionRules rules used when Finder.InjectionCheck is true, strings in static SQL such as status='A' are allowed by default
+// The lexical check cannot find every injection, use ? placeholders for string parameters, add InjectionRuleStringLiteral for a stricter check
+var FinderInjectionRules = InjectionRuleStackedStatement | InjectionRuleComment | InjectionRuleTautology | InjectionRuleUnclosedString | InjectionRuleQuoteBreakout
+
+// CheckSQLInjection 使用sqlScanner词法检查SQL注入,返回的错误包含违反规则的位置(字节偏移).字符串中的 \ 是转义字符,和mysql一致
+// CheckSQLInjection checks SQL injection lexically with sqlScanner, the returned error contains the offending position (byte offset). \ is an escape character in strings, the same as mysql
+func CheckSQLInjection(sqlStr string, rules InjectionRule) error {
+	return CheckDialectSQLInjection(nil, sqlStr, rules)
+}
+
+// CheckDialectSQLInjection 按照数据库方言检查SQL注入,方言的SupportBackslashEscape()决定字符串中的 \ 是否是转义字符,dialect为nil时和CheckSQLInjection一致
+// CheckDialectSQLInjection checks SQL injection according to the database dialect, SupportBackslashEscape() of the dialect decides whether \ is an escape character in strings, the same as CheckSQLInjection when dialect is nil
+func CheckDialectSQLInjection(dialect IDialect, sqlStr string, rules InjectionRule) error {
+	sc := &sqlScanner{sqlStr: sqlStr, sqlLen: len(sqlStr)}
+	if dialect != nil {
+		sc.noBackslashEscape = !dialect.SupportBackslashEscape()
+	}
+	// 上一个单引号字符串结束的位置
+	// End position of the last single-quoted string
+	lastStringEnd := -1
+	for sc.index < sc.sqlLen {
+		c := sqlStr[sc.index]
+		start := sc.index
+		switch c {
+		case '\'', '"':
+			if c == '\'' && rules&InjectionRuleStringLiteral != 0 {
+				return injectionError(sqlStr, start, "字符串参数请使用问号占位符")
+			}
+			if c == '\'' && rules&InjectionRuleQuoteBreakout != 0 && isAfterConditionKeyword(sqlStr, start) && isConstantCondition(sqlStr, start, sc.noBackslashEscape) {
+				return injectionError(sqlStr, start, "字符串常量条件")
+			}
+			if !sc.skipString() && rules&InjectionRuleUnclosedString != 0 {
+				return injectionError(sqlStr, start, "字符串没有闭合")
+			}
+			if c == '\'' {
+				lastStringEnd = sc.index
+			}
+			continue
+		case '-', '/':
+			// /*+ */ 是数据库的hint
+			// /*+ */ is a database hint
+			isHint := c == '/' && start+2 < sc.sqlLen && sqlStr[start+2] == '+'
+			if sc.skipComment() {
+				if !isHint && rules&InjectionRuleComment != 0 {
+					return injectionError(sqlStr, start, "注释")
+				}
+				continue
+			}
+		case ';':
+			if rules&InjectionRuleStackedStatement != 0 && strings.TrimSpace(sqlStr[start+1:]) != "" {
+				return injectionError(sqlStr, start, "多条语句")
+			}
+		case 'o', 'O':
+			if rules&InjectionRuleTautology != 0 && matchKeyword(sqlStr, start, "or") && isConstantCondition(sqlStr, start+2, sc.noBackslashEscape) {
+				return injectionError(sqlStr, start, "恒真条件")
+			}
+			// 字符串后面紧跟 OR 列和字符串的比较,例如 name='x' OR name LIKE '%'
+			// A string followed by OR comparing a column with a string, e.g. name='x' OR name LIKE '%'
+			if rules&InjectionRuleQuoteBreakout != 0 && lastStringEnd >= 0 && matchKeyword(sqlStr, start, "or") && skipSpaceAndCloseParen(sqlStr, lastStringEnd) == start && isColumnStringCondition(sqlStr, start+2) {
+				return injectionError(sqlStr, start, "字符串后的OR条件")
+			}
+		case 'u', 'U':
+			// 字符串后面紧跟UNION,例如 name='x' UNION SELECT
+			// A string followed by UNION, e.g. name='x' UNION SELECT
+			if rules&InjectionRuleQuoteBreakout != 0 && lastStringEnd >= 0 && matchKeyword(sqlStr, start, "union") && skipSpaceAndCloseParen(sqlStr, lastStringEnd) == start {
+				return injectionError(sqlStr, start, "字符串后的UNION")
+			}
+		}
+		sc.index++
+	}
+	return nil
+}
+
+// injectionError 注入检查的错误,包含位置和附近的SQL
+func injectionError(sqlStr string, index int, reason string) error {
+	end := index + 20
+	if end > len(sqlStr) {
+		end = len(sqlStr)
+	}
+	return fmt.Errorf("->CheckSQLInjection-->SQL语句第%d个字符存在注入风险(%s):%s", index, reason, sqlStr[index:end])
+}
+
+// isConstantCondition 判断 i 位置开始的条件是否是常量,例如 1=1 , 'a'='a' , TRUE , (2>1)
+func isConstantCondition(sqlStr string, i int, noBackslashEscape bool) bool {
+	// 第一个操作数必须是常量
+	// The first operand must be a constant
+	i = skipSpaceAndParen(sqlStr, i)
+	i, ok := skipConstant(sqlStr, i, noBackslashEscape)
+	if !ok {
+		return false
+	}
+	i = skipSpace(sqlStr, i)
+	// 只有一个常量,例如 OR 1 , OR TRUE
+	// Only one constant, e.g. OR 1 , OR TRUE
+	if i >= len(sqlStr) || sqlStr[i] == ')' || sqlStr[i] == ';' || sqlStr[i] == '-' || sqlStr[i] == '/' || isIdentChar(sqlStr[i]) && !matchKeyword(sqlStr, i, "like") && !matchKeyword(sqlStr, i, "is") {
+		return true
+	}
+	// 比较运算符
+	// Comparison operator
+	switch {
+	case matchKeyword(sqlStr, i, "like"):
+		i += 4
+	case matchKeyword(sqlStr, i, "is"):
+		i += 2
+	default:
+		start := i
+		for i < len(sqlStr) && strings.IndexByte("=<>!", sqlStr[i]) >= 0 {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	// 第二个操作数也是常量
+	// The second operand is also a constant
+	i = skipSpaceAndParen(sqlStr, i)
+	_, ok = skipConstant(sqlStr, i, noBackslashEscape)
+	return ok
+}
+
+// isColumnStringCondition 判断 i 位置开始的条件是否是列和字符串的比较,例如 name='y' , t.name LIKE '%'
+func isColumnStringCondition(sqlStr string, i int) bool {
+	// 列名,可以包含表别名
+	// Column name, the table alias is allowed
+	i = skipSpaceAndParen(sqlStr, i)
+	start := i
+	for i < len(sqlStr) && (isIdentChar(sqlStr[i]) || sqlStr[i] == '.') {
+		i++
+	}
+	if i == start {
+		return false
+	}
+	i = skipSpace(sqlStr, i)
+	// 比较运算符
+	// Comparison operator
+	if matchKeyword(sqlStr, i, "like") {
+		i += 4
+	} else {
+		start = i
+		for i < len(sqlStr) && strings.IndexByte("=<>!", sqlStr[i]) >= 0 {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	i = skipSpaceAndParen(sqlStr, i)
+	return i < len(sqlStr) && sqlStr[i] == '\''
+}
+
+// skipConstant 跳过数字,字符串,TRUE,NULL 常量,返回常量之后的位置和是否是常量
+func skipConstant(sqlStr string, i int, noBackslashEscape bool) (int, bool) {
+	if i >= len(sqlStr) {
+		return i, false
+	}
+	c := sqlStr[i]
+	switch {
+	case c == '\'':
+		sc := &sqlScanner{sqlStr: sqlStr, sqlLen: len(sqlStr), index: i, noBackslashEscape: noBackslashEscape}
+		sc.skipString()
+		return sc.index, true
+	case c >= '0' && c <= '9', c == '-' || c == '+' || c == '.':
+		start := i
+		if c == '-' || c == '+' {
+			i++
+		}
+		for i < len(sqlStr) && (sqlStr[i] >= '0' && sqlStr[i] <= '9' || sqlStr[i] == '.') {
+			i++
+		}
+		return i, i > start && !(i < len(sqlStr) && isIdentChar(sqlStr[i])) && (sqlStr[i-1] >= '0' && sqlStr[i-1] <= '9')
+	case matchKeyword(sqlStr, i, "true"), matchKeyword(sqlStr, i, "null"):
+		return i + 4, true
+	}
+	return i, false
+}
+
+// skipSpace 跳过空白字符
+func skipSpace(sqlStr string, i int) int {
+	for i < len(sqlStr) && (sqlStr[i] == ' ' || sqlStr[i] == '\t' || sqlStr[i] == '\n' || sqlStr[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// isAfterConditionKeyword 判断 i 位置前面(跳过空白字符和左括号)是否是 WHERE,AND,ON,HAVING 关键字
+func isAfterConditionKeyword(sqlStr string, i int) bool {
+	for i > 0 && (sqlStr[i-1] == ' ' || sqlStr[i-1] == '\t' || sqlStr[i-1] == '\n' || sqlStr[i-1] == '\r' || sqlStr[i-1] == '(') {
+		i--
+	}
+	for _, keyword := range []string{"where", "and", "on", "having"} {
+		if i >= len(keyword) && matchKeyword(sqlStr, i-len(keyword), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSpaceAndCloseParen 跳过空白字符和右括号
+func skipSpaceAndCloseParen(sqlStr string, i int) int {
+	for i < len(sqlStr) && (sqlStr[i] == ' ' || sqlStr[i] == '\t' || sqlStr[i] == '\n' || sqlStr[i] == '\r' || sqlStr[i] == ')') {
+		i++
+	}
+	return i
+}
+
+// skipSpaceAndParen 跳过空白字符和左括号
+func skipSpaceAndParen(sqlStr string, i int) int {
+	for i < len(sqlStr) && (sqlStr[i] == ' ' || sqlStr[i] == '\t' || sqlStr[i] == '\n' || sqlStr[i] == '\r' || sqlStr[i] == '(') {
+		i++
+	}
+	return i
+}
@@ -699,7 +699,7 @@ func TestParseSQL_SelectCountSubquery(t *testing.T) {
 
 	// 调用 selectCount, 内部会构建 COUNT SQL 并调用 QueryRow -> queryRow
 	ctx := context.Background()
-	_, _ = selectCount(ctx, finder)
+	_, _ = selectCount(ctx, &DataSourceConfig{Dialect: "mysql"}, finder)
 
 	t.Logf("原始 SQL: %s", originalSQL)
 	t.Logf("生成的 COUNT SQL: %s", capturedSQL)
@@ -1196,3 +1196,189 @@ func TestParseSQL_FinancialReport(t *testing.T) {
 	assertPart(t, sql, parts.From, "FROM")
 	assertPart(t, sql, parts.OrderBy, "ORDER")
 }
+
+// ---------------- TestCheckSQLInjection ----------------
+// 测试词法注入检查, 静态字符串允许, 多条语句/注释/恒真条件/未闭合字符串返回错误并包含位置
+func TestCheckSQLInjection(t *testing.T) {
+	allowed := []string{
+		`SELECT * FROM users WHERE status='A' AND name=?`,
+		`SELECT * FROM users WHERE 1=1 AND id=?`,
+		`SELECT * FROM users WHERE id=? OR name=? ORDER BY id`,
+		`SELECT /*+ INDEX(users idx_name) */ * FROM users WHERE name = 'O''Brien';`,
+		`SELECT * FROM users WHERE note = 'a;b -- c' AND code='1=1'`,
+		`SELECT * FROM users WHERE status IN ('A','B') OR id=?`,
+		`SELECT * FROM users WHERE a.orders = 1 OR b.x = 2`,
+	}
+	for _, sql := range allowed {
+		if err := CheckSQLInjection(sql, FinderInjectionRules); err != nil {
+			t.Errorf("不应报错: %s, %v", sql, err)
+		}
+	}
+
+	rejected := []struct {
+		sql      string
+		position string
+	}{
+		{`SELECT * FROM users WHERE id=1; DROP TABLE users`, "第30个字符"},
+		{`SELECT * FROM users WHERE name='admin'--' AND pwd=?`, "第38个字符"},
+		{`SELECT * FROM users WHERE id=1 /* AND deleted=0 */`, "第31个字符"},
+		{`SELECT * FROM users WHERE id=1 OR 1=1`, "第31个字符"},
+		{`SELECT * FROM users WHERE id=1 or 'a' = 'a'`, "第31个字符"},
+		{`SELECT * FROM users WHERE id=? OR (2>1)`, "第31个字符"},
+		{`SELECT * FROM users WHERE id=? OR TRUE ORDER BY id`, "第31个字符"},
+		{`SELECT * FROM users WHERE name='it's'`, "第36个字符"},
+	}
+	for _, tt := range rejected {
+		err := CheckSQLInjection(tt.sql, FinderInjectionRules)
+		if err == nil {
+			t.Errorf("应该报错: %s", tt.sql)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.position) {
+			t.Errorf("错误位置不正确: %s, 期望 %s, 实际 %v", tt.sql, tt.position, err)
+		}
+	}
+
+	// 兼容之前版本的单引号检查
+	// Compatible with the single quote check of previous versions
+	if err := CheckSQLInjection(allowed[0], FinderInjectionRules|InjectionRuleStringLiteral); err == nil {
+		t.Error("InjectionRuleStringLiteral 应该禁止字符串")
+	}
+
+	// 拼接的参数闭合了引号
+	// The concatenated parameter closes the quote
+	breakouts := []struct {
+		sql      string
+		position string
+	}{
+		{`SELECT * FROM users WHERE name='x' UNION SELECT pwd FROM users WHERE 'a'='a'`, "第35个字符"},
+		{`SELECT * FROM users WHERE name='x' AND 'a'='a'`, "第39个字符"},
+		{`SELECT * FROM users WHERE ('a'='a') AND id=?`, "第27个字符"},
+		{`SELECT * FROM users WHERE name='x' OR name LIKE '%'`, "第35个字符"},
+		{`SELECT * FROM users WHERE name='x' OR name='y'`, "第35个字符"},
+		{`SELECT * FROM users WHERE (name='x') OR u.name <> 'y'`, "第37个字符"},
+		{`SELECT * FROM users WHERE note = 'a;b -- c' OR code='1=1'`, "第44个字符"},
+	}
+	for _, tt := range breakouts {
+		err := CheckSQLInjection(tt.sql, FinderInjectionRules)
+		if err == nil {
+			t.Errorf("应该报错: %s", tt.sql)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.position) {
+			t.Errorf("错误位置不正确: %s, 期望 %s, 实际 %v", tt.sql, tt.position, err)
+		}
+	}
+	if err := CheckSQLInjection(`SELECT id FROM a WHERE type='A' AND id IN (SELECT id FROM b UNION SELECT id FROM c)`, FinderInjectionRules); err != nil {
+		t.Errorf("不应报错: %v", err)
+	}
+
+	// 字符串中的 \ 是否是转义字符由方言决定
+	// Whether \ is an escape character in strings depends on the dialect
+	backslashSQL := `SELECT * FROM files WHERE path='C:\' AND id=?`
+	if err := CheckDialectSQLInjection(GetDialect("postgresql"), backslashSQL, FinderInjectionRules); err != nil {
+		t.Errorf("postgresql 不应报错: %v", err)
+	}
+	if err := CheckDialectSQLInjection(GetDialect("mysql"), backslashSQL, FinderInjectionRules); err == nil {
+		t.Error("mysql 字符串没有闭合,应该报错")
+	}
+	if err := CheckDialectSQLInjection(GetDialect("postgresql"), `SELECT * FROM users WHERE name='\' OR 1=1 --'`, FinderInjectionRules); err == nil {
+		t.Error("postgresql 的 \\ 不是转义字符,应该报错")
+	}
+
+	// Finder.GetSQL 使用 FinderInjectionRules
+	if _, err := NewFinder().Append("SELECT * FROM users WHERE status='A'").GetSQL(); err != nil {
+		t.Errorf("Finder 不应报错: %v", err)
+	}
+	if _, err := NewFinder().Append("SELECT * FROM users WHERE id=1 OR 1=1").GetSQL(); err == nil {
+		t.Error("Finder 应该报错")
+	}
+}
+
+// ---------------- TestParseSQL_With ----------------
+// 测试 WITH 和 WITH RECURSIVE 子句, CTE 中的关键字不影响主语句
+func TestParseSQL_With(t *testing.T) {
+	sql := ` WITH RECURSIVE t(n) AS (SELECT 1 FROM dual UNION ALL SELECT n+1 FROM t WHERE n < 10), u AS (SELECT * FROM users ORDER BY id) SELECT DISTINCT n FROM t WHERE n > ? ORDER BY n`
+
+	parts := parseSQL(sql)
+
+	assertPart(t, sql, parts.With, "WITH RECURSIVE")
+	assertPart(t, sql, parts.Select, "SELECT DISTINCT n")
+	assertPart(t, sql, parts.From, "FROM t")
+	assertPart(t, sql, parts.Where, "WHERE n > ?")
+	assertPart(t, sql, parts.OrderBy, "ORDER BY n")
+	if !strings.HasPrefix(sql[parts.Select.Start:], "SELECT DISTINCT") {
+		t.Errorf("SELECT 应从主语句开始: %s", sql[parts.Select.Start:])
+	}
+	if parts.Union.End != 0 {
+		t.Errorf("CTE 中的 UNION 不应影响主语句")
+	}
+	if parts.Distinct.End == 0 {
+		t.Errorf("主语句的 DISTINCT 未识别")
+	}
+
+	// 没有 WITH 时 With 为空, SELECT 从 0 开始
+	parts = parseSQL("SELECT * FROM users WHERE name = 'with'")
+	if parts.With.End != 0 || parts.Select.Start != 0 {
+		t.Errorf("没有 WITH 时 With=%v, Select=%v", parts.With, parts.Select)
+	}
+}
+
+// ---------------- TestParseSQL_WithCountAndPage ----------------
+// 测试 WITH 语句的总条数和分页, WITH 子句保留在最前面
+func TestParseSQL_WithCountAndPage(t *testing.T) {
+	var capturedSQL string
+	originalQueryRow := queryRow
+	queryRow = func(ctx context.Context, f *Finder, entity interface{}) (bool, error) {
+		capturedSQL, _ = f.GetSQL()
+		return false, nil
+	}
+	defer func() {
+		queryRow = originalQueryRow
+	}()
+
+	withSQL := "WITH u AS (SELECT id,name FROM users WHERE status=?) "
+	tests := []struct {
+		dialect string
+		version string
+		sql     string
+		count   string
+		page    string
+	}{
+		{"mysql", "", withSQL + "SELECT * FROM u ORDER BY id",
+			"SELECT COUNT(*) FROM u ",
+			"SELECT * FROM u ORDER BY id LIMIT 0,20"},
+		{"postgresql", "", withSQL + "SELECT DISTINCT name FROM u",
+			"SELECT COUNT(*) AS temp_zorm_row_count FROM (SELECT DISTINCT name FROM u) temp_zorm_noob_table_name WHERE 1=1 ",
+			"SELECT DISTINCT name FROM u LIMIT 20 OFFSET 0"},
+		{"mssql", "", withSQL + "SELECT * FROM u ORDER BY id",
+			"SELECT COUNT(*) FROM u ",
+			"SELECT * FROM u ORDER BY id OFFSET 0 ROWS FETCH NEXT 20 ROWS ONLY "},
+		{"mssql", "2008", withSQL + "SELECT * FROM u ORDER BY id",
+			"SELECT COUNT(*) FROM u ",
+			"SELECT * FROM (SELECT temp_zorm_page_table.*,ROW_NUMBER() OVER(ORDER BY id) AS temp_zorm_rownum FROM (SELECT * FROM u ) temp_zorm_page_table) temp_zorm_page_table2 WHERE temp_zorm_rownum BETWEEN 1 AND 20 ORDER BY temp_zorm_rownum"},
+		{"oracle", "11g", withSQL + "SELECT * FROM u",
+			"SELECT COUNT(*) FROM u",
+			"SELECT * FROM (SELECT temp_zorm_page_table.*,ROWNUM temp_zorm_rownum FROM (SELECT * FROM u) temp_zorm_page_table WHERE ROWNUM <= 20) WHERE temp_zorm_rownum > 0"},
+	}
+	for _, tt := range tests {
+		config := &DataSourceConfig{Dialect: tt.dialect, DialectVersion: tt.version}
+		finder := NewFinder().Append(tt.sql, 1)
+		capturedSQL = ""
+		if _, err := selectCount(context.Background(), config, finder); err != nil {
+			t.Errorf("%s: %v", tt.dialect, err)
+			continue
+		}
+		if want := withSQL + tt.count; strings.TrimLeft(capturedSQL, " ") != want {
+			t.Errorf("%s %s COUNT SQL 不正确.\n期望: %s\n实际: %s", tt.dialect, tt.version, want, capturedSQL)
+		}
+		pageSQL, err := wrapPageSQL(context.Background(), config, finder, NewPage())
+		if err != nil {
+			t.Errorf("%s: %v", tt.dialect, err)
+			continue
+		}
+		if want := " " + withSQL + tt.page; pageSQL != want {
+			t.Errorf("%s %s 分页 SQL 不正确.\n期望: %s\n实际: %s", tt.dialect, tt.version, want, pageSQL)
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 // tagColumnName tag标签的名称
@@ -72,6 +73,14 @@ type fieldColumnCache struct {
 	isPtr bool
 	// isPK 是否是主键字段
 	isPK bool
+	// isTenant 是否是 tenant:"true" 的租户字段
+	isTenant bool
+	// isRedact 是否是 redact:"true" 的脱敏字段,RegisterRedactEntity时注册
+	isRedact bool
+	// isJSON 是否是 zorm:"json" 的字段,保存时序列化为JSON,查询时反序列化
+	isJSON bool
+	// isArray 是否是 zorm:"array" 的字段,保存和查询使用postgresql和kingbase数组的文本格式
+	isArray bool
 
 	// 以下属性仅用在查询时的映射上,每个查询都是重新初始化的struct对象
 
@@ -85,6 +94,12 @@ type fieldColumnCache struct {
 	customDriverValueConver ICustomDriverValueConver
 	// tempDriverValue 记录customDriverValueConver.GetDriverValue临时的值
 	tempDriverValue driver.Value
+	// valueScanner IValueConver转换的接收对象,没有注册IValueConver时是nil
+	valueScanner *valueConverScanner
+	// timeScanner DataSourceConfig.TimePolicy转换time.Time字段的接收对象,没有配置TimePolicy或者不是时间字段时是nil
+	timeScanner *timeScanner
+	// nestedPath 嵌套struct字段的路径,例如 customer.name 列对应 Customer 字段,不是嵌套struct时是nil
+	nestedPath []nestedFieldStep
 }
 
 // entityStructCache entity和struct结构体缓存,包含实体类的字段和数据库列的映射信息
@@ -111,6 +126,10 @@ type entityStructCache struct {
 	pkSequence string // 主键序列名称
 	// autoIncrement 自增类型  0(不自增),1(普通自增),2(序列自增)
 	autoIncrement int
+	// tenantField tenant:"true" 的租户字段
+	tenantField *fieldColumnCache
+	// structType struct的类型,用于获取RegisterFieldValueConver注册的字段转换
+	structType reflect.Type
 }
 
 // buildStructCache 构建基础的Struct字段缓存,不存储到map中
@@ -122,7 +141,7 @@ func buildStructCache(ctx context.Context, typeOf reflect.Type) (*entityStructCa
 		return nil, errors.New("->buildStructCache-->NumField entity没有属性")
 	}
 	// 创建实体类字段缓存
-	entityCache := &entityStructCache{}
+	entityCache := &entityStructCache{structType: typeOf}
 	entityCache.fields = make([]*fieldColumnCache, 0, fieldNum)
 	entityCache.fieldMap = make(map[string]*fieldColumnCache)
 	entityCache.columns = make([]*fieldColumnCache, 0, fieldNum)
@@ -138,9 +157,23 @@ func buildStructCache(ctx context.Context, typeOf reflect.Type) (*entityStructCa
 			funcCreateEntityStructCache(ctx, entityCache, field)
 		}
 	}
+	for _, column := range entityCache.columns {
+		if column.isTenant {
+			entityCache.tenantField = column
+			break
+		}
+	}
 	return entityCache, nil
 }
 
+// entityCacheDialectKey 缓存key的数据库部分,包裹标识符时insertSQL和deleteSQL不同,使用单独的缓存
+func entityCacheDialectKey(config *DataSourceConfig) string {
+	if config.QuoteIdentifier {
+		return config.Dialect + "_quote"
+	}
+	return config.Dialect
+}
+
 // getStructTypeOfCache 获取Struct实体类的结构体缓存,可以是普通的Struct
 func getStructTypeOfCache(ctx context.Context, typeOfPtr *reflect.Type, config *DataSourceConfig) (*entityStructCache, error) {
 	// pkgPath + _ + pkgName(因为单独用这个不保证唯一)
@@ -149,7 +182,7 @@ func getStructTypeOfCache(ctx context.Context, typeOfPtr *reflect.Type, config *
 	pkgPath := typeOf.PkgPath()
 	typeOfString := typeOf.String()
 	// 不同方言的缓存分开存储.不同方言的columnTag并不一样,例如 `name` 和 "name",一个项目使用多种数据库时,同一个Struct的映射会有区别
-	key := config.Dialect + "_" + pkgPath + "_" + typeOfString
+	key := entityCacheDialectKey(config) + "_" + pkgPath + "_" + typeOfString
 
 	// 缓存的值
 	entityCacheLoad, cacheOK := entityStructCacheMap.Load(key)
@@ -193,7 +226,7 @@ func getEntityStructCache(ctx context.Context, entity IEntityStruct, config *Dat
 	pkgPath := typeOf.PkgPath()
 	typeOfString := typeOf.String()
 	// 生成和getStructTypeOfCache相同的缓存key
-	key := config.Dialect + "_" + pkgPath + "_" + typeOfString
+	key := entityCacheDialectKey(config) + "_" + pkgPath + "_" + typeOfString
 
 	// 先检查缓存是否存在且已经完全构建
 	entityCacheLoad, cacheOK := entityStructCacheMap.Load(key)
@@ -260,7 +293,7 @@ func getEntityStructCache(ctx context.Context, entity IEntityStruct, config *Dat
 		}
 	}
 
-	entityCache.insertSQL = "INSERT INTO " + entity.GetTableName()
+	entityCache.insertSQL = "INSERT INTO " + wrapQuoteIdentifier(config, entity.GetTableName())
 
 	// insert SQL语句
 	err = wrapInsertSQL(ctx, entityCache, config)
@@ -268,7 +301,7 @@ func getEntityStructCache(ctx context.Context, entity IEntityStruct, config *Dat
 		return nil, err
 	}
 	// delete SQL语句
-	entityCache.deleteSQL, err = wrapDeleteSQL(ctx, entity)
+	entityCache.deleteSQL, err = wrapDeleteSQL(ctx, config, entity)
 	if err != nil {
 		return nil, err
 	}
@@ -286,6 +319,20 @@ func insertEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 	// 获取实体类的反射,指针下的struct
 	valueOf := reflect.ValueOf(entity).Elem()
 
+	// 使用ctx中的租户ID给租户字段赋值
+	// Assign the tenant ID of ctx to the tenant field
+	if tenantField := entityTenantField(entity, entityCache); tenantField != nil {
+		tenantID, hasTenant, err := contextTenantID(ctx)
+		if err != nil {
+			return err
+		}
+		if hasTenant {
+			if err = setTenantFieldValue(valueOf.FieldByIndex(tenantField.fieldIndex), tenantID); err != nil {
+				return err
+			}
+		}
+	}
+
 	// 默认值的map,只对 Insert 和 InsertSlice 有效
 	var defaultValueMap map[string]interface{} = nil
 	if useDefaultValue {
@@ -314,14 +361,12 @@ func insertEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 			valueOf.FieldByIndex(entityCache.pkField.fieldIndex).Set(reflect.ValueOf(id))
 		} else if isDefaultValue && isZero { // 如果有默认值,并且fv是零值,等于默认值
 			value = defaultValue
-		} else if column.isPtr { // 如果是指针类型
-			if !fv.IsNil() { // 如果不是nil值
-				value = fv.Elem().Interface()
-			} else {
-				value = nil
-			}
 		} else {
-			value = fv.Interface()
+			fieldValue, err := fieldColumnValue(ctx, entityCache, column, fv)
+			if err != nil {
+				return err
+			}
+			value = fieldValue
 		}
 		// 添加到记录值的数组
 		*values = append(*values, value)
@@ -330,14 +375,33 @@ func insertEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 	return nil
 }
 
+// setEntityAutoIncrementPK 设置实体类自增主键的值,只处理int和int64类型的主键
+func setEntityAutoIncrementPK(entity IEntityStruct, entityCache *entityStructCache, autoIncrementIDInt64 int64) error {
+	switch entityCache.pkType {
+	case "int":
+		// int64 转 int
+		// int64 to int
+		autoIncrementIDInt, err := typeConvertInt64toInt(autoIncrementIDInt64)
+		if err != nil {
+			return err
+		}
+		pk := reflect.ValueOf(entity).Elem().FieldByIndex(entityCache.pkField.fieldIndex)
+		pk.Set(reflect.ValueOf(autoIncrementIDInt))
+	case "int64":
+		pk := reflect.ValueOf(entity).Elem().FieldByIndex(entityCache.pkField.fieldIndex)
+		pk.Set(reflect.ValueOf(autoIncrementIDInt64))
+	}
+	return nil
+}
+
 // updateEntityFieldValues 获取实体类的字段值数组
-func updateEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCache *entityStructCache, onlyUpdateNotZero bool) (*string, *[]interface{}, error) {
+func updateEntityFieldValues(ctx context.Context, config *DataSourceConfig, entity IEntityStruct, entityCache *entityStructCache, onlyUpdateNotZero bool) (*string, *[]interface{}, error) {
 	// SQL语句的构造器
 	// SQL statement constructor
 	var updateSQLBuilder strings.Builder
 	updateSQLBuilder.Grow(stringBuilderGrowLen)
 	updateSQLBuilder.WriteString("UPDATE ")
-	updateSQLBuilder.WriteString(entity.GetTableName())
+	updateSQLBuilder.WriteString(wrapTableName(ctx, config, entity.GetTableName()))
 	updateSQLBuilder.WriteString(" SET ")
 
 	fLen := len(entityCache.columns)
@@ -346,20 +410,18 @@ func updateEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 
 	// 获取实体类的反射,指针下的struct
 	valueOf := reflect.ValueOf(entity).Elem()
-	// Update仅更新指定列
-	var onlyUpdateColsMap map[string]bool
-	// UpdateNotZeroValue 必须更新指定列
-	var mustUpdateColsMap map[string]bool
-
-	if onlyUpdateNotZero { // 只更新非零值时,需要处理mustUpdateCols
-		mustUpdateCols := ctx.Value(contextMustUpdateColsValueKey)
-		if mustUpdateCols != nil { // 指定了仅更新的列
-			mustUpdateColsMap = mustUpdateCols.(map[string]bool)
+	// Update仅更新指定列,UpdateNotZeroValue 必须更新指定列
+	onlyUpdateColsMap, mustUpdateColsMap := contextUpdateColsMap(ctx, onlyUpdateNotZero)
+	// 租户字段,绑定了租户ID时不更新,作为WHERE条件
+	tenantColumnName := ""
+	tenantField := entityTenantField(entity, entityCache)
+	if tenantField != nil {
+		_, hasTenant, err := contextTenantID(ctx)
+		if err != nil {
+			return nil, nil, err
 		}
-	} else { // update 更新全部字段时,需要处理onlyUpdateCols
-		onlyUpdateCols := ctx.Value(contextOnlyUpdateColsValueKey)
-		if onlyUpdateCols != nil { // 指定了仅更新的列
-			onlyUpdateColsMap = onlyUpdateCols.(map[string]bool)
+		if hasTenant {
+			tenantColumnName = tenantField.columnTag
 		}
 	}
 	// 记录需要更新字段的索引,因为有些字段会跳过,所以不用 i
@@ -370,6 +432,9 @@ func updateEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 		if column.isPK { // 主键不更新
 			continue
 		}
+		if tenantColumnName != "" && column == tenantField { // 租户字段不更新
+			continue
+		}
 
 		// Update 指定仅更新的列
 		if onlyUpdateColsMap != nil && !onlyUpdateColsMap[column.columnNameLower] {
@@ -390,21 +455,16 @@ func updateEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 				continue
 			}
 		}
-		if column.isPtr { // 如果是指针类型
-			if fieldValue.IsNil() { // 如果是nil值
-				value = nil
-			} else {
-				value = fieldValue.Elem().Interface()
-			}
-		} else { // 不是指针
-			value = fieldValue.Interface()
+		value, err := fieldColumnValue(ctx, entityCache, column, fieldValue)
+		if err != nil {
+			return nil, nil, err
 		}
 		// 添加 , 逗号
 		if updateColumnIndex > 0 {
 			updateSQLBuilder.WriteByte(',')
 		}
 		updateColumnIndex++
-		updateSQLBuilder.WriteString(column.columnTag)
+		updateSQLBuilder.WriteString(wrapQuoteIdentifier(config, column.columnTag))
 		updateSQLBuilder.WriteString("=?")
 		// 添加到记录值的数组
 		values = append(values, value)
@@ -414,23 +474,248 @@ func updateEntityFieldValues(ctx context.Context, entity IEntityStruct, entityCa
 	}
 	// 添加组件参数
 	updateSQLBuilder.WriteString(" WHERE ")
-	updateSQLBuilder.WriteString(entity.GetPKColumnName())
+	updateSQLBuilder.WriteString(wrapQuoteIdentifier(config, entity.GetPKColumnName()))
 	updateSQLBuilder.WriteString("=?")
 	// 添加主键值
 	pkValue := valueOf.FieldByIndex(entityCache.pkField.fieldIndex).Interface()
 	values = append(values, pkValue)
 	updateSQL := updateSQLBuilder.String()
+	// 租户条件
+	// Tenant condition
+	if err := wrapTenantWhereSQL(ctx, config, tenantColumnName, &updateSQL, &values); err != nil {
+		return nil, nil, err
+	}
+	return &updateSQL, &values, nil
+}
+
+// contextUpdateColsMap 获取ctx中Update仅更新的列,UpdateNotZeroValue必须更新的列
+func contextUpdateColsMap(ctx context.Context, onlyUpdateNotZero bool) (map[string]bool, map[string]bool) {
+	// Update仅更新指定列
+	var onlyUpdateColsMap map[string]bool
+	// UpdateNotZeroValue 必须更新指定列
+	var mustUpdateColsMap map[string]bool
+
+	if onlyUpdateNotZero { // 只更新非零值时,需要处理mustUpdateCols
+		mustUpdateCols := ctx.Value(contextMustUpdateColsValueKey)
+		if mustUpdateCols != nil { // 指定了仅更新的列
+			mustUpdateColsMap = mustUpdateCols.(map[string]bool)
+		}
+	} else { // update 更新全部字段时,需要处理onlyUpdateCols
+		onlyUpdateCols := ctx.Value(contextOnlyUpdateColsValueKey)
+		if onlyUpdateCols != nil { // 指定了仅更新的列
+			onlyUpdateColsMap = onlyUpdateCols.(map[string]bool)
+		}
+	}
+	return onlyUpdateColsMap, mustUpdateColsMap
+}
+
+// fieldColumnValue 保存的字段值,指针类型取指向的值,JSON字段序列化为JSON字符串,数组字段使用Array包装,RegisterFieldValueConver注册的字段使用IValueConver转换
+func fieldColumnValue(ctx context.Context, entityCache *entityStructCache, column *fieldColumnCache, fieldValue reflect.Value) (interface{}, error) {
+	if valueConver := registeredFieldValueConver(entityCache.structType, column); valueConver != nil { // 字段注册的转换 | Conversion registered for the field
+		return fieldConverValue(ctx, valueConver, fieldValue)
+	}
+	if column.isJSON { // 序列化为JSON
+		return jsonFieldValue(fieldValue)
+	}
+	if column.isArray { // 数组列,Array不会被reBuildSQL展开
+		return Array{V: fieldValue.Interface()}.Value()
+	}
+	if column.isPtr { // 如果是指针类型
+		if fieldValue.IsNil() { // 如果是nil值
+			return nil, nil
+		}
+		return fieldValue.Elem().Interface(), nil
+	}
+	// 不是指针
+	return fieldValue.Interface(), nil
+}
+
+// updateSliceFieldValues 获取批量更新的语句和值数组,每列使用 CASE pk WHEN ? THEN ? ... ELSE 列 END ,没有更新的行保持原值
+// 例如 UPDATE t SET a=CASE id WHEN ? THEN ? WHEN ? THEN ? ELSE a END WHERE id IN (?,?)
+func updateSliceFieldValues(ctx context.Context, config *DataSourceConfig, entityStructSlice []IEntityStruct, entityCache *entityStructCache, onlyUpdateNotZero bool) (*string, *[]interface{}, error) {
+	entity := entityStructSlice[0]
+	pkColumnName := wrapQuoteIdentifier(config, entity.GetPKColumnName())
+	sliceLen := len(entityStructSlice)
+
+	// 每个实体类的反射和主键的值
+	valueOfs := make([]reflect.Value, sliceLen)
+	pkValues := make([]interface{}, sliceLen)
+	for i, entity := range entityStructSlice {
+		if entity == nil {
+			return nil, nil, errors.New("->updateSliceFieldValues-->entity对象不能为空")
+		}
+		valueOfs[i] = reflect.ValueOf(entity).Elem()
+		if valueOfs[i].Type() != valueOfs[0].Type() {
+			return nil, nil, errors.New("->updateSliceFieldValues-->entityStructSlice必须是相同的类型")
+		}
+		pkValues[i] = valueOfs[i].FieldByIndex(entityCache.pkField.fieldIndex).Interface()
+	}
+
+	// SQL语句的构造器
+	// SQL statement constructor
+	var updateSQLBuilder strings.Builder
+	updateSQLBuilder.Grow(stringBuilderGrowLen * sliceLen)
+	updateSQLBuilder.WriteString("UPDATE ")
+	updateSQLBuilder.WriteString(wrapTableName(ctx, config, entity.GetTableName()))
+	updateSQLBuilder.WriteString(" SET ")
+	// 接收值的数组
+	values := make([]interface{}, 0, (2*len(entityCache.columns)+1)*sliceLen)
+
+	onlyUpdateColsMap, mustUpdateColsMap := contextUpdateColsMap(ctx, onlyUpdateNotZero)
+	// 租户字段,绑定了租户ID时不更新,作为WHERE条件
+	tenantColumnName := ""
+	tenantField := entityTenantField(entity, entityCache)
+	if tenantField != nil {
+		_, hasTenant, err := contextTenantID(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if hasTenant {
+			tenantColumnName = tenantField.columnTag
+		}
+	}
+	// 记录需要更新字段的索引,因为有些字段会跳过,所以不用 i
+	updateColumnIndex := 0
+	for _, column := range entityCache.columns {
+		if column.isPK { // 主键不更新
+			continue
+		}
+		if tenantColumnName != "" && column == tenantField { // 租户字段不更新
+			continue
+		}
+		// Update 指定仅更新的列
+		if onlyUpdateColsMap != nil && !onlyUpdateColsMap[column.columnNameLower] {
+			continue
+		}
+		columnName := wrapQuoteIdentifier(config, column.columnTag)
+		// 这一列更新的行数
+		whenCount := 0
+		for i := range valueOfs {
+			fieldValue := valueOfs[i].FieldByIndex(column.fieldIndex)
+			// 更新非零值,并且是零值,不是mustUpdateCols
+			if onlyUpdateNotZero && fieldValue.IsZero() && !mustUpdateColsMap[column.columnNameLower] {
+				continue
+			}
+			if whenCount == 0 {
+				// 添加 , 逗号
+				if updateColumnIndex > 0 {
+					updateSQLBuilder.WriteByte(',')
+				}
+				updateSQLBuilder.WriteString(columnName)
+				updateSQLBuilder.WriteString("=CASE ")
+				updateSQLBuilder.WriteString(pkColumnName)
+			}
+			value, err := fieldColumnValue(ctx, entityCache, column, fieldValue)
+			if err != nil {
+				return nil, nil, err
+			}
+			whenCount++
+			updateSQLBuilder.WriteString(" WHEN ? THEN ?")
+			values = append(values, pkValues[i], value)
+		}
+		if whenCount > 0 {
+			updateColumnIndex++
+			updateSQLBuilder.WriteString(" ELSE ")
+			updateSQLBuilder.WriteString(columnName)
+			updateSQLBuilder.WriteString(" END")
+		}
+	}
+	if updateColumnIndex == 0 { //没有要更新的字段
+		return nil, nil, errors.New("->updateSliceFieldValues 没有要更新的列")
+	}
+	// 主键条件
+	updateSQLBuilder.WriteString(" WHERE ")
+	updateSQLBuilder.WriteString(pkColumnName)
+	updateSQLBuilder.WriteString(" IN (")
+	for i := range pkValues {
+		if i > 0 {
+			updateSQLBuilder.WriteByte(',')
+		}
+		updateSQLBuilder.WriteByte('?')
+	}
+	updateSQLBuilder.WriteByte(')')
+	values = append(values, pkValues...)
+	updateSQL := updateSQLBuilder.String()
+	// 租户条件
+	// Tenant condition
+	if err := wrapTenantWhereSQL(ctx, config, tenantColumnName, &updateSQL, &values); err != nil {
+		return nil, nil, err
+	}
 	return &updateSQL, &values, nil
 }
 
-// sqlRowsValues 包装接收sqlRows的Values数组,反射rows屏蔽数据库null值,兼容单个字段查询和Struct映射
-// 当读取数据库的值为NULL时,由于基本类型不支持为NULL,通过反射将未知driver.Value改为interface{},不再映射到struct实体类
+// nullScanner 只记录列值是否为NULL,不转换也不复制值
+// nullScanner only records whether the column value is NULL, without converting or copying the value
+type nullScanner struct {
+	isNull bool
+}
+
+// Scan 实现sql.Scanner接口
+// Scan implements the sql.Scanner interface
+func (s *nullScanner) Scan(src interface{}) error {
+	s.isNull = src == nil
+	return nil
+}
+
+// discardScanner 丢弃列值,用于NULL值和实体类没有对应字段的列.空struct赋值给interface{}不分配内存
+// discardScanner discards the column value, used for NULL values and columns without a struct field. Assigning an empty struct to interface{} does not allocate
+type discardScanner struct{}
+
+// Scan 实现sql.Scanner接口
+// Scan implements the sql.Scanner interface
+func (discardScanner) Scan(src interface{}) error {
+	return nil
+}
+
+// rowsNullChecker 检查当前行哪些列是NULL,每个查询创建一次,每行复用.
+// 以前通过反射读取*sql.Rows未导出的lastcols字段,标准库修改字段或者使用sqlmock等包装的驱动时会失效.
+// database/sql允许同一行多次调用rows.Scan,先用nullScanner扫描一次记录NULL列,再扫描到实际的接收对象
+// rowsNullChecker checks which columns of the current row are NULL, created once per query and reused for each row.
+// Previously the unexported lastcols field of *sql.Rows was read by reflection, which breaks when the standard library changes the field or with wrapped drivers like sqlmock.
+// database/sql allows rows.Scan to be called multiple times for the same row, scan once with nullScanner to record the NULL columns, then scan into the actual receivers
+type rowsNullChecker struct {
+	scanners []nullScanner
+	dest     []interface{}
+}
+
+// newRowsNullChecker 创建columnLen列的rowsNullChecker
+// newRowsNullChecker creates a rowsNullChecker of columnLen columns
+func newRowsNullChecker(columnLen int) *rowsNullChecker {
+	checker := &rowsNullChecker{
+		scanners: make([]nullScanner, columnLen),
+		dest:     make([]interface{}, columnLen),
+	}
+	for i := range checker.scanners {
+		checker.dest[i] = &checker.scanners[i]
+	}
+	return checker
+}
+
+// scan 扫描当前行,记录NULL列
+// scan scans the current row and records the NULL columns
+func (checker *rowsNullChecker) scan(rows *sql.Rows) error {
+	return rows.Scan(checker.dest...)
+}
+
+// isNull 当前行第i列是否是NULL
+// isNull whether the i-th column of the current row is NULL
+func (checker *rowsNullChecker) isNull(i int) bool {
+	return checker.scanners[i].isNull
+}
+
+// sqlRowsValues 包装接收sqlRows的Values数组,屏蔽数据库null值,兼容单个字段查询和Struct映射
+// 当读取数据库的值为NULL时,由于基本类型不支持为NULL,使用discardScanner丢弃,不再映射到struct实体类
 // 感谢@fastabler提交的pr fix:converting NULL to int is unsupported
 // oneColumnScanner 只有一个字段,而且可以直接Scan,例如string或者[]string,不需要反射StructType进行处理
-func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.Type, rows *sql.Rows, driverValue *reflect.Value, fieldCaches []*fieldColumnCache, entity interface{}) error {
+func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.Type, rows *sql.Rows, nullChecker *rowsNullChecker, fieldCaches []*fieldColumnCache, entity interface{}) error {
 	if entity == nil && (valueOf == nil || valueOf.IsNil()) {
 		return errors.New("->sqlRowsValues-->接收值的entity参数为nil")
 	}
+	// 记录当前行的NULL列
+	// Record the NULL columns of the current row
+	if err := nullChecker.scan(rows); err != nil {
+		return err
+	}
 
 	var valueOfElem reflect.Value
 	if entity == nil && valueOf != nil {
@@ -492,6 +777,8 @@ func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.
 		*valuesPtr = values
 		valuesSlicePool.Put(valuesPtr)
 	}()
+	// 是否有IValueConver转换的字段
+	hasValueConver := false
 	// 记录需要类型转换的字段信息
 	var tempDriverValues []*fieldColumnCache
 	if iscdvm {
@@ -500,14 +787,25 @@ func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.
 	// 循环字段
 	for i, fieldCache := range fieldCaches {
 		if fieldCache == nil { // 数据库字段比实体类的多,实体类无法接收,设置为默认值
-			values[i] = new(interface{})
+			values[i] = discardScanner{}
 			continue
 		}
 		columnType := fieldCache.columnType
-		dv := driverValue.Index(i)
-		// if dv.IsValid() && dv.InterfaceData()[0] == 0 {
-		if dv.IsValid() && dv.IsNil() { // 该字段的数据库值是null,取默认值 | The database value of this field is null, no further processing is required, use the default value
-			values[i] = new(interface{})
+		if nullChecker.isNull(i) { // 该字段的数据库值是null,取默认值 | The database value of this field is null, no further processing is required, use the default value
+			values[i] = discardScanner{}
+			continue
+		}
+		if fieldCache.valueScanner != nil { // IValueConver转换的字段,优先于JSON和customDriverValueConver
+			values[i] = fieldCache.valueScanner
+			hasValueConver = true
+			continue
+		}
+		if fieldCache.isJSON && entity == nil && fieldCache.structField != nil { // JSON字段反序列化到字段,优先于customDriverValueConver
+			values[i] = &jsonScanner{dest: selectFieldValue(valueOfElem, fieldCache).Addr().Interface()}
+			continue
+		}
+		if fieldCache.isArray && entity == nil && fieldCache.structField != nil { // 数组字段解析到slice,优先于customDriverValueConver
+			values[i] = &Array{V: selectFieldValue(valueOfElem, fieldCache).Addr().Interface()}
 			continue
 		}
 		if fieldCache.customDriverValueConver != nil { // 如果是需要转换的字段
@@ -532,19 +830,34 @@ func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.
 			continue
 		}
 
+		if fieldCache.timeScanner != nil { // TimePolicy转换的时间字段
+			if entity != nil { // 查询一个字段,并且可以直接接收
+				fieldCache.timeScanner.dest = entity.(*time.Time)
+			} else {
+				fieldValue := selectFieldValue(valueOfElem, fieldCache)
+				if fieldCache.isPtr {
+					fieldValue.Set(reflect.New(timeType))
+					fieldValue = fieldValue.Elem()
+				}
+				fieldCache.timeScanner.dest = fieldValue.Addr().Interface().(*time.Time)
+			}
+			values[i] = fieldCache.timeScanner
+			continue
+		}
+
 		// 不需要customDriverValueConver特殊转换
 		if entity != nil { // 查询一个字段,并且可以直接接收
 			values[i] = entity
 			continue
 		}
 		if fieldCache.structField == nil { // 如果不存在这个字段
-			values[i] = new(interface{})
+			values[i] = discardScanner{}
 			continue
 		}
 		// 记录值
 		var v interface{}
 		// 字段的反射值
-		fieldValue := valueOfElem.FieldByIndex(fieldCache.fieldIndex)
+		fieldValue := selectFieldValue(valueOfElem, fieldCache)
 		if fieldCache.isPtr { // 如果是指针类型
 			// 反射new一个对应类型的指针
 			newValue := reflect.New(fieldCache.structField.Type.Elem())
@@ -562,6 +875,24 @@ func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.
 	if err != nil {
 		return err
 	}
+	// IValueConver转换后的值赋值给字段
+	// Assign the values converted by IValueConver to the fields
+	if hasValueConver {
+		for i, fieldCache := range fieldCaches {
+			if fieldCache == nil || fieldCache.valueScanner == nil || nullChecker.isNull(i) {
+				continue
+			}
+			var fieldValue reflect.Value
+			if entity != nil { // 查询一个字段,并且可以直接接收
+				fieldValue = reflect.ValueOf(entity).Elem()
+			} else {
+				fieldValue = selectFieldValue(valueOfElem, fieldCache)
+			}
+			if err = setConverValue(fieldValue, fieldCache.valueScanner.value); err != nil {
+				return err
+			}
+		}
+	}
 	// 没有特殊类型替换的值
 	if len(tempDriverValues) < 1 {
 		return nil
@@ -584,7 +915,7 @@ func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.
 		// 如果是Struct类型接收
 		if fieldCache.structField != nil {
 			// 字段的反射值
-			fieldValue := valueOfElem.FieldByIndex(fieldCache.fieldIndex)
+			fieldValue := selectFieldValue(valueOfElem, fieldCache)
 			// 给字段赋值
 			fieldValue.Set(reflect.ValueOf(rightValue).Elem())
 		}
@@ -596,7 +927,7 @@ func sqlRowsValues(ctx context.Context, valueOf *reflect.Value, typeOf *reflect.
 
 // buildSelectFieldColumnCache 构建查询字段缓存
 // buildSelectFieldColumnCache builds a cache of query fields
-func buildSelectFieldColumnCache(columnTypes []*sql.ColumnType, entityCache *entityStructCache, dialect string) ([]*fieldColumnCache, error) {
+func buildSelectFieldColumnCache(ctx context.Context, columnTypes []*sql.ColumnType, entityCache *entityStructCache, config *DataSourceConfig) ([]*fieldColumnCache, error) {
 	if columnTypes == nil {
 		return nil, errors.New("->buildSelectFieldColumnCache-->columnTypes不能为nil")
 	}
@@ -608,16 +939,20 @@ func buildSelectFieldColumnCache(columnTypes []*sql.ColumnType, entityCache *ent
 	for i, columnType := range columnTypes {
 		//field, err := getStructFieldByColumnType(columnType, dbColumnFieldMap, exportFieldMap)
 		columnName := strings.ToLower(columnType.Name())
-		field, ok := entityCache.columnMap[columnName]
-		if !ok {
-			field, ok = entityCache.fieldMap[columnName] // 尝试用struct属性名查找
-		}
-		if !ok && strings.Contains(columnName, "_") { // 尝试驼峰命名转换(去除下划线)
-			cname := strings.ReplaceAll(columnName, "_", "")
-			field, ok = entityCache.fieldMap[cname]
+		field := lookupColumnFieldCache(entityCache, columnName)
+		// 字段所在struct的缓存,嵌套struct的字段是嵌套struct的缓存
+		// Cache of the struct containing the field, the cache of the nested struct for nested fields
+		fieldEntityCache := entityCache
+		var nestedPath []nestedFieldStep
+		if field == nil { // 嵌套struct的字段,例如 customer.name | Field of a nested struct, e.g. customer.name
+			var err error
+			field, nestedPath, fieldEntityCache, err = findNestedFieldCache(ctx, config, entityCache, columnName, 0)
+			if err != nil {
+				return nil, err
+			}
 		}
 		// 数据库字段可能比Struct里多, fieldCache[i] = nil
-		if field == nil || !ok {
+		if field == nil {
 			fieldCaches[i] = nil
 			continue
 		}
@@ -631,6 +966,9 @@ func buildSelectFieldColumnCache(columnTypes []*sql.ColumnType, entityCache *ent
 			isPtr:            field.isPtr,
 			fieldName:        field.fieldName,
 			fieldIndex:       field.fieldIndex,
+			isJSON:           field.isJSON,
+			isArray:          field.isArray,
+			nestedPath:       nestedPath,
 
 			// VARCHAR 和 TEXT 可以同时映射到一个string字段上,所以每次临时获取,不能缓存到field上
 			//dialectDatabaseTypeName: field.dialectDatabaseTypeName,
@@ -638,8 +976,8 @@ func buildSelectFieldColumnCache(columnTypes []*sql.ColumnType, entityCache *ent
 		}
 
 		// 缓存带方言前缀的数据库类型名
-		if dialect != "" {
-			fieldCache.dialectDatabaseTypeName = dialect + "." + databaseTypeName
+		if config.Dialect != "" {
+			fieldCache.dialectDatabaseTypeName = config.Dialect + "." + databaseTypeName
 		}
 
 		// 缓存customDriverValueConver,避免每行每列的map查找
@@ -652,6 +990,17 @@ func buildSelectFieldColumnCache(columnTypes []*sql.ColumnType, entityCache *ent
 			}
 		}
 
+		// IValueConver的接收对象
+		// Receiver of IValueConver
+		if valueConver := fieldValueConver(fieldEntityCache.structType, fieldCache); valueConver != nil {
+			fieldCache.valueScanner = &valueConverScanner{ctx: ctx, valueConver: valueConver}
+		}
+		// TimePolicy的接收对象
+		// Receiver of TimePolicy
+		if config.TimePolicy != nil && field.structField != nil && isTimeType(field.structField.Type) {
+			fieldCache.timeScanner = &timeScanner{policy: config.TimePolicy, dialect: config.Dialect}
+		}
+
 		fieldCaches[i] = fieldCache
 		//columnTypeToCache[columnType] = cacheItem
 	}
@@ -661,7 +1010,7 @@ func buildSelectFieldColumnCache(columnTypes []*sql.ColumnType, entityCache *ent
 
 // buildEmptySelectFieldColumnCache 构建空的查询字段缓存(用于单字段查询)
 // buildEmptySelectFieldColumnCache builds an empty query field cache (used for single field queries)
-func buildEmptySelectFieldColumnCache(columnTypes []*sql.ColumnType, dialect string) []*fieldColumnCache {
+func buildEmptySelectFieldColumnCache(ctx context.Context, columnTypes []*sql.ColumnType, typeOf *reflect.Type, config *DataSourceConfig) []*fieldColumnCache {
 	if columnTypes == nil {
 		return nil
 	}
@@ -677,8 +1026,8 @@ func buildEmptySelectFieldColumnCache(columnTypes []*sql.ColumnType, dialect str
 			databaseTypeName: databaseTypeName,
 		}
 		// 缓存带方言前缀的数据库类型名
-		if dialect != "" {
-			fieldCache.dialectDatabaseTypeName = dialect + "." + databaseTypeName
+		if config.Dialect != "" {
+			fieldCache.dialectDatabaseTypeName = config.Dialect + "." + databaseTypeName
 		}
 		// 缓存customDriverValueConver,避免每行每列的map查找
 		if iscdvm {
@@ -690,6 +1039,16 @@ func buildEmptySelectFieldColumnCache(columnTypes []*sql.ColumnType, dialect str
 			}
 			//cacheItem.cdvcStatus = 1 // 已检查
 		}
+		// 接收类型的IValueConver
+		// IValueConver of the receiving type
+		if valueConver := typeValueConver(*typeOf); valueConver != nil {
+			fieldCache.valueScanner = &valueConverScanner{ctx: ctx, valueConver: valueConver}
+		}
+		// 接收类型是time.Time时TimePolicy的接收对象
+		// Receiver of TimePolicy when the receiving type is time.Time
+		if config.TimePolicy != nil && *typeOf == timeType {
+			fieldCache.timeScanner = &timeScanner{policy: config.TimePolicy, dialect: config.Dialect}
+		}
 		fieldCaches[i] = fieldCache
 		//columnTypeToCache[columnType] = cacheItem
 	}
@@ -770,6 +1129,18 @@ func funcCreateEntityStructCache(ctx context.Context, entityCache *entityStructC
 	}
 	fieldCache.columnName = columnName
 	fieldCache.columnNameLower = strings.ToLower(columnName)
+	// 需要脱敏的列,RegisterRedactEntity时注册
+	// Column to redact, registered by RegisterRedactEntity
+	fieldCache.isRedact = field.Tag.Get(tagRedactName) == "true"
+	// 租户列
+	// Tenant column
+	fieldCache.isTenant = field.Tag.Get(tagTenantName) == "true"
+	// JSON列
+	// JSON column
+	fieldCache.isJSON = hasZormTagOption(field.Tag, zormTagOptionJSON)
+	// 数组列
+	// Array column
+	fieldCache.isArray = hasZormTagOption(field.Tag, zormTagOptionArray)
 
 	fieldCache.columnTag = columnTag
 	// @TODO 这里需要考虑已经在column tag中添加了包裹符,最好是把包裹符号放到Config中,取消FuncWrapFieldTagName函数
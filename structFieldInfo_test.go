@@ -20,9 +20,16 @@ package zorm
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // testEntity 测试用的实体结构体
@@ -504,3 +511,245 @@ func Test_funcCreateEntityStructCache_nestedAnonymousOverride(t *testing.T) {
 		}
 	})
 }
+
+// scanFakeDriver 测试扫描结果集的数据库驱动,DSN是 表名:行数,返回scanFakeTable的列和行,SELECT 列名 FROM 开头的语句只返回这一列.
+// 每个功能的测试使用自己的scanFakeTable,修改一个功能的结果集不影响其他功能的测试
+// scanFakeDriver test driver for scanning result sets, the DSN is table name:row count, the columns and rows of the scanFakeTable are returned, statements starting with SELECT column FROM only return that column.
+// The tests of each feature use their own scanFakeTable, changing the result set of one feature does not affect the tests of other features
+type scanFakeDriver struct{}
+
+// scanFakeTable scanFakeDriver返回的结果集
+// scanFakeTable result set returned by scanFakeDriver
+type scanFakeTable struct {
+	// name 注册的名称,用于DSN
+	// name registered name, used in the DSN
+	name        string
+	columns     []string
+	columnTypes []string
+	// row 给第index行的所有列赋值,index从1开始
+	// row assigns all columns of the index-th row, index starts from 1
+	row func(index int, values []driver.Value)
+}
+
+// scanFakeTables 注册的scanFakeTable,key是name
+// scanFakeTables registered scanFakeTables, the key is the name
+var scanFakeTables sync.Map
+
+type scanFakeConn struct {
+	table    *scanFakeTable
+	rowCount int
+}
+
+type scanFakeStmt struct {
+	conn  *scanFakeConn
+	query string
+}
+
+type scanFakeRows struct {
+	table    *scanFakeTable
+	rowCount int
+	index    int
+	// column 只返回的列,-1返回所有列
+	// column the only returned column, -1 returns all columns
+	column int
+	values []driver.Value
+}
+
+// scanFakeCreatedAt 时间列的值
+// scanFakeCreatedAt value of the time columns
+var scanFakeCreatedAt = time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+
+// scanFakeEntityTable testEntity的结果集,偶数行的user_name和age是NULL,extra列在testEntity中没有对应字段
+// scanFakeEntityTable result set of testEntity, user_name and age of even rows are NULL, the extra column has no field in testEntity
+var scanFakeEntityTable = &scanFakeTable{
+	name:        "entity",
+	columns:     []string{"id", "user_name", "age", "email", "is_active", "extra"},
+	columnTypes: []string{"VARCHAR", "VARCHAR", "INT", "VARCHAR", "BOOLEAN", "VARCHAR"},
+	row: func(index int, values []driver.Value) {
+		scanFakeUserValues(index, values)
+		values[3] = "a@b.c"
+		values[4] = true
+		values[5] = []byte("extra")
+	},
+}
+
+// scanFakeUserValues 给前三列 id,user_name,age 赋值,偶数行的user_name和age是NULL
+// scanFakeUserValues assigns the first three columns id, user_name, age, user_name and age of even rows are NULL
+func scanFakeUserValues(index int, values []driver.Value) {
+	values[0] = []byte(strconv.Itoa(index))
+	values[1], values[2] = nil, nil
+	if index%2 == 1 {
+		values[1] = []byte("name")
+		values[2] = int64(index)
+	}
+}
+
+func (scanFakeDriver) Open(dsn string) (driver.Conn, error) {
+	index := strings.LastIndexByte(dsn, ':')
+	table, ok := scanFakeTables.Load(dsn[:index+1])
+	if !ok {
+		return nil, errors.New("scanFakeDriver: unknown table " + dsn)
+	}
+	rowCount, err := strconv.Atoi(dsn[index+1:])
+	return &scanFakeConn{table: table.(*scanFakeTable), rowCount: rowCount}, err
+}
+func (c *scanFakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &scanFakeStmt{conn: c, query: query}, nil
+}
+func (c *scanFakeConn) Close() error              { return nil }
+func (c *scanFakeConn) Begin() (driver.Tx, error) { return recordFakeTx{}, nil }
+func (s *scanFakeStmt) Close() error              { return nil }
+func (s *scanFakeStmt) NumInput() int             { return -1 }
+func (s *scanFakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return recordFakeResult{}, nil
+}
+func (s *scanFakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	table := s.conn.table
+	rows := &scanFakeRows{table: table, rowCount: s.conn.rowCount, column: -1, values: make([]driver.Value, len(table.columns))}
+	for i, column := range table.columns {
+		if strings.HasPrefix(strings.TrimSpace(s.query), "SELECT "+column+" FROM") {
+			rows.column = i
+		}
+	}
+	return rows, nil
+}
+func (r *scanFakeRows) Columns() []string {
+	if r.column >= 0 {
+		return r.table.columns[r.column : r.column+1]
+	}
+	return r.table.columns
+}
+func (r *scanFakeRows) Close() error { return nil }
+func (r *scanFakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	if r.column >= 0 {
+		index = r.column
+	}
+	return r.table.columnTypes[index]
+}
+func (r *scanFakeRows) Next(dest []driver.Value) error {
+	if r.index >= r.rowCount {
+		return io.EOF
+	}
+	r.index++
+	values := r.values
+	r.table.row(r.index, values)
+	if r.column >= 0 {
+		values = values[r.column : r.column+1]
+	}
+	copy(dest, values)
+	return nil
+}
+
+func init() {
+	sql.Register("zorm_scan_fake", scanFakeDriver{})
+}
+
+// newScanFakeContext 返回scanFakeDriver数据库连接的ctx,查询返回table的rowCount行
+// newScanFakeContext returns the ctx of a scanFakeDriver connection, queries return rowCount rows of the table
+func newScanFakeContext(tb testing.TB, table *scanFakeTable, rowCount int) context.Context {
+	return newScanFakeConfigContext(tb, table, &DataSourceConfig{DSN: strconv.Itoa(rowCount), Dialect: "mysql"})
+}
+
+// newScanFakeConfigContext 使用config的其他配置返回scanFakeDriver数据库连接的ctx,config.DSN是返回的行数,测试结束关闭数据库
+// newScanFakeConfigContext returns the ctx of a scanFakeDriver connection with the other settings of config, config.DSN is the number of returned rows, the database is closed after the test
+func newScanFakeConfigContext(tb testing.TB, table *scanFakeTable, config *DataSourceConfig) context.Context {
+	scanFakeTables.Store(table.name+":", table)
+	config.DSN = table.name + ":" + config.DSN
+	config.DriverName = "zorm_scan_fake"
+	dbDao, err := NewDBDao(config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	// defaultDao 被其他测试使用,不关闭
+	// defaultDao is used by other tests, it is not closed
+	if dbDao != defaultDao {
+		tb.Cleanup(func() { _ = dbDao.CloseDB() })
+	}
+	ctx, err := dbDao.BindContextDBConnection(context.Background())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return ctx
+}
+
+// recordScanFakeSQL 记录测试执行的语句,返回获取语句的函数,测试结束恢复FuncPrintSQL
+// recordScanFakeSQL records the statements executed by the test and returns a function getting them, FuncPrintSQL is restored after the test
+func recordScanFakeSQL(t *testing.T) func() []string {
+	statements := make([]string, 0)
+	printSQL := FuncPrintSQL
+	FuncPrintSQL = func(ctx context.Context, sqlstr string, args []interface{}, execSQLMillis int64) {
+		statements = append(statements, sqlstr)
+	}
+	t.Cleanup(func() { FuncPrintSQL = printSQL })
+	return func() []string { return statements }
+}
+
+func Test_sqlRowsValues_null(t *testing.T) {
+	ctx := newScanFakeContext(t, scanFakeEntityTable, 2)
+	finder := NewSelectFinder("test_table")
+	list := make([]testEntity, 0)
+	if err := Query(ctx, finder, &list, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []testEntity{
+		{ID: "1", UserName: "name", Age: 1, Email: "a@b.c", IsActive: true},
+		{ID: "2", Email: "a@b.c", IsActive: true},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("Query = %+v, want %+v", list, want)
+	}
+
+	// 单列查询NULL值是默认值,指针是nil
+	// Single column query, NULL is the default value, pointer is nil
+	names := make([]string, 0)
+	if err := Query(ctx, NewFinder().Append("SELECT user_name FROM test_table"), &names, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"name", ""}) {
+		t.Errorf("names = %q", names)
+	}
+	type ptrEntity struct {
+		UserName *string `column:"user_name"`
+		Age      *int    `column:"age"`
+	}
+	ptrs := make([]ptrEntity, 0)
+	if err := Query(ctx, finder, &ptrs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ptrs) != 2 || ptrs[0].UserName == nil || *ptrs[0].Age != 1 || ptrs[1].UserName != nil || ptrs[1].Age != nil {
+		t.Errorf("ptrs = %+v", ptrs)
+	}
+
+	maps, err := QueryMap(ctx, finder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 2 || maps[0]["user_name"] != "name" || maps[0]["age"] != 1 || maps[1]["user_name"] != nil || maps[1]["age"] != nil || maps[1]["extra"] != "extra" {
+		t.Errorf("QueryMap = %v", maps)
+	}
+}
+
+func BenchmarkQuery_scan(b *testing.B) {
+	ctx := newScanFakeContext(b, scanFakeEntityTable, 500)
+	finder := NewSelectFinder("test_table")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list := make([]testEntity, 0, 500)
+		if err := Query(ctx, finder, &list, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryMap_scan(b *testing.B) {
+	ctx := newScanFakeContext(b, scanFakeEntityTable, 500)
+	finder := NewSelectFinder("test_table")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := QueryMap(ctx, finder, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,610 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// tagTenantName 租户列的tag标签名称,例如 `column:"tenant_id" tenant:"true"`.只对IEntityStruct生效,Finder语句需要RegisterTenantColumn注册表
+// tagTenantName tag name of the tenant column, e.g. `column:"tenant_id" tenant:"true"`. Only IEntityStruct is affected, Finder statements need the table registered by RegisterTenantColumn
+const tagTenantName = "tenant"
+
+// tenantColumnMap 表名(小写)和租户列名的映射
+// tenantColumnMap mapping from lower case table names to tenant column names
+var (
+	tenantColumnMap = sync.Map{}
+	// tenantColumnCount 注册的租户表数量,没有注册时Finder语句不处理
+	// tenantColumnCount number of registered tenant tables, Finder statements are not processed when there is none
+	tenantColumnCount int32
+)
+
+// RegisterTenantColumn 注册表的租户列,表名不区分大小写.注册后IEntityMap和Finder语句会自动处理租户条件,IEntityStruct也可以使用 tenant:"true" 的tag.
+// Finder的SELECT,UPDATE,DELETE语句会给最外层 FROM/JOIN/UPDATE 的租户表增加 表名或者别名.租户列=? 的条件,JOIN使用ON条件,其他表使用WHERE条件.
+// 子查询或者UNION等语句中有租户表时返回错误,需要使用BindContextIgnoreTenant并手动增加租户条件
+// RegisterTenantColumn registers the tenant column of a table, the table name is case insensitive. After registration IEntityMap and Finder statements handle the tenant condition automatically, IEntityStruct can also use the tenant:"true" tag.
+// SELECT, UPDATE and DELETE Finder statements get the tableName or alias.tenantColumn=? condition for the tenant tables of the outermost FROM/JOIN/UPDATE, JOIN uses the ON condition, the other tables use the WHERE condition.
+// An error is returned when a subquery or UNION etc. contains a tenant table, use BindContextIgnoreTenant and add the tenant condition manually
+func RegisterTenantColumn(tableName string, columnName string) {
+	if tableName == "" || columnName == "" {
+		return
+	}
+	if _, loaded := tenantColumnMap.LoadOrStore(strings.ToLower(tableName), columnName); !loaded {
+		atomic.AddInt32(&tenantColumnCount, 1)
+	}
+}
+
+// tenantColumn 获取表注册的租户列,先匹配完整的表名,再匹配去掉schema的表名,没有注册返回""
+// tenantColumn gets the registered tenant column of the table, the full table name is matched first, then the table name without schema, "" if not registered
+func tenantColumn(tableName string) string {
+	if atomic.LoadInt32(&tenantColumnCount) == 0 {
+		return ""
+	}
+	tableName = strings.ToLower(unquoteIdentifier(tableName))
+	if column, ok := tenantColumnMap.Load(tableName); ok {
+		return column.(string)
+	}
+	if index := strings.LastIndexByte(tableName, '.'); index >= 0 {
+		if column, ok := tenantColumnMap.Load(tableName[index+1:]); ok {
+			return column.(string)
+		}
+	}
+	return ""
+}
+
+// unquoteIdentifier 去掉名称每一部分的包裹符号,例如 `db`.`user` 返回 db.user
+// unquoteIdentifier removes the quotes of each part of the name, e.g. `db`.`user` returns db.user
+func unquoteIdentifier(name string) string {
+	if strings.IndexAny(name, "`\"[") < 0 {
+		return name
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if len(part) > 1 {
+			first := part[0]
+			if first == '`' || first == '"' || first == '[' {
+				parts[i] = part[1 : len(part)-1]
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// contextTenantID 获取ctx中绑定的租户ID,BindContextIgnoreTenant时返回false.没有绑定租户ID时返回错误,避免跨租户读写数据
+// contextTenantID gets the tenant ID bound to ctx, false with BindContextIgnoreTenant. An error is returned without a bound tenant ID, to avoid reading and writing across tenants
+func contextTenantID(ctx context.Context) (interface{}, bool, error) {
+	if ignore, ok := ctx.Value(contextIgnoreTenantValueKey).(bool); ok && ignore {
+		return nil, false, nil
+	}
+	tenantID := ctx.Value(contextTenantIDValueKey)
+	if tenantID == nil {
+		return nil, false, errors.New("->contextTenantID-->ctx没有绑定租户ID,请使用zorm.BindContextTenantID,跨租户的操作使用zorm.BindContextIgnoreTenant")
+	}
+	return tenantID, true, nil
+}
+
+// entityTenantField 获取实体类的租户字段,优先使用 tenant:"true" 的tag,其次是RegisterTenantColumn注册的列,没有返回nil
+// entityTenantField gets the tenant field of the entity, the tenant:"true" tag takes precedence over the column registered by RegisterTenantColumn, nil if there is none
+func entityTenantField(entity IEntityStruct, entityCache *entityStructCache) *fieldColumnCache {
+	if entityCache.tenantField != nil {
+		return entityCache.tenantField
+	}
+	if column := tenantColumn(entity.GetTableName()); column != "" {
+		return entityCache.columnMap[strings.ToLower(column)]
+	}
+	return nil
+}
+
+// setTenantFieldValue 把租户ID赋值给实体类的租户字段,支持指针和数字类型之间的转换
+// setTenantFieldValue assigns the tenant ID to the tenant field of the entity, conversions between pointers and number types are supported
+func setTenantFieldValue(fieldValue reflect.Value, tenantID interface{}) error {
+	valueOf := reflect.ValueOf(tenantID)
+	typeOf := fieldValue.Type()
+	if typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if valueOf.Type() != typeOf {
+		// 只转换数字类型,避免 int 转 string 变成字符
+		// Only numbers are converted, avoid int to string becoming a character
+		if !isNumberKind(valueOf.Kind()) || !isNumberKind(typeOf.Kind()) {
+			return errors.New("->setTenantFieldValue-->租户ID的类型" + valueOf.Type().String() + "和字段的类型" + typeOf.String() + "不一致")
+		}
+		valueOf = valueOf.Convert(typeOf)
+	}
+	if fieldValue.Kind() == reflect.Ptr {
+		ptr := reflect.New(typeOf)
+		ptr.Elem().Set(valueOf)
+		valueOf = ptr
+	}
+	fieldValue.Set(valueOf)
+	return nil
+}
+
+// isNumberKind 是否是整数类型
+// isNumberKind whether it is an integer kind
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// wrapTenantWhereSQL 生成的UPDATE和DELETE语句增加 AND 租户列=? 的条件,tenantColumnName为空或者BindContextIgnoreTenant时不处理
+// wrapTenantWhereSQL adds the AND tenantColumn=? condition to the generated UPDATE and DELETE statements, nothing is done when tenantColumnName is empty or with BindContextIgnoreTenant
+func wrapTenantWhereSQL(ctx context.Context, config *DataSourceConfig, tenantColumnName string, sqlstr *string, values *[]interface{}) error {
+	if tenantColumnName == "" {
+		return nil
+	}
+	tenantID, hasTenant, err := contextTenantID(ctx)
+	if err != nil || !hasTenant {
+		return err
+	}
+	*sqlstr = *sqlstr + " AND " + wrapQuoteIdentifier(config, tenantColumnName) + "=?"
+	*values = append(*values, tenantID)
+	return nil
+}
+
+// wrapTenantEntityMap 注册了租户列的IEntityMap,保存时使用ctx中的租户ID赋值
+// wrapTenantEntityMap assigns the tenant ID of ctx to the IEntityMap whose table has a registered tenant column when saving
+func wrapTenantEntityMap(ctx context.Context, entity IEntityMap) error {
+	column := tenantColumn(entity.GetTableName())
+	if column == "" {
+		return nil
+	}
+	tenantID, hasTenant, err := contextTenantID(ctx)
+	if err != nil || !hasTenant {
+		return err
+	}
+	entity.Set(column, tenantID)
+	return nil
+}
+
+// ================= Finder语句的租户条件 / Tenant condition of Finder statements =================
+
+// tenantSQLToken 租户解析使用的词法单元,只记录最外层的单元,括号内的内容作为一个单元
+// tenantSQLToken lexical token used by the tenant parsing, only the outermost tokens are recorded, the content of parentheses is one token
+type tenantSQLToken struct {
+	sqlSpan
+	// kind 'w'名称或者关键字,','逗号,'('括号,'o'其他
+	// kind 'w' name or keyword, ',' comma, '(' parentheses, 'o' others
+	kind byte
+	// word 大写的名称或者关键字,用于匹配关键字
+	// word upper case name or keyword, used to match keywords
+	word string
+}
+
+// tenantStopWords 结束表列表或者条件的关键字
+// tenantStopWords keywords ending the table list or the condition
+var tenantStopWords = map[string]bool{"WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true, "FETCH": true, "FOR": true, "WINDOW": true, "RETURNING": true, "LOCK": true, "SET": true, "UNION": true, "INTERSECT": true, "EXCEPT": true}
+
+// tenantJoinWords JOIN之前的关键字
+// tenantJoinWords keywords before JOIN
+var tenantJoinWords = map[string]bool{"JOIN": true, "STRAIGHT_JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "FULL": true, "CROSS": true, "NATURAL": true}
+
+// tenantNotAliasWords 表名后面不是别名的关键字
+// tenantNotAliasWords keywords after the table name that are not aliases
+var tenantNotAliasWords = map[string]bool{"ON": true, "USING": true, "WITH": true, "AS": true, "USE": true, "FORCE": true, "IGNORE": true, "PARTITION": true}
+
+// tenantSQLTokens 扫描 [start,end) 范围的SQL,返回最外层的词法单元
+// tenantSQLTokens scans the SQL in [start,end), returns the outermost tokens
+func tenantSQLTokens(sqlstr string, start int, end int) []tenantSQLToken {
+	sc := &sqlScanner{sqlStr: sqlstr[:end], sqlLen: end, index: start}
+	tokens := make([]tenantSQLToken, 0, 8)
+	for {
+		token, ok := nextTenantSQLToken(sc)
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// nextTenantSQLToken 读取下一个词法单元,跳过空白和注释,没有时返回false
+// nextTenantSQLToken reads the next token, skipping spaces and comments, false if there is none
+func nextTenantSQLToken(sc *sqlScanner) (tenantSQLToken, bool) {
+	sqlstr := sc.sqlStr
+	for sc.index < sc.sqlLen {
+		c := sqlstr[sc.index]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			sc.index++
+			continue
+		}
+		if (c == '-' || c == '/') && sc.skipComment() {
+			continue
+		}
+		tokenStart := sc.index
+		switch {
+		case c == '\'':
+			sc.skipString()
+			return tenantSQLToken{sqlSpan: sqlSpan{Start: tokenStart, End: sc.index}, kind: 'o'}, true
+		case c == '(':
+			// 括号内的内容作为一个单元
+			// The content of parentheses is one token
+			depth := 0
+			for sc.index < sc.sqlLen {
+				c = sqlstr[sc.index]
+				if c == '\'' || c == '"' {
+					sc.skipString()
+					continue
+				}
+				if (c == '-' || c == '/') && sc.skipComment() {
+					continue
+				}
+				sc.index++
+				if c == '(' {
+					depth++
+				} else if c == ')' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			return tenantSQLToken{sqlSpan: sqlSpan{Start: tokenStart, End: sc.index}, kind: '('}, true
+		case c == ',':
+			sc.index++
+			return tenantSQLToken{sqlSpan: sqlSpan{Start: tokenStart, End: sc.index}, kind: ','}, true
+		case isIdentChar(c) || c == '`' || c == '"' || c == '[':
+			// 名称可以是 schema.table 或者包裹的 `db`.`user`
+			// The name can be schema.table or the quoted `db`.`user`
+			for sc.index < sc.sqlLen {
+				c = sqlstr[sc.index]
+				if isIdentChar(c) || c == '.' || c == '$' || c == '#' {
+					sc.index++
+					continue
+				}
+				if c == '`' || c == '"' || c == '[' {
+					closeChar := c
+					if c == '[' {
+						closeChar = ']'
+					}
+					sc.index++
+					for sc.index < sc.sqlLen && sqlstr[sc.index] != closeChar {
+						sc.index++
+					}
+					if sc.index < sc.sqlLen {
+						sc.index++
+					}
+					continue
+				}
+				break
+			}
+			return tenantSQLToken{sqlSpan: sqlSpan{Start: tokenStart, End: sc.index}, kind: 'w', word: strings.ToUpper(sqlstr[tokenStart:sc.index])}, true
+		default:
+			sc.index++
+			return tenantSQLToken{sqlSpan: sqlSpan{Start: tokenStart, End: sc.index}, kind: 'o'}, true
+		}
+	}
+	return tenantSQLToken{}, false
+}
+
+// tenantTableRef Finder语句中需要增加租户条件的表
+// tenantTableRef table of the Finder statement that needs the tenant condition
+type tenantTableRef struct {
+	// condition 租户条件,例如 o.tenant_id=?
+	// condition tenant condition, e.g. o.tenant_id=?
+	condition string
+	// on JOIN的ON条件的范围,没有ON条件时Start和End都是0,使用WHERE条件
+	// on range of the ON condition of JOIN, Start and End are 0 without ON condition and the WHERE condition is used
+	on sqlSpan
+}
+
+// parseTenantTableRefs 解析表列表,返回租户表和表列表结束的位置
+// 表列表是 FROM 或者 UPDATE 后面的内容,例如 orders o LEFT JOIN users u ON o.user_id=u.id, dept d
+// parseTenantTableRefs parses the table list, returns the tenant tables and the end position of the table list
+// The table list is the content after FROM or UPDATE, e.g. orders o LEFT JOIN users u ON o.user_id=u.id, dept d
+func parseTenantTableRefs(config *DataSourceConfig, sqlstr string, tokens []tenantSQLToken) ([]tenantTableRef, int) {
+	refs := make([]tenantTableRef, 0, 2)
+	expectTable := true
+	end := 0
+	// lastRef 上一个JOIN的租户表在refs中的索引,用于记录ON条件
+	// lastRef index in refs of the tenant table of the last JOIN, used to record the ON condition
+	lastRef := -1
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.kind == 'w' && tenantStopWords[token.word] {
+			break
+		}
+		end = token.End
+		if expectTable {
+			expectTable = false
+			lastRef = -1
+			if token.kind != 'w' { // 子查询等,不处理
+				continue
+			}
+			column := tenantColumn(sqlstr[token.Start:token.End])
+			qualifier := sqlstr[token.Start:token.End]
+			// 别名
+			// Alias
+			if i+1 < len(tokens) && tokens[i+1].kind == 'w' && tokens[i+1].word == "AS" && i+2 < len(tokens) {
+				i += 2
+				qualifier = sqlstr[tokens[i].Start:tokens[i].End]
+				end = tokens[i].End
+			} else if i+1 < len(tokens) && tokens[i+1].kind == 'w' && !tenantStopWords[tokens[i+1].word] && !tenantJoinWords[tokens[i+1].word] && !tenantNotAliasWords[tokens[i+1].word] {
+				i++
+				qualifier = sqlstr[tokens[i].Start:tokens[i].End]
+				end = tokens[i].End
+			}
+			if column != "" {
+				refs = append(refs, tenantTableRef{condition: qualifier + "." + wrapQuoteIdentifier(config, column) + "=?"})
+				lastRef = len(refs) - 1
+			}
+			continue
+		}
+		switch {
+		case token.kind == ',':
+			expectTable = true
+		case token.kind == 'w' && (token.word == "JOIN" || token.word == "STRAIGHT_JOIN"):
+			expectTable = true
+		case token.kind == 'w' && token.word == "ON":
+			// ON条件到下一个JOIN,逗号或者结束关键字
+			// The ON condition ends at the next JOIN, comma or stop keyword
+			on := sqlSpan{}
+			for i+1 < len(tokens) {
+				next := tokens[i+1]
+				if next.kind == ',' || (next.kind == 'w' && (tenantJoinWords[next.word] || tenantStopWords[next.word])) {
+					break
+				}
+				i++
+				if on.Start == 0 {
+					on.Start = next.Start
+				}
+				on.End = next.End
+				end = next.End
+			}
+			if lastRef >= 0 && on.End > on.Start {
+				refs[lastRef].on = on
+			}
+		}
+	}
+	return refs, end
+}
+
+// tenantConditionEnd WHERE条件结束的位置,条件后面可能有 LIMIT,FOR UPDATE 等
+// tenantConditionEnd end position of the WHERE condition, LIMIT, FOR UPDATE etc. may follow the condition
+func tenantConditionEnd(tokens []tenantSQLToken) sqlSpan {
+	condition := sqlSpan{}
+	for _, token := range tokens {
+		if token.kind == 'w' && tenantStopWords[token.word] {
+			break
+		}
+		if condition.Start == 0 {
+			condition.Start = token.Start
+		}
+		condition.End = token.End
+	}
+	return condition
+}
+
+// findNestedTenantTable 查找子查询中 FROM 或者 JOIN 后面的租户表,minDepth是括号的最小深度,0表示所有的位置
+// findNestedTenantTable finds the tenant table after FROM or JOIN in subqueries, minDepth is the minimum depth of parentheses, 0 means every position
+func findNestedTenantTable(sqlstr string, minDepth int) string {
+	sc := &sqlScanner{sqlStr: sqlstr, sqlLen: len(sqlstr)}
+	for sc.index < sc.sqlLen {
+		c := sqlstr[sc.index]
+		if c == '\'' || c == '"' {
+			sc.skipString()
+			continue
+		}
+		if (c == '-' || c == '/') && sc.skipComment() {
+			continue
+		}
+		switch c {
+		case '(':
+			sc.depth++
+		case ')':
+			if sc.depth > 0 {
+				sc.depth--
+			}
+		case 'f', 'F', 'j', 'J':
+			if sc.depth >= minDepth && (matchKeyword(sqlstr, sc.index, "from") || matchKeyword(sqlstr, sc.index, "join")) {
+				token, ok := nextTenantSQLToken(&sqlScanner{sqlStr: sqlstr, sqlLen: sc.sqlLen, index: sc.index + 4})
+				if ok && token.kind == 'w' {
+					tableName := sqlstr[token.Start:token.End]
+					if tenantColumn(tableName) != "" {
+						return tableName
+					}
+				}
+			}
+		}
+		sc.index++
+	}
+	return ""
+}
+
+// tenantPlaceholderCount 统计 [0,end) 范围内的 ? 占位符数量,跳过字符串和注释
+// tenantPlaceholderCount counts the ? placeholders in [0,end), skipping strings and comments
+func tenantPlaceholderCount(sqlstr string, end int) int {
+	sc := &sqlScanner{sqlStr: sqlstr[:end], sqlLen: end}
+	count := 0
+	for sc.index < sc.sqlLen {
+		c := sqlstr[sc.index]
+		if c == '\'' || c == '"' {
+			sc.skipString()
+			continue
+		}
+		if (c == '-' || c == '/') && sc.skipComment() {
+			continue
+		}
+		if c == '?' {
+			count++
+		}
+		sc.index++
+	}
+	return count
+}
+
+// tenantInsert 在SQL的pos位置插入的内容
+// tenantInsert content inserted at the pos position of the SQL
+type tenantInsert struct {
+	pos  int
+	text string
+	// values 插入的参数数量
+	// values number of the inserted parameters
+	values int
+}
+
+// wrapTenantFinder 给Finder的SELECT,UPDATE,DELETE和INSERT ... SELECT语句增加租户条件,返回新的Finder,不修改传入的finder.没有租户表或者BindContextIgnoreTenant时返回原finder
+// wrapTenantFinder adds the tenant condition to the SELECT, UPDATE, DELETE and INSERT ... SELECT statements of the Finder, a new Finder is returned and the finder is not modified. The original finder is returned when there is no tenant table or with BindContextIgnoreTenant
+var wrapTenantFinder = func(ctx context.Context, config *DataSourceConfig, finder *Finder) (*Finder, error) {
+	if finder == nil || atomic.LoadInt32(&tenantColumnCount) == 0 {
+		return finder, nil
+	}
+	if ignore, ok := ctx.Value(contextIgnoreTenantValueKey).(bool); ok && ignore {
+		return finder, nil
+	}
+	sqlstr, err := finder.getSQL(config)
+	if err != nil {
+		return finder, err
+	}
+	sqlPart := finder.sqlPartCache
+	// 有 WITH 时从主语句开始, CTE 中的租户表在下面作为子查询检查
+	// With WITH, start at the main statement, tenant tables in CTEs are checked as subqueries below
+	tokens := tenantSQLTokens(sqlstr, sqlPart.Select.Start, len(sqlstr))
+	if len(tokens) < 1 || tokens[0].kind != 'w' {
+		return finder, nil
+	}
+	statement := tokens[0].word
+	switch statement {
+	case "SELECT", "UPDATE", "DELETE":
+	case "INSERT", "REPLACE":
+		// INSERT ... SELECT 给 SELECT 的 FROM 表增加租户条件,INSERT ... VALUES 没有 FROM 不处理
+		// INSERT ... SELECT adds the tenant condition to the FROM tables of SELECT, INSERT ... VALUES has no FROM and is not processed
+	default:
+		// 其他语句读取的租户表无法自动处理,返回错误,避免跨租户读写数据
+		// Tenant tables read by other statements cannot be handled, return an error to avoid reading and writing across tenants
+		if tableName := findNestedTenantTable(sqlstr, 0); tableName != "" {
+			return finder, errors.New("->wrapTenantFinder-->" + statement + "语句中有租户表" + tableName + ",无法自动增加租户条件,请使用zorm.BindContextIgnoreTenant并手动增加租户条件")
+		}
+		return finder, nil
+	}
+	// 子查询或者UNION等复合语句中的租户表无法自动处理,返回错误,避免跨租户读写数据
+	// Tenant tables in subqueries or compound statements such as UNION cannot be handled, return an error to avoid reading and writing across tenants
+	minDepth := 1
+	if sqlPart.Union.End > 0 || sqlPart.Intersect.End > 0 || sqlPart.Except.End > 0 {
+		minDepth = 0
+	}
+	if tableName := findNestedTenantTable(sqlstr, minDepth); tableName != "" {
+		return finder, errors.New("->wrapTenantFinder-->子查询或者UNION等语句中有租户表" + tableName + ",无法自动增加租户条件,请使用zorm.BindContextIgnoreTenant并手动增加租户条件")
+	}
+
+	refs := make([]tenantTableRef, 0, 2)
+	// tableEnd 表列表结束的位置,没有WHERE条件时在这里增加 WHERE
+	// tableEnd end position of the table list, WHERE is added here without WHERE condition
+	tableEnd := 0
+	if statement == "UPDATE" {
+		updateRefs, updateEnd := parseTenantTableRefs(config, sqlstr, tokens[1:])
+		refs = append(refs, updateRefs...)
+		// SET 后面的内容,可能有 RETURNING 等
+		// The content after SET, RETURNING etc. may follow
+		setEnd := sqlPart.Select.End
+		if sqlPart.From.End > sqlPart.From.Start {
+			setEnd = sqlPart.From.Start
+		}
+		tableEnd = updateEnd
+		for i := 1; i < len(tokens) && tokens[i].Start < setEnd; i++ {
+			if tokens[i].kind == 'w' && tokens[i].word == "SET" {
+				tableEnd = tenantConditionEnd(tenantSQLTokens(sqlstr, tokens[i].End, setEnd)).End
+				break
+			}
+		}
+	}
+	if sqlPart.From.End > sqlPart.From.Start {
+		fromRefs, fromEnd := parseTenantTableRefs(config, sqlstr, tenantSQLTokens(sqlstr, sqlPart.From.Start+4, sqlPart.From.End))
+		refs = append(refs, fromRefs...)
+		if fromEnd > 0 {
+			tableEnd = fromEnd
+		}
+	}
+	if len(refs) < 1 {
+		return finder, nil
+	}
+	tenantID, hasTenant, err := contextTenantID(ctx)
+	if err != nil || !hasTenant {
+		return finder, err
+	}
+	if tenantPlaceholderCount(sqlstr, len(sqlstr)) != len(finder.values) {
+		return finder, errors.New("->wrapTenantFinder-->问号占位符和参数的数量不一致,无法增加租户条件,请使用zorm.BindContextIgnoreTenant并手动增加租户条件")
+	}
+
+	// 需要插入的内容, ON条件和WHERE条件都使用 (原条件) AND 租户条件
+	// The content to insert, both ON and WHERE conditions use (condition) AND tenant condition
+	inserts := make([]tenantInsert, 0, 2*len(refs)+2)
+	whereConditions := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref.on.End > ref.on.Start {
+			inserts = append(inserts, tenantInsert{pos: ref.on.Start, text: "("}, tenantInsert{pos: ref.on.End, text: ") AND " + ref.condition, values: 1})
+		} else {
+			whereConditions = append(whereConditions, ref.condition)
+		}
+	}
+	if len(whereConditions) > 0 {
+		whereSQL := strings.Join(whereConditions, " AND ")
+		condition := sqlSpan{}
+		if sqlPart.Where.End > sqlPart.Where.Start {
+			condition = tenantConditionEnd(tenantSQLTokens(sqlstr, sqlPart.Where.Start+5, sqlPart.Where.End))
+		}
+		if condition.End > condition.Start {
+			inserts = append(inserts, tenantInsert{pos: condition.Start, text: "("}, tenantInsert{pos: condition.End, text: ") AND " + whereSQL, values: len(whereConditions)})
+		} else {
+			inserts = append(inserts, tenantInsert{pos: tableEnd, text: " WHERE " + whereSQL, values: len(whereConditions)})
+		}
+	}
+	sort.SliceStable(inserts, func(i, j int) bool {
+		return inserts[i].pos < inserts[j].pos
+	})
+
+	var sqlBuilder strings.Builder
+	sqlBuilder.Grow(len(sqlstr) + 32*len(inserts))
+	values := make([]interface{}, 0, len(finder.values)+len(refs))
+	pos, valueIndex := 0, 0
+	for _, insert := range inserts {
+		sqlBuilder.WriteString(sqlstr[pos:insert.pos])
+		sqlBuilder.WriteString(insert.text)
+		if insert.values > 0 {
+			// 插入位置之前的参数
+			// The parameters before the insert position
+			count := tenantPlaceholderCount(sqlstr, insert.pos)
+			values = append(values, finder.values[valueIndex:count]...)
+			valueIndex = count
+			for i := 0; i < insert.values; i++ {
+				values = append(values, tenantID)
+			}
+		}
+		pos = insert.pos
+	}
+	sqlBuilder.WriteString(sqlstr[pos:])
+	values = append(values, finder.values[valueIndex:]...)
+
+	tenantFinder := NewFinder()
+	tenantFinder.sqlBuilder.WriteString(sqlBuilder.String())
+	tenantFinder.values = values
+	tenantFinder.InjectionCheck = finder.InjectionCheck
+	tenantFinder.SelectTotalCount = finder.SelectTotalCount
+	tenantFinder.CountFinder = finder.CountFinder
+	return tenantFinder, nil
+}
@@ -0,0 +1,193 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// testTenantEntity 使用 tenant:"true" tag的租户实体类
+// testTenantEntity tenant entity using the tenant:"true" tag
+type testTenantEntity struct {
+	EntityStruct
+	ID       string `column:"id"`
+	Name     string `column:"name"`
+	TenantID int64  `column:"tenant_id" tenant:"true"`
+}
+
+func (entity *testTenantEntity) GetTableName() string {
+	return "t_tenant_order"
+}
+
+func Test_wrapTenantFinder(t *testing.T) {
+	RegisterTenantColumn("t_tenant_order", "tenant_id")
+	RegisterTenantColumn("t_tenant_user", "tenant_id")
+	ctx, _ := BindContextTenantID(context.Background(), 42)
+	tests := []struct {
+		sql    string
+		values []interface{}
+		want   string
+		wantV  []interface{}
+	}{
+		{"SELECT * FROM t_tenant_order", nil,
+			" SELECT * FROM t_tenant_order WHERE t_tenant_order.tenant_id=?", []interface{}{42}},
+		{"SELECT * FROM t_tenant_order o WHERE o.id=? OR o.name=? ORDER BY o.id LIMIT ?", []interface{}{1, "a", 10},
+			" SELECT * FROM t_tenant_order o WHERE (o.id=? OR o.name=?) AND o.tenant_id=? ORDER BY o.id LIMIT ?", []interface{}{1, "a", 42, 10}},
+		{"SELECT * FROM t_tenant_order AS o LEFT JOIN t_tenant_user u ON o.user_id=u.id, t_dept d WHERE d.id=? FOR UPDATE", []interface{}{1},
+			" SELECT * FROM t_tenant_order AS o LEFT JOIN t_tenant_user u ON (o.user_id=u.id) AND u.tenant_id=?, t_dept d WHERE (d.id=?) AND o.tenant_id=? FOR UPDATE", []interface{}{42, 1, 42}},
+		{"SELECT name,count(*) FROM t_tenant_order GROUP BY name", nil,
+			" SELECT name,count(*) FROM t_tenant_order WHERE t_tenant_order.tenant_id=? GROUP BY name", []interface{}{42}},
+		{"UPDATE t_tenant_order SET name=? WHERE id=?", []interface{}{"a", 1},
+			" UPDATE t_tenant_order SET name=? WHERE (id=?) AND t_tenant_order.tenant_id=?", []interface{}{"a", 1, 42}},
+		{"UPDATE t_tenant_order SET name=?", []interface{}{"a"},
+			" UPDATE t_tenant_order SET name=? WHERE t_tenant_order.tenant_id=?", []interface{}{"a", 42}},
+		{"DELETE FROM t_tenant_order WHERE id IN (?)", []interface{}{[]int{1, 2}},
+			" DELETE FROM t_tenant_order WHERE (id IN (?)) AND t_tenant_order.tenant_id=?", []interface{}{[]int{1, 2}, 42}},
+		{"SELECT * FROM t_dept WHERE id=?", []interface{}{1},
+			" SELECT * FROM t_dept WHERE id=?", []interface{}{1}},
+		{"INSERT INTO t_tenant_order(id) VALUES(?)", []interface{}{1},
+			" INSERT INTO t_tenant_order(id) VALUES(?)", []interface{}{1}},
+		{"INSERT INTO t_archive SELECT * FROM t_tenant_order o WHERE o.id=?", []interface{}{1},
+			" INSERT INTO t_archive SELECT * FROM t_tenant_order o WHERE (o.id=?) AND o.tenant_id=?", []interface{}{1, 42}},
+		{"WITH d AS (SELECT id FROM t_dept) INSERT INTO t_archive(id) SELECT o.id FROM t_tenant_order o JOIN d ON o.dept_id=d.id", nil,
+			" WITH d AS (SELECT id FROM t_dept) INSERT INTO t_archive(id) SELECT o.id FROM t_tenant_order o JOIN d ON o.dept_id=d.id WHERE o.tenant_id=?", []interface{}{42}},
+	}
+	for _, tt := range tests {
+		finder := NewFinder().Append(tt.sql, tt.values...)
+		got, err := wrapTenantFinder(ctx, nil, finder)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sql, err)
+			continue
+		}
+		gotSQL, _ := got.GetSQL()
+		if gotSQL != tt.want {
+			t.Errorf("sql = %q, want %q", gotSQL, tt.want)
+		}
+		if !reflect.DeepEqual(got.values, tt.wantV) && !(len(got.values) == 0 && len(tt.wantV) == 0) {
+			t.Errorf("%s: values = %v, want %v", tt.sql, got.values, tt.wantV)
+		}
+	}
+
+	// 子查询,UNION和其他语句中的租户表返回错误
+	// Tenant tables in subqueries, UNION and other statements return an error
+	for _, sqlstr := range []string{
+		"SELECT * FROM t_dept WHERE id IN (SELECT dept_id FROM t_tenant_user)",
+		"SELECT id FROM t_dept UNION SELECT id FROM t_tenant_user",
+		"WITH u AS (SELECT * FROM t_tenant_user) SELECT * FROM u",
+		"INSERT INTO t_archive(id) VALUES((SELECT max(id) FROM t_tenant_order))",
+		"WITH u AS (SELECT * FROM t_tenant_user) INSERT INTO t_archive SELECT * FROM u",
+		"MERGE INTO t_archive a USING (SELECT * FROM t_tenant_order) o ON (a.id=o.id) WHEN NOT MATCHED THEN INSERT (id) VALUES (o.id)",
+		"CREATE TABLE t_archive AS SELECT * FROM t_tenant_order",
+	} {
+		if _, err := wrapTenantFinder(ctx, nil, NewFinder().Append(sqlstr)); err == nil {
+			t.Errorf("%s: expected error", sqlstr)
+		}
+	}
+	// 没有绑定租户ID返回错误,BindContextIgnoreTenant不处理
+	// An error is returned without a bound tenant ID, BindContextIgnoreTenant is not processed
+	finder := NewFinder().Append("SELECT * FROM t_tenant_order")
+	if _, err := wrapTenantFinder(context.Background(), nil, finder); err == nil {
+		t.Error("expected error without tenant ID")
+	}
+	ignoreCtx, _ := BindContextIgnoreTenant(context.Background())
+	if got, err := wrapTenantFinder(ignoreCtx, nil, finder); err != nil || got != finder {
+		t.Errorf("ignore tenant: finder = %v, err = %v", got, err)
+	}
+}
+
+func Test_Tenant_entity(t *testing.T) {
+	// Finder语句只处理注册的租户表
+	// Finder statements only handle registered tenant tables
+	RegisterTenantColumn("t_tenant_order", "tenant_id")
+	RegisterTenantColumn("t_tenant_map", "tenant_id")
+	ctx, statements := newRecordFakeContext(t, &DataSourceConfig{Dialect: "mysql"})
+	entity := &testTenantEntity{ID: "1", Name: "a"}
+	entityMap := NewEntityMap("t_tenant_map")
+	entityMap.PkColumnName = "id"
+	entityMap.Set("id", "1")
+	_, err := Transaction(ctx, func(ctx context.Context) (interface{}, error) {
+		if _, err := Insert(ctx, entity); err == nil {
+			t.Error("expected error without tenant ID")
+		}
+		tenantCtx, _ := BindContextTenantID(ctx, 42)
+		if _, err := Insert(tenantCtx, entity); err != nil {
+			return nil, err
+		}
+		if _, err := Update(tenantCtx, entity); err != nil {
+			return nil, err
+		}
+		if _, err := DeleteSlice(tenantCtx, []IEntityStruct{entity}); err != nil {
+			return nil, err
+		}
+		if _, err := InsertEntityMap(tenantCtx, entityMap); err != nil {
+			return nil, err
+		}
+		if _, err := UpdateEntityMap(tenantCtx, entityMap); err != nil {
+			return nil, err
+		}
+		if _, err := UpdateFinder(tenantCtx, NewUpdateFinder("t_tenant_map").Append("name=?", "b")); err != nil {
+			return nil, err
+		}
+		// 分页查询和总条数都增加租户条件
+		// Both the page query and the total count get the tenant condition
+		list := make([]testTenantEntity, 0)
+		if err := Query(tenantCtx, NewSelectFinder("t_tenant_order").Append("WHERE name=?", "a"), &list, NewPage()); err != nil {
+			return nil, err
+		}
+		// GROUP BY和DISTINCT的总条数使用子查询,子查询中不再重复处理租户表
+		// The total count of GROUP BY and DISTINCT uses a subquery, tenant tables in it are not processed again
+		names := make([]string, 0)
+		if err := Query(tenantCtx, NewFinder().Append("SELECT name FROM t_tenant_order GROUP BY name"), &names, NewPage()); err != nil {
+			return nil, err
+		}
+		if _, err := QueryMap(tenantCtx, NewFinder().Append("SELECT DISTINCT name FROM t_tenant_order"), NewPage()); err != nil {
+			return nil, err
+		}
+		// 跨租户的管理任务
+		// Cross-tenant admin job
+		ignoreCtx, _ := BindContextIgnoreTenant(ctx)
+		return Delete(ignoreCtx, entity)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity.TenantID != 42 || entityMap.GetDBFieldMap()["tenant_id"] != 42 {
+		t.Errorf("tenant id = %v, %v", entity.TenantID, entityMap.GetDBFieldMap()["tenant_id"])
+	}
+	want := []string{
+		"INSERT INTO t_tenant_order(id,name,tenant_id) VALUES(?,?,?)",
+		"UPDATE t_tenant_order SET name=? WHERE id=? AND tenant_id=?",
+		"DELETE FROM t_tenant_order WHERE id IN (?) AND tenant_id=?",
+		"INSERT INTO t_tenant_map(id,tenant_id) VALUES (?,?)",
+		"UPDATE t_tenant_map SET tenant_id=? WHERE id=? AND tenant_id=?",
+		"UPDATE t_tenant_map SET  name=? WHERE t_tenant_map.tenant_id=?",
+		"SELECT * FROM t_tenant_order WHERE (name=?) AND t_tenant_order.tenant_id=? LIMIT 0,20",
+		" SELECT COUNT(*) FROM t_tenant_order WHERE (name=?) AND t_tenant_order.tenant_id=?",
+		" SELECT name FROM t_tenant_order WHERE t_tenant_order.tenant_id=? GROUP BY name LIMIT 0,20",
+		" SELECT COUNT(*) AS temp_zorm_row_count FROM ( SELECT name FROM t_tenant_order WHERE t_tenant_order.tenant_id=? GROUP BY name) temp_zorm_noob_table_name WHERE 1=1 ",
+		" SELECT DISTINCT name FROM t_tenant_order WHERE t_tenant_order.tenant_id=? LIMIT 0,20",
+		" SELECT COUNT(*) AS temp_zorm_row_count FROM ( SELECT DISTINCT name FROM t_tenant_order WHERE t_tenant_order.tenant_id=?) temp_zorm_noob_table_name WHERE 1=1 ",
+		"DELETE FROM t_tenant_order WHERE id=?",
+	}
+	if got := statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
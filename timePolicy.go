@@ -0,0 +1,209 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TimePolicy 数据源的时区和时间精度策略,DataSourceConfig.TimePolicy为nil时不处理,保持数据库驱动的行为.
+// 保存时作用于Finder的参数,实体类字段和EntityMap的值;查询时作用于实体类字段,单列查询和QueryMap的值
+// TimePolicy time zone and time precision policy of the datasource, nothing is processed when DataSourceConfig.TimePolicy is nil, keeping the behavior of the database driver.
+// It is applied to Finder args, entity fields and EntityMap values when saving; to entity fields, single column queries and QueryMap values when querying
+type TimePolicy struct {
+	// StorageLocation 保存到数据库的时区,保存的time.Time转换到这个时区.数据库返回不带时区的时间字符串时也使用这个时区解析,nil时使用UTC
+	// StorageLocation time zone saved to the database, saved time.Time values are converted to it. Time strings without zone returned by the database are also parsed in it, UTC when nil
+	StorageLocation *time.Location
+
+	// ReadLocation 查询的time.Time转换到这个时区,nil时不转换
+	// ReadLocation queried time.Time values are converted to this time zone, not converted when nil
+	ReadLocation *time.Location
+
+	// Precision 时间截断到的精度,应该和列的小数秒精度一致,例如DATETIME(3)是time.Millisecond,保存和查询时都会截断,0不截断
+	// Precision precision the time is truncated to, it should match the fractional second precision of the column, e.g. time.Millisecond for DATETIME(3), truncated when saving and querying, 0 does not truncate
+	Precision time.Duration
+
+	// Layouts 解析数据库返回的时间字符串的格式,为空时使用方言的默认格式,例如sqlite和没有开启parseTime的mysql返回字符串
+	// Layouts layouts parsing time strings returned by the database, the default layouts of the dialect are used when empty, e.g. sqlite and mysql without parseTime return strings
+	Layouts []string
+}
+
+// defaultTimeLayouts 方言没有默认格式时,解析时间字符串的格式
+// defaultTimeLayouts layouts parsing time strings when the dialect has no default layouts
+var defaultTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// dialectTimeLayouts 方言解析时间字符串的默认格式
+// dialectTimeLayouts default layouts parsing time strings of the dialect
+var dialectTimeLayouts = map[string][]string{
+	"mysql":      {"2006-01-02 15:04:05.999999999", "2006-01-02", "15:04:05.999999999"},
+	"postgresql": {"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999", "2006-01-02", "15:04:05.999999999Z07", "15:04:05.999999999"},
+	"kingbase":   {"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999", "2006-01-02", "15:04:05.999999999Z07", "15:04:05.999999999"},
+	"sqlite":     {"2006-01-02 15:04:05.999999999-07:00", "2006-01-02T15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05.999999999Z07:00", "2006-01-02"},
+	"mssql":      {"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999 -07:00", "2006-01-02 15:04:05.999999999", "2006-01-02", "15:04:05.999999999"},
+}
+
+// storageTime 保存的时间,转换到StorageLocation并截断到Precision
+// storageTime saved time, converted to StorageLocation and truncated to Precision
+func (policy *TimePolicy) storageTime(t time.Time) time.Time {
+	if policy.StorageLocation != nil {
+		t = t.In(policy.StorageLocation)
+	}
+	if policy.Precision > 0 {
+		t = t.Truncate(policy.Precision)
+	}
+	return t
+}
+
+// readTime 查询的时间,转换到ReadLocation并截断到Precision
+// readTime queried time, converted to ReadLocation and truncated to Precision
+func (policy *TimePolicy) readTime(t time.Time) time.Time {
+	if policy.ReadLocation != nil {
+		t = t.In(policy.ReadLocation)
+	}
+	if policy.Precision > 0 {
+		t = t.Truncate(policy.Precision)
+	}
+	return t
+}
+
+// parseTime 解析数据库驱动返回的值,支持time.Time,string和[]byte
+// parseTime parses the value returned by the database driver, time.Time, string and []byte are supported
+func (policy *TimePolicy) parseTime(dialect string, src interface{}) (time.Time, error) {
+	var text string
+	switch v := src.(type) {
+	case time.Time:
+		return policy.readTime(v), nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return time.Time{}, fmt.Errorf("->parseTime-->不支持的时间类型:%T", src)
+	}
+	layouts := policy.Layouts
+	if len(layouts) < 1 {
+		layouts = dialectTimeLayouts[dialect]
+	}
+	if len(layouts) < 1 {
+		layouts = defaultTimeLayouts
+	}
+	location := policy.StorageLocation
+	if location == nil {
+		location = time.UTC
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, text, location); err == nil {
+			return policy.readTime(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("->parseTime-->不支持的时间格式:%s", text)
+}
+
+// timeScanner 使用TimePolicy接收time.Time字段
+// timeScanner receives time.Time fields with TimePolicy
+type timeScanner struct {
+	policy  *TimePolicy
+	dialect string
+	dest    *time.Time
+}
+
+// Scan 实现sql.Scanner接口
+// Scan implements the sql.Scanner interface
+func (scanner *timeScanner) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+	t, err := scanner.policy.parseTime(scanner.dialect, src)
+	if err != nil {
+		return err
+	}
+	*scanner.dest = t
+	return nil
+}
+
+// isTimeType 是否是time.Time或者*time.Time
+// isTimeType whether it is time.Time or *time.Time
+func isTimeType(typeOf reflect.Type) bool {
+	return typeOf == timeType || (typeOf.Kind() == reflect.Ptr && typeOf.Elem() == timeType)
+}
+
+// wrapTimePolicyArgs 使用TimePolicy转换参数中的time.Time,*time.Time和[]time.Time.没有需要转换的参数时返回原参数,不修改原数组
+// wrapTimePolicyArgs converts time.Time, *time.Time and []time.Time args with TimePolicy. The original args are returned when nothing is converted, the original array is not modified
+func wrapTimePolicyArgs(policy *TimePolicy, args *[]interface{}) *[]interface{} {
+	if policy == nil || args == nil {
+		return args
+	}
+	var newArgs []interface{}
+	for i, arg := range *args {
+		var value interface{}
+		switch v := arg.(type) {
+		case time.Time:
+			value = policy.storageTime(v)
+		case *time.Time:
+			if v == nil {
+				continue
+			}
+			value = policy.storageTime(*v)
+		case []time.Time:
+			times := make([]time.Time, len(v))
+			for j := range v {
+				times[j] = policy.storageTime(v[j])
+			}
+			value = times
+		default:
+			continue
+		}
+		if newArgs == nil {
+			newArgs = make([]interface{}, len(*args))
+			copy(newArgs, *args)
+		}
+		newArgs[i] = value
+	}
+	if newArgs == nil {
+		return args
+	}
+	return &newArgs
+}
+
+// timeDatabaseTypes 字符串值使用TimePolicy解析的数据库类型,TIME和INTERVAL可能超出一天,保持原值
+// timeDatabaseTypes database types whose string values are parsed with TimePolicy, TIME and INTERVAL may exceed one day and keep the original value
+var timeDatabaseTypes = map[string]bool{"DATE": true, "DATETIME": true, "TIMESTAMP": true, "TIMESTAMPTZ": true, "DATETIME2": true, "SMALLDATETIME": true, "DATETIMEOFFSET": true}
+
+// timePolicyMapValue 使用TimePolicy转换QueryMap的值,time.Time转换时区和精度,时间列的字符串解析为time.Time
+// timePolicyMapValue converts QueryMap values with TimePolicy, time.Time is converted to the time zone and precision, strings of time columns are parsed to time.Time
+func timePolicyMapValue(config *DataSourceConfig, databaseTypeName string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return config.TimePolicy.readTime(v), nil
+	case string, []byte:
+		if timeDatabaseTypes[databaseTypeName] {
+			return config.TimePolicy.parseTime(config.Dialect, v)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package zorm
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+// testShanghai 测试使用的东八区
+// testShanghai UTC+8 used in tests
+var testShanghai = time.FixedZone("CST", 8*3600)
+
+func Test_TimePolicy_parseTime(t *testing.T) {
+	policy := &TimePolicy{StorageLocation: testShanghai, ReadLocation: time.UTC, Precision: time.Millisecond}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 123000000, testShanghai)
+	tests := []struct {
+		dialect string
+		src     interface{}
+	}{
+		{"mysql", []byte("2024-01-02 03:04:05.123456")},
+		{"postgresql", "2024-01-02 03:04:05.123456+08"},
+		{"sqlite", "2024-01-02T03:04:05.123456+08:00"},
+		{"mssql", "2024-01-02T03:04:05.1234567+08:00"},
+		{"dm", "2024-01-02 03:04:05.123"},
+		{"mysql", want.Add(456 * time.Microsecond)},
+	}
+	for _, tt := range tests {
+		got, err := policy.parseTime(tt.dialect, tt.src)
+		if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("%s parseTime(%v) = %v, %v", tt.dialect, tt.src, got, err)
+		}
+	}
+	if _, err := policy.parseTime("mysql", "01/02/2024"); err == nil {
+		t.Error("expected error for an unknown layout")
+	}
+	custom := &TimePolicy{Layouts: []string{"01/02/2006"}}
+	if got, err := custom.parseTime("mysql", "01/02/2024"); err != nil || !got.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("custom layout = %v, %v", got, err)
+	}
+
+	// QueryMap只解析时间列的字符串
+	// QueryMap only parses strings of time columns
+	config := &DataSourceConfig{Dialect: "mysql", TimePolicy: policy}
+	if got, err := timePolicyMapValue(config, "DATETIME", "2024-01-02 03:04:05.123"); err != nil || !got.(time.Time).Equal(want) {
+		t.Errorf("timePolicyMapValue(DATETIME) = %v, %v", got, err)
+	}
+	if got, _ := timePolicyMapValue(config, "TIME", "838:59:59"); got != "838:59:59" {
+		t.Errorf("timePolicyMapValue(TIME) = %v", got)
+	}
+}
+
+func Test_wrapTimePolicyArgs(t *testing.T) {
+	policy := &TimePolicy{StorageLocation: testShanghai, Precision: time.Second}
+	now := time.Date(2024, 1, 1, 20, 0, 0, 999, time.UTC)
+	want := time.Date(2024, 1, 2, 4, 0, 0, 0, testShanghai)
+	var nilTime *time.Time
+	args := []interface{}{1, now, &now, nilTime, []time.Time{now}}
+	got := wrapTimePolicyArgs(policy, &args)
+	if (*got)[0] != 1 || (*got)[3] != nilTime {
+		t.Errorf("args = %v", *got)
+	}
+	for _, value := range []interface{}{(*got)[1], (*got)[2], (*got)[4].([]time.Time)[0]} {
+		tm := value.(time.Time)
+		if !tm.Equal(want) || tm.Location() != testShanghai {
+			t.Errorf("time = %v, want %v", tm, want)
+		}
+	}
+	if args[1] != now {
+		t.Error("args must not be modified")
+	}
+	plain := []interface{}{"a"}
+	if wrapTimePolicyArgs(policy, &plain) != &plain || wrapTimePolicyArgs(nil, &args) != &args {
+		t.Error("args without conversion must be returned as is")
+	}
+}
+
+// scanFakeTimeTable 时间列的结果集,created_at是UTC时间
+// scanFakeTimeTable result set of the time column, created_at is a UTC time
+var scanFakeTimeTable = &scanFakeTable{
+	name:        "time",
+	columns:     []string{"created_at"},
+	columnTypes: []string{"DATETIME"},
+	row: func(index int, values []driver.Value) {
+		values[0] = scanFakeCreatedAt
+	},
+}
+
+func Test_TimePolicy_read(t *testing.T) {
+	policy := &TimePolicy{ReadLocation: testShanghai, Precision: time.Millisecond}
+	ctx := newScanFakeConfigContext(t, scanFakeTimeTable, &DataSourceConfig{DSN: "1", Dialect: "mysql", TimePolicy: policy})
+	want := scanFakeCreatedAt.Truncate(time.Millisecond)
+
+	type timeRow struct {
+		CreatedAt time.Time `column:"created_at"`
+	}
+	row := timeRow{}
+	if _, err := QueryRow(ctx, NewSelectFinder("test_table"), &row); err != nil {
+		t.Fatal(err)
+	}
+	if !row.CreatedAt.Equal(want) || row.CreatedAt.Location() != testShanghai {
+		t.Errorf("QueryRow = %v", row.CreatedAt)
+	}
+	type timePtrRow struct {
+		CreatedAt *time.Time `column:"created_at"`
+	}
+	ptrRow := timePtrRow{}
+	if _, err := QueryRow(ctx, NewSelectFinder("test_table"), &ptrRow); err != nil || ptrRow.CreatedAt == nil || !ptrRow.CreatedAt.Equal(want) {
+		t.Errorf("QueryRow pointer = %v, %v", ptrRow.CreatedAt, err)
+	}
+
+	var createdAt time.Time
+	if _, err := QueryRow(ctx, NewFinder().Append("SELECT created_at FROM test_table"), &createdAt); err != nil || !createdAt.Equal(want) || createdAt.Location() != testShanghai {
+		t.Errorf("single column = %v, %v", createdAt, err)
+	}
+
+	maps, err := QueryMap(ctx, NewSelectFinder("test_table"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := maps[0]["created_at"].(time.Time); !ok || !got.Equal(want) || got.Location() != testShanghai {
+		t.Errorf("QueryMap = %v", maps[0]["created_at"])
+	}
+}
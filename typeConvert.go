@@ -20,6 +20,11 @@ package zorm
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"gitee.com/chunanyong/zorm/decimal"
 )
@@ -35,6 +40,161 @@ func typeConvertInt64toInt(from int64) (int, error) {
 	return int(from), nil
 }
 
+// typeConvertValue 把value转换为typeOf类型的值,用于EntityMap的值赋值给struct字段.
+// 支持整数,浮点数,字符串,[]byte,bool和实现了fmt.Stringer的类型(例如decimal)之间的转换,整数溢出和小数转换为整数时返回错误
+// typeConvertValue converts value to a value of typeOf, used to assign EntityMap values to struct fields.
+// Conversions between integers, floats, strings, []byte, bool and types implementing fmt.Stringer (e.g. decimal) are supported, integer overflows and fractions converted to integers return errors
+func typeConvertValue(value interface{}, typeOf reflect.Type) (reflect.Value, error) {
+	valueOf := reflect.ValueOf(value)
+	if valueOf.Type().AssignableTo(typeOf) {
+		return valueOf, nil
+	}
+	result := reflect.New(typeOf).Elem()
+	text, isText := "", true
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case fmt.Stringer:
+		text = v.String()
+	default:
+		isText = false
+	}
+	switch typeOf.Kind() {
+	case reflect.String:
+		if !isText {
+			text = fmt.Sprint(value)
+		}
+		result.SetString(text)
+		return result, nil
+	case reflect.Bool:
+		if isText {
+			b, err := strconv.ParseBool(strings.TrimSpace(text))
+			if err != nil {
+				return result, fmt.Errorf("->typeConvertValue-->%s不能转换为bool:%w", text, err)
+			}
+			result.SetBool(b)
+			return result, nil
+		}
+		if f, ok := typeConvertNumber(valueOf); ok {
+			result.SetBool(f != 0)
+			return result, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok, err := typeConvertToInt64(valueOf, text, isText)
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			if result.OverflowInt(i) {
+				return result, fmt.Errorf("->typeConvertValue-->%d超出%s的范围", i, typeOf.String())
+			}
+			result.SetInt(i)
+			return result, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if valueOf.Kind() >= reflect.Uint && valueOf.Kind() <= reflect.Uintptr {
+			u = valueOf.Uint()
+		} else {
+			i, ok, err := typeConvertToInt64(valueOf, text, isText)
+			if err != nil {
+				return result, err
+			}
+			if !ok {
+				break
+			}
+			if i < 0 {
+				return result, fmt.Errorf("->typeConvertValue-->%d超出%s的范围", i, typeOf.String())
+			}
+			u = uint64(i)
+		}
+		if result.OverflowUint(u) {
+			return result, fmt.Errorf("->typeConvertValue-->%d超出%s的范围", u, typeOf.String())
+		}
+		result.SetUint(u)
+		return result, nil
+	case reflect.Float32, reflect.Float64:
+		if isText {
+			f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+			if err != nil {
+				return result, fmt.Errorf("->typeConvertValue-->%s不能转换为%s:%w", text, typeOf.String(), err)
+			}
+			result.SetFloat(f)
+			return result, nil
+		}
+		if f, ok := typeConvertNumber(valueOf); ok {
+			result.SetFloat(f)
+			return result, nil
+		}
+	case reflect.Slice:
+		if typeOf.Elem().Kind() == reflect.Uint8 && isText {
+			result.SetBytes([]byte(text))
+			return result, nil
+		}
+	}
+	if valueOf.Type().ConvertibleTo(typeOf) && valueOf.Kind() == typeOf.Kind() {
+		return valueOf.Convert(typeOf), nil
+	}
+	return result, fmt.Errorf("->typeConvertValue-->%s类型不能转换为%s", valueOf.Type().String(), typeOf.String())
+}
+
+// typeConvertToInt64 字符串,数字和bool转换为int64,不支持的类型返回false
+// typeConvertToInt64 converts strings, numbers and bool to int64, false for unsupported types
+func typeConvertToInt64(valueOf reflect.Value, text string, isText bool) (int64, bool, error) {
+	switch {
+	case isText:
+		i, err := typeConvertParseInt(text)
+		return i, err == nil, err
+	case valueOf.Kind() >= reflect.Int && valueOf.Kind() <= reflect.Int64:
+		return valueOf.Int(), true, nil
+	case valueOf.Kind() >= reflect.Uint && valueOf.Kind() <= reflect.Uintptr:
+		if valueOf.Uint() > math.MaxInt64 {
+			return 0, false, fmt.Errorf("->typeConvertToInt64-->%d超出int64的范围", valueOf.Uint())
+		}
+		return int64(valueOf.Uint()), true, nil
+	}
+	f, ok := typeConvertNumber(valueOf)
+	if ok && f != math.Trunc(f) {
+		return 0, false, fmt.Errorf("->typeConvertToInt64-->%v不能转换为整数", valueOf.Interface())
+	}
+	return int64(f), ok, nil
+}
+
+// typeConvertNumber 数字和bool转换为float64
+// typeConvertNumber converts numbers and bool to float64
+func typeConvertNumber(valueOf reflect.Value) (float64, bool) {
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(valueOf.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(valueOf.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return valueOf.Float(), true
+	case reflect.Bool:
+		if valueOf.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// typeConvertParseInt 字符串转换为int64,兼容 12.0 这样小数部分是0的字符串
+// typeConvertParseInt converts a string to int64, compatible with strings like 12.0 whose fraction is 0
+func typeConvertParseInt(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("->typeConvertParseInt-->%s不能转换为整数", text)
+	}
+	return int64(f), nil
+}
+
 /*
 func typeConvertFloat32(i interface{}) (float32, error) {
 	if i == nil {